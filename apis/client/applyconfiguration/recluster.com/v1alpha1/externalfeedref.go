@@ -17,12 +17,24 @@ limitations under the License.
 
 package v1alpha1
 
+import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
 // ExternalFeedRefApplyConfiguration represents a declarative configuration of the ExternalFeedRef type for use
 // with apply.
 type ExternalFeedRefApplyConfiguration struct {
-	Name     *string                               `json:"name,omitempty"`
-	URL      *string                               `json:"url,omitempty"`
-	Mappings []FeedMetricMappingApplyConfiguration `json:"mappings,omitempty"`
+	Name                 *string                                     `json:"name,omitempty"`
+	URL                  *string                                     `json:"url,omitempty"`
+	BearerTokenSecretRef *corev1.SecretKeySelectorApplyConfiguration `json:"bearerTokenSecretRef,omitempty"`
+	Prometheus           *PrometheusSelectorApplyConfiguration       `json:"prometheus,omitempty"`
+	JSONPath             *string                                     `json:"jsonPath,omitempty"`
+	Interval             *v1.Duration                                `json:"interval,omitempty"`
+	Timeout              *v1.Duration                                `json:"timeout,omitempty"`
+	TTL                  *v1.Duration                                `json:"ttl,omitempty"`
+	FallbackMultiplier   *float64                                    `json:"fallbackMultiplier,omitempty"`
+	Mappings             []FeedMetricMappingApplyConfiguration       `json:"mappings,omitempty"`
 }
 
 // ExternalFeedRefApplyConfiguration constructs a declarative configuration of the ExternalFeedRef type for use with
@@ -47,6 +59,62 @@ func (b *ExternalFeedRefApplyConfiguration) WithURL(value string) *ExternalFeedR
 	return b
 }
 
+// WithBearerTokenSecretRef sets the BearerTokenSecretRef field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the BearerTokenSecretRef field is set to the value of the last call.
+func (b *ExternalFeedRefApplyConfiguration) WithBearerTokenSecretRef(value *corev1.SecretKeySelectorApplyConfiguration) *ExternalFeedRefApplyConfiguration {
+	b.BearerTokenSecretRef = value
+	return b
+}
+
+// WithPrometheus sets the Prometheus field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Prometheus field is set to the value of the last call.
+func (b *ExternalFeedRefApplyConfiguration) WithPrometheus(value *PrometheusSelectorApplyConfiguration) *ExternalFeedRefApplyConfiguration {
+	b.Prometheus = value
+	return b
+}
+
+// WithJSONPath sets the JSONPath field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the JSONPath field is set to the value of the last call.
+func (b *ExternalFeedRefApplyConfiguration) WithJSONPath(value string) *ExternalFeedRefApplyConfiguration {
+	b.JSONPath = &value
+	return b
+}
+
+// WithInterval sets the Interval field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Interval field is set to the value of the last call.
+func (b *ExternalFeedRefApplyConfiguration) WithInterval(value v1.Duration) *ExternalFeedRefApplyConfiguration {
+	b.Interval = &value
+	return b
+}
+
+// WithTimeout sets the Timeout field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Timeout field is set to the value of the last call.
+func (b *ExternalFeedRefApplyConfiguration) WithTimeout(value v1.Duration) *ExternalFeedRefApplyConfiguration {
+	b.Timeout = &value
+	return b
+}
+
+// WithTTL sets the TTL field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the TTL field is set to the value of the last call.
+func (b *ExternalFeedRefApplyConfiguration) WithTTL(value v1.Duration) *ExternalFeedRefApplyConfiguration {
+	b.TTL = &value
+	return b
+}
+
+// WithFallbackMultiplier sets the FallbackMultiplier field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the FallbackMultiplier field is set to the value of the last call.
+func (b *ExternalFeedRefApplyConfiguration) WithFallbackMultiplier(value float64) *ExternalFeedRefApplyConfiguration {
+	b.FallbackMultiplier = &value
+	return b
+}
+
 // WithMappings adds the given value to the Mappings field in the declarative configuration
 // and returns the receiver, so that objects can be build by chaining "With" function invocations.
 // If called multiple times, values provided by each call will be appended to the Mappings field.
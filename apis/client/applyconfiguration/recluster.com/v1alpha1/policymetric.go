@@ -24,11 +24,13 @@ import (
 // PolicyMetricApplyConfiguration represents a declarative configuration of the PolicyMetric type for use
 // with apply.
 type PolicyMetricApplyConfiguration struct {
-	Key       *string                         `json:"key,omitempty"`
-	Weight    *float64                        `json:"weight,omitempty"`
-	Source    *reclustercomv1alpha1.ValueFrom `json:"source,omitempty"`
-	Selector  *string                         `json:"selector,omitempty"`
-	Transform *string                         `json:"transform,omitempty"`
+	Key       *string                             `json:"key,omitempty"`
+	Weight    *float64                            `json:"weight,omitempty"`
+	Source    *reclustercomv1alpha1.ValueFrom     `json:"source,omitempty"`
+	Selector  *string                             `json:"selector,omitempty"`
+	Transform *string                             `json:"transform,omitempty"`
+	Normalize *reclustercomv1alpha1.Normalization `json:"normalize,omitempty"`
+	Tolerance *float64                            `json:"tolerance,omitempty"`
 }
 
 // PolicyMetricApplyConfiguration constructs a declarative configuration of the PolicyMetric type for use with
@@ -76,3 +78,19 @@ func (b *PolicyMetricApplyConfiguration) WithTransform(value string) *PolicyMetr
 	b.Transform = &value
 	return b
 }
+
+// WithNormalize sets the Normalize field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Normalize field is set to the value of the last call.
+func (b *PolicyMetricApplyConfiguration) WithNormalize(value reclustercomv1alpha1.Normalization) *PolicyMetricApplyConfiguration {
+	b.Normalize = &value
+	return b
+}
+
+// WithTolerance sets the Tolerance field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Tolerance field is set to the value of the last call.
+func (b *PolicyMetricApplyConfiguration) WithTolerance(value float64) *PolicyMetricApplyConfiguration {
+	b.Tolerance = &value
+	return b
+}
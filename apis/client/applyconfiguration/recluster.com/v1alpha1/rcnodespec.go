@@ -19,6 +19,7 @@ package v1alpha1
 
 import (
 	reclustercomv1alpha1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	v1 "k8s.io/api/core/v1"
 )
 
 // RcNodeSpecApplyConfiguration represents a declarative configuration of the RcNodeSpec type for use
@@ -32,6 +33,7 @@ type RcNodeSpecApplyConfiguration struct {
 	Memory                         *int64                                  `json:"memoryBytes,omitempty"`
 	Storage                        []RcNodeStorageSpecApplyConfiguration   `json:"storages,omitempty"`
 	Network                        []RcNodeInterfaceSpecApplyConfiguration `json:"interfaces,omitempty"`
+	ExtendedResources              *v1.ResourceList                        `json:"extendedResources,omitempty"`
 	MinPowerConsumption            *int                                    `json:"minPowerConsumption,omitempty"`
 	MaxEfficiencyPowerConsumption  *int                                    `json:"maxEfficiencyPowerConsumption,omitempty"`
 	MinPerformancePowerConsumption *int                                    `json:"minPerformancePowerConsumption,omitempty"`
@@ -39,6 +41,7 @@ type RcNodeSpecApplyConfiguration struct {
 	PowerCurve                     *RcNodePowerCurveSpecApplyConfiguration `json:"powerCurve,omitempty"`
 	BootSeconds                    *int                                    `json:"bootSeconds,omitempty"`
 	DesiredState                   *string                                 `json:"desiredState,omitempty"`
+	Driver                         *string                                 `json:"driver,omitempty"`
 }
 
 // RcNodeSpecApplyConfiguration constructs a declarative configuration of the RcNodeSpec type for use with
@@ -125,6 +128,14 @@ func (b *RcNodeSpecApplyConfiguration) WithNetwork(values ...*RcNodeInterfaceSpe
 	return b
 }
 
+// WithExtendedResources sets the ExtendedResources field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ExtendedResources field is set to the value of the last call.
+func (b *RcNodeSpecApplyConfiguration) WithExtendedResources(value v1.ResourceList) *RcNodeSpecApplyConfiguration {
+	b.ExtendedResources = &value
+	return b
+}
+
 // WithMinPowerConsumption sets the MinPowerConsumption field in the declarative configuration to the given value
 // and returns the receiver, so that objects can be built by chaining "With" function invocations.
 // If called multiple times, the MinPowerConsumption field is set to the value of the last call.
@@ -180,3 +191,11 @@ func (b *RcNodeSpecApplyConfiguration) WithDesiredState(value string) *RcNodeSpe
 	b.DesiredState = &value
 	return b
 }
+
+// WithDriver sets the Driver field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Driver field is set to the value of the last call.
+func (b *RcNodeSpecApplyConfiguration) WithDriver(value string) *RcNodeSpecApplyConfiguration {
+	b.Driver = &value
+	return b
+}
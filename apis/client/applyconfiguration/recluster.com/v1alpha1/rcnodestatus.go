@@ -20,21 +20,25 @@ package v1alpha1
 import (
 	reclustercomv1alpha1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1 "k8s.io/client-go/applyconfigurations/meta/v1"
 )
 
 // RcNodeStatusApplyConfiguration represents a declarative configuration of the RcNodeStatus type for use
 // with apply.
 type RcNodeStatusApplyConfiguration struct {
-	State               *reclustercomv1alpha1.NodeStatus `json:"state,omitempty"`
-	Reason              *string                          `json:"reason,omitempty"`
-	Message             *string                          `json:"message,omitempty"`
-	LastHeartbeat       *v1.Time                         `json:"lastHeartbeat,omitempty"`
-	LastTransition      *v1.Time                         `json:"lastTransition,omitempty"`
-	NodePoolAssigned    *bool                            `json:"nodePoolAssigned,omitempty"`
-	UtilizationMilliCPU *int                             `json:"utilizationMilliCPU,omitempty"`
-	UtilizationPct      *float64                         `json:"utilizationPct,omitempty"`
-	PredictedPowerWatts *int                             `json:"predictedPowerWatts,omitempty"`
-	ObservedPowerWatts  *int                             `json:"observedPowerWatts,omitempty"`
+	State               *reclustercomv1alpha1.NodeStatus     `json:"state,omitempty"`
+	Reason              *string                              `json:"reason,omitempty"`
+	Message             *string                              `json:"message,omitempty"`
+	LastHeartbeat       *v1.Time                             `json:"lastHeartbeat,omitempty"`
+	LastTransition      *v1.Time                             `json:"lastTransition,omitempty"`
+	NodePoolAssigned    *bool                                `json:"nodePoolAssigned,omitempty"`
+	UtilizationMilliCPU *int                                 `json:"utilizationMilliCPU,omitempty"`
+	UtilizationPct      *float64                             `json:"utilizationPct,omitempty"`
+	PredictedPowerWatts *int                                 `json:"predictedPowerWatts,omitempty"`
+	ObservedPowerWatts  *int                                 `json:"observedPowerWatts,omitempty"`
+	Driver              *string                              `json:"driver,omitempty"`
+	ObservedGeneration  *int64                               `json:"observedGeneration,omitempty"`
+	Conditions          []metav1.ConditionApplyConfiguration `json:"conditions,omitempty"`
 }
 
 // RcNodeStatusApplyConfiguration constructs a declarative configuration of the RcNodeStatus type for use with
@@ -122,3 +126,32 @@ func (b *RcNodeStatusApplyConfiguration) WithObservedPowerWatts(value int) *RcNo
 	b.ObservedPowerWatts = &value
 	return b
 }
+
+// WithDriver sets the Driver field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Driver field is set to the value of the last call.
+func (b *RcNodeStatusApplyConfiguration) WithDriver(value string) *RcNodeStatusApplyConfiguration {
+	b.Driver = &value
+	return b
+}
+
+// WithObservedGeneration sets the ObservedGeneration field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ObservedGeneration field is set to the value of the last call.
+func (b *RcNodeStatusApplyConfiguration) WithObservedGeneration(value int64) *RcNodeStatusApplyConfiguration {
+	b.ObservedGeneration = &value
+	return b
+}
+
+// WithConditions adds the given value to the Conditions field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the Conditions field.
+func (b *RcNodeStatusApplyConfiguration) WithConditions(values ...*metav1.ConditionApplyConfiguration) *RcNodeStatusApplyConfiguration {
+	for i := range values {
+		if values[i] == nil {
+			panic("nil value passed to WithConditions")
+		}
+		b.Conditions = append(b.Conditions, *values[i])
+	}
+	return b
+}
@@ -18,17 +18,21 @@ limitations under the License.
 package v1alpha1
 
 import (
+	reclustercomv1alpha1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
 	v1 "k8s.io/client-go/applyconfigurations/meta/v1"
 )
 
 // RcPolicySpecApplyConfiguration represents a declarative configuration of the RcPolicySpec type for use
 // with apply.
 type RcPolicySpecApplyConfiguration struct {
-	Selector        *v1.LabelSelectorApplyConfiguration     `json:"selector,omitempty"`
-	Metrics         []PolicyMetricApplyConfiguration        `json:"metrics,omitempty"`
-	HardConstraints []PolicyConstraintApplyConfiguration    `json:"hardConstraints,omitempty"`
-	Schedule        []PolicyScheduleEntryApplyConfiguration `json:"schedule,omitempty"`
-	ExternalFeeds   []ExternalFeedRefApplyConfiguration     `json:"externalFeeds,omitempty"`
+	Selector        *v1.LabelSelectorApplyConfiguration      `json:"selector,omitempty"`
+	Metrics         []PolicyMetricApplyConfiguration         `json:"metrics,omitempty"`
+	Mode            *reclustercomv1alpha1.RankingMode        `json:"mode,omitempty"`
+	TieBreaker      *reclustercomv1alpha1.RankingMode        `json:"tieBreaker,omitempty"`
+	HardConstraints []PolicyConstraintApplyConfiguration     `json:"hardConstraints,omitempty"`
+	SoftConstraints []PolicySoftConstraintApplyConfiguration `json:"softConstraints,omitempty"`
+	Schedule        []PolicyScheduleEntryApplyConfiguration  `json:"schedule,omitempty"`
+	ExternalFeeds   []ExternalFeedRefApplyConfiguration      `json:"externalFeeds,omitempty"`
 }
 
 // RcPolicySpecApplyConfiguration constructs a declarative configuration of the RcPolicySpec type for use with
@@ -58,6 +62,22 @@ func (b *RcPolicySpecApplyConfiguration) WithMetrics(values ...*PolicyMetricAppl
 	return b
 }
 
+// WithMode sets the Mode field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Mode field is set to the value of the last call.
+func (b *RcPolicySpecApplyConfiguration) WithMode(value reclustercomv1alpha1.RankingMode) *RcPolicySpecApplyConfiguration {
+	b.Mode = &value
+	return b
+}
+
+// WithTieBreaker sets the TieBreaker field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the TieBreaker field is set to the value of the last call.
+func (b *RcPolicySpecApplyConfiguration) WithTieBreaker(value reclustercomv1alpha1.RankingMode) *RcPolicySpecApplyConfiguration {
+	b.TieBreaker = &value
+	return b
+}
+
 // WithHardConstraints adds the given value to the HardConstraints field in the declarative configuration
 // and returns the receiver, so that objects can be build by chaining "With" function invocations.
 // If called multiple times, values provided by each call will be appended to the HardConstraints field.
@@ -71,6 +91,19 @@ func (b *RcPolicySpecApplyConfiguration) WithHardConstraints(values ...*PolicyCo
 	return b
 }
 
+// WithSoftConstraints adds the given value to the SoftConstraints field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the SoftConstraints field.
+func (b *RcPolicySpecApplyConfiguration) WithSoftConstraints(values ...*PolicySoftConstraintApplyConfiguration) *RcPolicySpecApplyConfiguration {
+	for i := range values {
+		if values[i] == nil {
+			panic("nil value passed to WithSoftConstraints")
+		}
+		b.SoftConstraints = append(b.SoftConstraints, *values[i])
+	}
+	return b
+}
+
 // WithSchedule adds the given value to the Schedule field in the declarative configuration
 // and returns the receiver, so that objects can be build by chaining "With" function invocations.
 // If called multiple times, values provided by each call will be appended to the Schedule field.
@@ -19,15 +19,20 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1 "k8s.io/client-go/applyconfigurations/meta/v1"
 )
 
 // RcPolicyStatusApplyConfiguration represents a declarative configuration of the RcPolicyStatus type for use
 // with apply.
 type RcPolicyStatusApplyConfiguration struct {
-	MatchedPods  *int32   `json:"matchedPods,omitempty"`
-	RejectedPods *int32   `json:"rejectedPods,omitempty"`
-	LastResolved *v1.Time `json:"lastResolved,omitempty"`
-	LastFeedSync *v1.Time `json:"lastFeedSync,omitempty"`
+	MatchedPods        *int32                               `json:"matchedPods,omitempty"`
+	RejectedPods       *int32                               `json:"rejectedPods,omitempty"`
+	LastResolved       *v1.Time                             `json:"lastResolved,omitempty"`
+	LastFeedSync       *v1.Time                             `json:"lastFeedSync,omitempty"`
+	ObservedGeneration *int64                               `json:"observedGeneration,omitempty"`
+	CompileErrors      []string                             `json:"compileErrors,omitempty"`
+	Feeds              []FeedStatusApplyConfiguration       `json:"feeds,omitempty"`
+	Conditions         []metav1.ConditionApplyConfiguration `json:"conditions,omitempty"`
 }
 
 // RcPolicyStatusApplyConfiguration constructs a declarative configuration of the RcPolicyStatus type for use with
@@ -67,3 +72,47 @@ func (b *RcPolicyStatusApplyConfiguration) WithLastFeedSync(value v1.Time) *RcPo
 	b.LastFeedSync = &value
 	return b
 }
+
+// WithObservedGeneration sets the ObservedGeneration field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the ObservedGeneration field is set to the value of the last call.
+func (b *RcPolicyStatusApplyConfiguration) WithObservedGeneration(value int64) *RcPolicyStatusApplyConfiguration {
+	b.ObservedGeneration = &value
+	return b
+}
+
+// WithCompileErrors adds the given value to the CompileErrors field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the CompileErrors field.
+func (b *RcPolicyStatusApplyConfiguration) WithCompileErrors(values ...string) *RcPolicyStatusApplyConfiguration {
+	for i := range values {
+		b.CompileErrors = append(b.CompileErrors, values[i])
+	}
+	return b
+}
+
+// WithFeeds adds the given value to the Feeds field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the Feeds field.
+func (b *RcPolicyStatusApplyConfiguration) WithFeeds(values ...*FeedStatusApplyConfiguration) *RcPolicyStatusApplyConfiguration {
+	for i := range values {
+		if values[i] == nil {
+			panic("nil value passed to WithFeeds")
+		}
+		b.Feeds = append(b.Feeds, *values[i])
+	}
+	return b
+}
+
+// WithConditions adds the given value to the Conditions field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the Conditions field.
+func (b *RcPolicyStatusApplyConfiguration) WithConditions(values ...*metav1.ConditionApplyConfiguration) *RcPolicyStatusApplyConfiguration {
+	for i := range values {
+		if values[i] == nil {
+			panic("nil value passed to WithConditions")
+		}
+		b.Conditions = append(b.Conditions, *values[i])
+	}
+	return b
+}
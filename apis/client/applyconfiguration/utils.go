@@ -35,6 +35,8 @@ func ForKind(kind schema.GroupVersionKind) interface{} {
 		return &reclustercomv1alpha1.ExternalFeedRefApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("FeedMetricMapping"):
 		return &reclustercomv1alpha1.FeedMetricMappingApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("FeedStatus"):
+		return &reclustercomv1alpha1.FeedStatusApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("MetricAdjustment"):
 		return &reclustercomv1alpha1.MetricAdjustmentApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("PolicyConstraint"):
@@ -43,6 +45,10 @@ func ForKind(kind schema.GroupVersionKind) interface{} {
 		return &reclustercomv1alpha1.PolicyMetricApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("PolicyScheduleEntry"):
 		return &reclustercomv1alpha1.PolicyScheduleEntryApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("PolicySoftConstraint"):
+		return &reclustercomv1alpha1.PolicySoftConstraintApplyConfiguration{}
+	case v1alpha1.SchemeGroupVersion.WithKind("PrometheusSelector"):
+		return &reclustercomv1alpha1.PrometheusSelectorApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("RcNode"):
 		return &reclustercomv1alpha1.RcNodeApplyConfiguration{}
 	case v1alpha1.SchemeGroupVersion.WithKind("RcNodeCPUSpec"):
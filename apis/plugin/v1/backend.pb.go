@@ -0,0 +1,846 @@
+// Out-of-process power drivers for recluster-sync.
+//
+// The manager is the client: for every RcNode whose driver names a plugin it
+// calls PowerOn or PowerOff (from Spec.DesiredState) on each reconcile, then
+// Observe. Calls are level-triggered and repeated, so a plugin must treat
+// PowerOn on a machine that is already on (or booting) as a no-op, and
+// likewise for PowerOff.
+//
+// Regenerate the Go code with `make proto`.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: apis/plugin/v1/backend.proto
+
+package pluginv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Capability int32
+
+const (
+	Capability_CAPABILITY_UNSPECIFIED Capability = 0
+	// Observe is implemented; without it the manager only looks at the Node.
+	Capability_CAPABILITY_OBSERVE Capability = 1
+	// ObserveResponse.power_watts is a measurement.
+	Capability_CAPABILITY_POWER_READING Capability = 2
+	// The plugin registers (and removes) the Kubernetes Node itself, as the
+	// KWOK driver does; real machines join the cluster on their own.
+	Capability_CAPABILITY_MANAGES_NODE Capability = 3
+)
+
+// Enum value maps for Capability.
+var (
+	Capability_name = map[int32]string{
+		0: "CAPABILITY_UNSPECIFIED",
+		1: "CAPABILITY_OBSERVE",
+		2: "CAPABILITY_POWER_READING",
+		3: "CAPABILITY_MANAGES_NODE",
+	}
+	Capability_value = map[string]int32{
+		"CAPABILITY_UNSPECIFIED":   0,
+		"CAPABILITY_OBSERVE":       1,
+		"CAPABILITY_POWER_READING": 2,
+		"CAPABILITY_MANAGES_NODE":  3,
+	}
+)
+
+func (x Capability) Enum() *Capability {
+	p := new(Capability)
+	*p = x
+	return p
+}
+
+func (x Capability) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Capability) Descriptor() protoreflect.EnumDescriptor {
+	return file_apis_plugin_v1_backend_proto_enumTypes[0].Descriptor()
+}
+
+func (Capability) Type() protoreflect.EnumType {
+	return &file_apis_plugin_v1_backend_proto_enumTypes[0]
+}
+
+func (x Capability) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Capability.Descriptor instead.
+func (Capability) EnumDescriptor() ([]byte, []int) {
+	return file_apis_plugin_v1_backend_proto_rawDescGZIP(), []int{0}
+}
+
+type PowerState int32
+
+const (
+	PowerState_POWER_STATE_UNSPECIFIED  PowerState = 0
+	PowerState_POWER_STATE_ON           PowerState = 1
+	PowerState_POWER_STATE_OFF          PowerState = 2
+	PowerState_POWER_STATE_POWERING_ON  PowerState = 3
+	PowerState_POWER_STATE_POWERING_OFF PowerState = 4
+	PowerState_POWER_STATE_UNKNOWN      PowerState = 5
+)
+
+// Enum value maps for PowerState.
+var (
+	PowerState_name = map[int32]string{
+		0: "POWER_STATE_UNSPECIFIED",
+		1: "POWER_STATE_ON",
+		2: "POWER_STATE_OFF",
+		3: "POWER_STATE_POWERING_ON",
+		4: "POWER_STATE_POWERING_OFF",
+		5: "POWER_STATE_UNKNOWN",
+	}
+	PowerState_value = map[string]int32{
+		"POWER_STATE_UNSPECIFIED":  0,
+		"POWER_STATE_ON":           1,
+		"POWER_STATE_OFF":          2,
+		"POWER_STATE_POWERING_ON":  3,
+		"POWER_STATE_POWERING_OFF": 4,
+		"POWER_STATE_UNKNOWN":      5,
+	}
+)
+
+func (x PowerState) Enum() *PowerState {
+	p := new(PowerState)
+	*p = x
+	return p
+}
+
+func (x PowerState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PowerState) Descriptor() protoreflect.EnumDescriptor {
+	return file_apis_plugin_v1_backend_proto_enumTypes[1].Descriptor()
+}
+
+func (PowerState) Type() protoreflect.EnumType {
+	return &file_apis_plugin_v1_backend_proto_enumTypes[1]
+}
+
+func (x PowerState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PowerState.Descriptor instead.
+func (PowerState) EnumDescriptor() ([]byte, []int) {
+	return file_apis_plugin_v1_backend_proto_rawDescGZIP(), []int{1}
+}
+
+type BootProgress int32
+
+const (
+	// Not booting (off).
+	BootProgress_BOOT_PROGRESS_UNSPECIFIED BootProgress = 0
+	// Powered, no Node yet.
+	BootProgress_BOOT_PROGRESS_POWER_ON BootProgress = 1
+	// Node registered, not Ready.
+	BootProgress_BOOT_PROGRESS_NODE_REGISTERED BootProgress = 2
+	// Node Ready.
+	BootProgress_BOOT_PROGRESS_READY BootProgress = 3
+)
+
+// Enum value maps for BootProgress.
+var (
+	BootProgress_name = map[int32]string{
+		0: "BOOT_PROGRESS_UNSPECIFIED",
+		1: "BOOT_PROGRESS_POWER_ON",
+		2: "BOOT_PROGRESS_NODE_REGISTERED",
+		3: "BOOT_PROGRESS_READY",
+	}
+	BootProgress_value = map[string]int32{
+		"BOOT_PROGRESS_UNSPECIFIED":     0,
+		"BOOT_PROGRESS_POWER_ON":        1,
+		"BOOT_PROGRESS_NODE_REGISTERED": 2,
+		"BOOT_PROGRESS_READY":           3,
+	}
+)
+
+func (x BootProgress) Enum() *BootProgress {
+	p := new(BootProgress)
+	*p = x
+	return p
+}
+
+func (x BootProgress) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (BootProgress) Descriptor() protoreflect.EnumDescriptor {
+	return file_apis_plugin_v1_backend_proto_enumTypes[2].Descriptor()
+}
+
+func (BootProgress) Type() protoreflect.EnumType {
+	return &file_apis_plugin_v1_backend_proto_enumTypes[2]
+}
+
+func (x BootProgress) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use BootProgress.Descriptor instead.
+func (BootProgress) EnumDescriptor() ([]byte, []int) {
+	return file_apis_plugin_v1_backend_proto_rawDescGZIP(), []int{2}
+}
+
+type CapabilitiesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CapabilitiesRequest) Reset() {
+	*x = CapabilitiesRequest{}
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapabilitiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapabilitiesRequest) ProtoMessage() {}
+
+func (x *CapabilitiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapabilitiesRequest.ProtoReflect.Descriptor instead.
+func (*CapabilitiesRequest) Descriptor() ([]byte, []int) {
+	return file_apis_plugin_v1_backend_proto_rawDescGZIP(), []int{0}
+}
+
+type CapabilitiesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the driver, for logs.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Protocol version implemented: "v1".
+	ProtocolVersion string `protobuf:"bytes,2,opt,name=protocol_version,json=protocolVersion,proto3" json:"protocol_version,omitempty"`
+	// Optional features.
+	Capabilities  []Capability `protobuf:"varint,3,rep,packed,name=capabilities,proto3,enum=recluster.plugin.v1.Capability" json:"capabilities,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CapabilitiesResponse) Reset() {
+	*x = CapabilitiesResponse{}
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CapabilitiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CapabilitiesResponse) ProtoMessage() {}
+
+func (x *CapabilitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CapabilitiesResponse.ProtoReflect.Descriptor instead.
+func (*CapabilitiesResponse) Descriptor() ([]byte, []int) {
+	return file_apis_plugin_v1_backend_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CapabilitiesResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CapabilitiesResponse) GetProtocolVersion() string {
+	if x != nil {
+		return x.ProtocolVersion
+	}
+	return ""
+}
+
+func (x *CapabilitiesResponse) GetCapabilities() []Capability {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+// Machine is the RcNode a call is about.
+type Machine struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Namespace string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Name      string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// spec.providerID the Node of this machine must carry (if the plugin
+	// creates the Node) so that the manager finds it.
+	ProviderId string `protobuf:"bytes,3,opt,name=provider_id,json=providerId,proto3" json:"provider_id,omitempty"`
+	NodePool   string `protobuf:"bytes,4,opt,name=node_pool,json=nodePool,proto3" json:"node_pool,omitempty"`
+	// spec.address
+	Address string `protobuf:"bytes,5,opt,name=address,proto3" json:"address,omitempty"`
+	// spec.bootSeconds
+	BootSeconds int32 `protobuf:"varint,6,opt,name=boot_seconds,json=bootSeconds,proto3" json:"boot_seconds,omitempty"`
+	// metadata.annotations, where per-machine driver settings live
+	// (endpoints, credential Secret names, …).
+	Annotations map[string]string   `protobuf:"bytes,7,rep,name=annotations,proto3" json:"annotations,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Interfaces  []*NetworkInterface `protobuf:"bytes,8,rep,name=interfaces,proto3" json:"interfaces,omitempty"`
+	// The whole RcNode as JSON (recluster.com/v1alpha1), for anything not
+	// mirrored above.
+	RcnodeJson    []byte `protobuf:"bytes,15,opt,name=rcnode_json,json=rcnodeJson,proto3" json:"rcnode_json,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Machine) Reset() {
+	*x = Machine{}
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Machine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Machine) ProtoMessage() {}
+
+func (x *Machine) ProtoReflect() protoreflect.Message {
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Machine.ProtoReflect.Descriptor instead.
+func (*Machine) Descriptor() ([]byte, []int) {
+	return file_apis_plugin_v1_backend_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Machine) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *Machine) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Machine) GetProviderId() string {
+	if x != nil {
+		return x.ProviderId
+	}
+	return ""
+}
+
+func (x *Machine) GetNodePool() string {
+	if x != nil {
+		return x.NodePool
+	}
+	return ""
+}
+
+func (x *Machine) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Machine) GetBootSeconds() int32 {
+	if x != nil {
+		return x.BootSeconds
+	}
+	return 0
+}
+
+func (x *Machine) GetAnnotations() map[string]string {
+	if x != nil {
+		return x.Annotations
+	}
+	return nil
+}
+
+func (x *Machine) GetInterfaces() []*NetworkInterface {
+	if x != nil {
+		return x.Interfaces
+	}
+	return nil
+}
+
+func (x *Machine) GetRcnodeJson() []byte {
+	if x != nil {
+		return x.RcnodeJson
+	}
+	return nil
+}
+
+type NetworkInterface struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Name  string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// MAC address
+	Address string `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	// Wake-on-LAN flags (ethtool letters), empty if WoL is off.
+	Wol           []string `protobuf:"bytes,3,rep,name=wol,proto3" json:"wol,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *NetworkInterface) Reset() {
+	*x = NetworkInterface{}
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NetworkInterface) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NetworkInterface) ProtoMessage() {}
+
+func (x *NetworkInterface) ProtoReflect() protoreflect.Message {
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NetworkInterface.ProtoReflect.Descriptor instead.
+func (*NetworkInterface) Descriptor() ([]byte, []int) {
+	return file_apis_plugin_v1_backend_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *NetworkInterface) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *NetworkInterface) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *NetworkInterface) GetWol() []string {
+	if x != nil {
+		return x.Wol
+	}
+	return nil
+}
+
+type PowerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Machine       *Machine               `protobuf:"bytes,1,opt,name=machine,proto3" json:"machine,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PowerRequest) Reset() {
+	*x = PowerRequest{}
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PowerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PowerRequest) ProtoMessage() {}
+
+func (x *PowerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PowerRequest.ProtoReflect.Descriptor instead.
+func (*PowerRequest) Descriptor() ([]byte, []int) {
+	return file_apis_plugin_v1_backend_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *PowerRequest) GetMachine() *Machine {
+	if x != nil {
+		return x.Machine
+	}
+	return nil
+}
+
+type PowerResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PowerResponse) Reset() {
+	*x = PowerResponse{}
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PowerResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PowerResponse) ProtoMessage() {}
+
+func (x *PowerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PowerResponse.ProtoReflect.Descriptor instead.
+func (*PowerResponse) Descriptor() ([]byte, []int) {
+	return file_apis_plugin_v1_backend_proto_rawDescGZIP(), []int{5}
+}
+
+type ObserveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Machine       *Machine               `protobuf:"bytes,1,opt,name=machine,proto3" json:"machine,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ObserveRequest) Reset() {
+	*x = ObserveRequest{}
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ObserveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ObserveRequest) ProtoMessage() {}
+
+func (x *ObserveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ObserveRequest.ProtoReflect.Descriptor instead.
+func (*ObserveRequest) Descriptor() ([]byte, []int) {
+	return file_apis_plugin_v1_backend_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ObserveRequest) GetMachine() *Machine {
+	if x != nil {
+		return x.Machine
+	}
+	return nil
+}
+
+type ObserveResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Power PowerState             `protobuf:"varint,1,opt,name=power,proto3,enum=recluster.plugin.v1.PowerState" json:"power,omitempty"`
+	Boot  BootProgress           `protobuf:"varint,2,opt,name=boot,proto3,enum=recluster.plugin.v1.BootProgress" json:"boot,omitempty"`
+	// Measured draw; unset if the plugin cannot tell.
+	PowerWatts *int32 `protobuf:"varint,3,opt,name=power_watts,json=powerWatts,proto3,oneof" json:"power_watts,omitempty"`
+	// Last sign of life; unset if the plugin has none.
+	Heartbeat *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=heartbeat,proto3" json:"heartbeat,omitempty"`
+	// Last failure of a power action, empty if it succeeded.
+	LastError string `protobuf:"bytes,5,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	// When the next change worth observing is due (a boot completing, …).
+	Recheck       *durationpb.Duration `protobuf:"bytes,6,opt,name=recheck,proto3" json:"recheck,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ObserveResponse) Reset() {
+	*x = ObserveResponse{}
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ObserveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ObserveResponse) ProtoMessage() {}
+
+func (x *ObserveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_apis_plugin_v1_backend_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ObserveResponse.ProtoReflect.Descriptor instead.
+func (*ObserveResponse) Descriptor() ([]byte, []int) {
+	return file_apis_plugin_v1_backend_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ObserveResponse) GetPower() PowerState {
+	if x != nil {
+		return x.Power
+	}
+	return PowerState_POWER_STATE_UNSPECIFIED
+}
+
+func (x *ObserveResponse) GetBoot() BootProgress {
+	if x != nil {
+		return x.Boot
+	}
+	return BootProgress_BOOT_PROGRESS_UNSPECIFIED
+}
+
+func (x *ObserveResponse) GetPowerWatts() int32 {
+	if x != nil && x.PowerWatts != nil {
+		return *x.PowerWatts
+	}
+	return 0
+}
+
+func (x *ObserveResponse) GetHeartbeat() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Heartbeat
+	}
+	return nil
+}
+
+func (x *ObserveResponse) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *ObserveResponse) GetRecheck() *durationpb.Duration {
+	if x != nil {
+		return x.Recheck
+	}
+	return nil
+}
+
+var File_apis_plugin_v1_backend_proto protoreflect.FileDescriptor
+
+const file_apis_plugin_v1_backend_proto_rawDesc = "" +
+	"\n" +
+	"\x1capis/plugin/v1/backend.proto\x12\x13recluster.plugin.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x15\n" +
+	"\x13CapabilitiesRequest\"\x9a\x01\n" +
+	"\x14CapabilitiesResponse\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12)\n" +
+	"\x10protocol_version\x18\x02 \x01(\tR\x0fprotocolVersion\x12C\n" +
+	"\fcapabilities\x18\x03 \x03(\x0e2\x1f.recluster.plugin.v1.CapabilityR\fcapabilities\"\xaf\x03\n" +
+	"\aMachine\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1f\n" +
+	"\vprovider_id\x18\x03 \x01(\tR\n" +
+	"providerId\x12\x1b\n" +
+	"\tnode_pool\x18\x04 \x01(\tR\bnodePool\x12\x18\n" +
+	"\aaddress\x18\x05 \x01(\tR\aaddress\x12!\n" +
+	"\fboot_seconds\x18\x06 \x01(\x05R\vbootSeconds\x12O\n" +
+	"\vannotations\x18\a \x03(\v2-.recluster.plugin.v1.Machine.AnnotationsEntryR\vannotations\x12E\n" +
+	"\n" +
+	"interfaces\x18\b \x03(\v2%.recluster.plugin.v1.NetworkInterfaceR\n" +
+	"interfaces\x12\x1f\n" +
+	"\vrcnode_json\x18\x0f \x01(\fR\n" +
+	"rcnodeJson\x1a>\n" +
+	"\x10AnnotationsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"R\n" +
+	"\x10NetworkInterface\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x10\n" +
+	"\x03wol\x18\x03 \x03(\tR\x03wol\"F\n" +
+	"\fPowerRequest\x126\n" +
+	"\amachine\x18\x01 \x01(\v2\x1c.recluster.plugin.v1.MachineR\amachine\"\x0f\n" +
+	"\rPowerResponse\"H\n" +
+	"\x0eObserveRequest\x126\n" +
+	"\amachine\x18\x01 \x01(\v2\x1c.recluster.plugin.v1.MachineR\amachine\"\xc3\x02\n" +
+	"\x0fObserveResponse\x125\n" +
+	"\x05power\x18\x01 \x01(\x0e2\x1f.recluster.plugin.v1.PowerStateR\x05power\x125\n" +
+	"\x04boot\x18\x02 \x01(\x0e2!.recluster.plugin.v1.BootProgressR\x04boot\x12$\n" +
+	"\vpower_watts\x18\x03 \x01(\x05H\x00R\n" +
+	"powerWatts\x88\x01\x01\x128\n" +
+	"\theartbeat\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\theartbeat\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\x05 \x01(\tR\tlastError\x123\n" +
+	"\arecheck\x18\x06 \x01(\v2\x19.google.protobuf.DurationR\arecheckB\x0e\n" +
+	"\f_power_watts*{\n" +
+	"\n" +
+	"Capability\x12\x1a\n" +
+	"\x16CAPABILITY_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12CAPABILITY_OBSERVE\x10\x01\x12\x1c\n" +
+	"\x18CAPABILITY_POWER_READING\x10\x02\x12\x1b\n" +
+	"\x17CAPABILITY_MANAGES_NODE\x10\x03*\xa6\x01\n" +
+	"\n" +
+	"PowerState\x12\x1b\n" +
+	"\x17POWER_STATE_UNSPECIFIED\x10\x00\x12\x12\n" +
+	"\x0ePOWER_STATE_ON\x10\x01\x12\x13\n" +
+	"\x0fPOWER_STATE_OFF\x10\x02\x12\x1b\n" +
+	"\x17POWER_STATE_POWERING_ON\x10\x03\x12\x1c\n" +
+	"\x18POWER_STATE_POWERING_OFF\x10\x04\x12\x17\n" +
+	"\x13POWER_STATE_UNKNOWN\x10\x05*\x85\x01\n" +
+	"\fBootProgress\x12\x1d\n" +
+	"\x19BOOT_PROGRESS_UNSPECIFIED\x10\x00\x12\x1a\n" +
+	"\x16BOOT_PROGRESS_POWER_ON\x10\x01\x12!\n" +
+	"\x1dBOOT_PROGRESS_NODE_REGISTERED\x10\x02\x12\x17\n" +
+	"\x13BOOT_PROGRESS_READY\x10\x032\xed\x02\n" +
+	"\vPowerDriver\x12c\n" +
+	"\fCapabilities\x12(.recluster.plugin.v1.CapabilitiesRequest\x1a).recluster.plugin.v1.CapabilitiesResponse\x12P\n" +
+	"\aPowerOn\x12!.recluster.plugin.v1.PowerRequest\x1a\".recluster.plugin.v1.PowerResponse\x12Q\n" +
+	"\bPowerOff\x12!.recluster.plugin.v1.PowerRequest\x1a\".recluster.plugin.v1.PowerResponse\x12T\n" +
+	"\aObserve\x12#.recluster.plugin.v1.ObserveRequest\x1a$.recluster.plugin.v1.ObserveResponseB=Z;github.com/lcereser6/recluster-sync/apis/plugin/v1;pluginv1b\x06proto3"
+
+var (
+	file_apis_plugin_v1_backend_proto_rawDescOnce sync.Once
+	file_apis_plugin_v1_backend_proto_rawDescData []byte
+)
+
+func file_apis_plugin_v1_backend_proto_rawDescGZIP() []byte {
+	file_apis_plugin_v1_backend_proto_rawDescOnce.Do(func() {
+		file_apis_plugin_v1_backend_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_apis_plugin_v1_backend_proto_rawDesc), len(file_apis_plugin_v1_backend_proto_rawDesc)))
+	})
+	return file_apis_plugin_v1_backend_proto_rawDescData
+}
+
+var file_apis_plugin_v1_backend_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_apis_plugin_v1_backend_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_apis_plugin_v1_backend_proto_goTypes = []any{
+	(Capability)(0),               // 0: recluster.plugin.v1.Capability
+	(PowerState)(0),               // 1: recluster.plugin.v1.PowerState
+	(BootProgress)(0),             // 2: recluster.plugin.v1.BootProgress
+	(*CapabilitiesRequest)(nil),   // 3: recluster.plugin.v1.CapabilitiesRequest
+	(*CapabilitiesResponse)(nil),  // 4: recluster.plugin.v1.CapabilitiesResponse
+	(*Machine)(nil),               // 5: recluster.plugin.v1.Machine
+	(*NetworkInterface)(nil),      // 6: recluster.plugin.v1.NetworkInterface
+	(*PowerRequest)(nil),          // 7: recluster.plugin.v1.PowerRequest
+	(*PowerResponse)(nil),         // 8: recluster.plugin.v1.PowerResponse
+	(*ObserveRequest)(nil),        // 9: recluster.plugin.v1.ObserveRequest
+	(*ObserveResponse)(nil),       // 10: recluster.plugin.v1.ObserveResponse
+	nil,                           // 11: recluster.plugin.v1.Machine.AnnotationsEntry
+	(*timestamppb.Timestamp)(nil), // 12: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),   // 13: google.protobuf.Duration
+}
+var file_apis_plugin_v1_backend_proto_depIdxs = []int32{
+	0,  // 0: recluster.plugin.v1.CapabilitiesResponse.capabilities:type_name -> recluster.plugin.v1.Capability
+	11, // 1: recluster.plugin.v1.Machine.annotations:type_name -> recluster.plugin.v1.Machine.AnnotationsEntry
+	6,  // 2: recluster.plugin.v1.Machine.interfaces:type_name -> recluster.plugin.v1.NetworkInterface
+	5,  // 3: recluster.plugin.v1.PowerRequest.machine:type_name -> recluster.plugin.v1.Machine
+	5,  // 4: recluster.plugin.v1.ObserveRequest.machine:type_name -> recluster.plugin.v1.Machine
+	1,  // 5: recluster.plugin.v1.ObserveResponse.power:type_name -> recluster.plugin.v1.PowerState
+	2,  // 6: recluster.plugin.v1.ObserveResponse.boot:type_name -> recluster.plugin.v1.BootProgress
+	12, // 7: recluster.plugin.v1.ObserveResponse.heartbeat:type_name -> google.protobuf.Timestamp
+	13, // 8: recluster.plugin.v1.ObserveResponse.recheck:type_name -> google.protobuf.Duration
+	3,  // 9: recluster.plugin.v1.PowerDriver.Capabilities:input_type -> recluster.plugin.v1.CapabilitiesRequest
+	7,  // 10: recluster.plugin.v1.PowerDriver.PowerOn:input_type -> recluster.plugin.v1.PowerRequest
+	7,  // 11: recluster.plugin.v1.PowerDriver.PowerOff:input_type -> recluster.plugin.v1.PowerRequest
+	9,  // 12: recluster.plugin.v1.PowerDriver.Observe:input_type -> recluster.plugin.v1.ObserveRequest
+	4,  // 13: recluster.plugin.v1.PowerDriver.Capabilities:output_type -> recluster.plugin.v1.CapabilitiesResponse
+	8,  // 14: recluster.plugin.v1.PowerDriver.PowerOn:output_type -> recluster.plugin.v1.PowerResponse
+	8,  // 15: recluster.plugin.v1.PowerDriver.PowerOff:output_type -> recluster.plugin.v1.PowerResponse
+	10, // 16: recluster.plugin.v1.PowerDriver.Observe:output_type -> recluster.plugin.v1.ObserveResponse
+	13, // [13:17] is the sub-list for method output_type
+	9,  // [9:13] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_apis_plugin_v1_backend_proto_init() }
+func file_apis_plugin_v1_backend_proto_init() {
+	if File_apis_plugin_v1_backend_proto != nil {
+		return
+	}
+	file_apis_plugin_v1_backend_proto_msgTypes[7].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_apis_plugin_v1_backend_proto_rawDesc), len(file_apis_plugin_v1_backend_proto_rawDesc)),
+			NumEnums:      3,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_apis_plugin_v1_backend_proto_goTypes,
+		DependencyIndexes: file_apis_plugin_v1_backend_proto_depIdxs,
+		EnumInfos:         file_apis_plugin_v1_backend_proto_enumTypes,
+		MessageInfos:      file_apis_plugin_v1_backend_proto_msgTypes,
+	}.Build()
+	File_apis_plugin_v1_backend_proto = out.File
+	file_apis_plugin_v1_backend_proto_goTypes = nil
+	file_apis_plugin_v1_backend_proto_depIdxs = nil
+}
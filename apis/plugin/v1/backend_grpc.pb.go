@@ -0,0 +1,265 @@
+// Out-of-process power drivers for recluster-sync.
+//
+// The manager is the client: for every RcNode whose driver names a plugin it
+// calls PowerOn or PowerOff (from Spec.DesiredState) on each reconcile, then
+// Observe. Calls are level-triggered and repeated, so a plugin must treat
+// PowerOn on a machine that is already on (or booting) as a no-op, and
+// likewise for PowerOff.
+//
+// Regenerate the Go code with `make proto`.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: apis/plugin/v1/backend.proto
+
+package pluginv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	PowerDriver_Capabilities_FullMethodName = "/recluster.plugin.v1.PowerDriver/Capabilities"
+	PowerDriver_PowerOn_FullMethodName      = "/recluster.plugin.v1.PowerDriver/PowerOn"
+	PowerDriver_PowerOff_FullMethodName     = "/recluster.plugin.v1.PowerDriver/PowerOff"
+	PowerDriver_Observe_FullMethodName      = "/recluster.plugin.v1.PowerDriver/Observe"
+)
+
+// PowerDriverClient is the client API for PowerDriver service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// PowerDriver powers the machines behind RcNodes on and off and reports what
+// it sees of them. Failures of the machine or its controller are reported as
+// UNKNOWN, INTERNAL or FAILED_PRECONDITION; UNAVAILABLE means the plugin itself
+// is going away, and the manager asks for its capabilities again.
+type PowerDriverClient interface {
+	// Capabilities is called before any other call; the manager refuses a
+	// plugin whose protocol_version it does not speak.
+	Capabilities(ctx context.Context, in *CapabilitiesRequest, opts ...grpc.CallOption) (*CapabilitiesResponse, error)
+	// PowerOn starts the machine (no-op if it is on or booting).
+	PowerOn(ctx context.Context, in *PowerRequest, opts ...grpc.CallOption) (*PowerResponse, error)
+	// PowerOff shuts the machine down (no-op if it is off or shutting down).
+	PowerOff(ctx context.Context, in *PowerRequest, opts ...grpc.CallOption) (*PowerResponse, error)
+	// Observe reports the machine's power state and boot progress.
+	Observe(ctx context.Context, in *ObserveRequest, opts ...grpc.CallOption) (*ObserveResponse, error)
+}
+
+type powerDriverClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPowerDriverClient(cc grpc.ClientConnInterface) PowerDriverClient {
+	return &powerDriverClient{cc}
+}
+
+func (c *powerDriverClient) Capabilities(ctx context.Context, in *CapabilitiesRequest, opts ...grpc.CallOption) (*CapabilitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CapabilitiesResponse)
+	err := c.cc.Invoke(ctx, PowerDriver_Capabilities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *powerDriverClient) PowerOn(ctx context.Context, in *PowerRequest, opts ...grpc.CallOption) (*PowerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PowerResponse)
+	err := c.cc.Invoke(ctx, PowerDriver_PowerOn_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *powerDriverClient) PowerOff(ctx context.Context, in *PowerRequest, opts ...grpc.CallOption) (*PowerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PowerResponse)
+	err := c.cc.Invoke(ctx, PowerDriver_PowerOff_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *powerDriverClient) Observe(ctx context.Context, in *ObserveRequest, opts ...grpc.CallOption) (*ObserveResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ObserveResponse)
+	err := c.cc.Invoke(ctx, PowerDriver_Observe_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PowerDriverServer is the server API for PowerDriver service.
+// All implementations must embed UnimplementedPowerDriverServer
+// for forward compatibility.
+//
+// PowerDriver powers the machines behind RcNodes on and off and reports what
+// it sees of them. Failures of the machine or its controller are reported as
+// UNKNOWN, INTERNAL or FAILED_PRECONDITION; UNAVAILABLE means the plugin itself
+// is going away, and the manager asks for its capabilities again.
+type PowerDriverServer interface {
+	// Capabilities is called before any other call; the manager refuses a
+	// plugin whose protocol_version it does not speak.
+	Capabilities(context.Context, *CapabilitiesRequest) (*CapabilitiesResponse, error)
+	// PowerOn starts the machine (no-op if it is on or booting).
+	PowerOn(context.Context, *PowerRequest) (*PowerResponse, error)
+	// PowerOff shuts the machine down (no-op if it is off or shutting down).
+	PowerOff(context.Context, *PowerRequest) (*PowerResponse, error)
+	// Observe reports the machine's power state and boot progress.
+	Observe(context.Context, *ObserveRequest) (*ObserveResponse, error)
+	mustEmbedUnimplementedPowerDriverServer()
+}
+
+// UnimplementedPowerDriverServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedPowerDriverServer struct{}
+
+func (UnimplementedPowerDriverServer) Capabilities(context.Context, *CapabilitiesRequest) (*CapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Capabilities not implemented")
+}
+func (UnimplementedPowerDriverServer) PowerOn(context.Context, *PowerRequest) (*PowerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PowerOn not implemented")
+}
+func (UnimplementedPowerDriverServer) PowerOff(context.Context, *PowerRequest) (*PowerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PowerOff not implemented")
+}
+func (UnimplementedPowerDriverServer) Observe(context.Context, *ObserveRequest) (*ObserveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Observe not implemented")
+}
+func (UnimplementedPowerDriverServer) mustEmbedUnimplementedPowerDriverServer() {}
+func (UnimplementedPowerDriverServer) testEmbeddedByValue()                     {}
+
+// UnsafePowerDriverServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PowerDriverServer will
+// result in compilation errors.
+type UnsafePowerDriverServer interface {
+	mustEmbedUnimplementedPowerDriverServer()
+}
+
+func RegisterPowerDriverServer(s grpc.ServiceRegistrar, srv PowerDriverServer) {
+	// If the following call pancis, it indicates UnimplementedPowerDriverServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&PowerDriver_ServiceDesc, srv)
+}
+
+func _PowerDriver_Capabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PowerDriverServer).Capabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PowerDriver_Capabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PowerDriverServer).Capabilities(ctx, req.(*CapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PowerDriver_PowerOn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PowerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PowerDriverServer).PowerOn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PowerDriver_PowerOn_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PowerDriverServer).PowerOn(ctx, req.(*PowerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PowerDriver_PowerOff_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PowerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PowerDriverServer).PowerOff(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PowerDriver_PowerOff_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PowerDriverServer).PowerOff(ctx, req.(*PowerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PowerDriver_Observe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ObserveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PowerDriverServer).Observe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PowerDriver_Observe_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PowerDriverServer).Observe(ctx, req.(*ObserveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PowerDriver_ServiceDesc is the grpc.ServiceDesc for PowerDriver service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PowerDriver_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "recluster.plugin.v1.PowerDriver",
+	HandlerType: (*PowerDriverServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Capabilities",
+			Handler:    _PowerDriver_Capabilities_Handler,
+		},
+		{
+			MethodName: "PowerOn",
+			Handler:    _PowerDriver_PowerOn_Handler,
+		},
+		{
+			MethodName: "PowerOff",
+			Handler:    _PowerDriver_PowerOff_Handler,
+		},
+		{
+			MethodName: "Observe",
+			Handler:    _PowerDriver_Observe_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "apis/plugin/v1/backend.proto",
+}
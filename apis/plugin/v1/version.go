@@ -0,0 +1,6 @@
+// Package pluginv1 is the gRPC protocol between the recluster-sync manager
+// and out-of-process power drivers (see backend.proto).
+package pluginv1
+
+// ProtocolVersion is what CapabilitiesResponse.protocol_version must say.
+const ProtocolVersion = "v1"
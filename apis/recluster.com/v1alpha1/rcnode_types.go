@@ -18,13 +18,25 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // +genclient
 
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.state`
+// +kubebuilder:printcolumn:name="Desired",type=string,JSONPath=`.spec.desiredState`
+// +kubebuilder:printcolumn:name="Pool",type=string,JSONPath=`.spec.nodePool`
+// +kubebuilder:printcolumn:name="Driver",type=string,JSONPath=`.status.driver`,priority=1
+// +kubebuilder:printcolumn:name="Watts",type=integer,JSONPath=`.status.predictedPowerWatts`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RcNode is a machine the planner may power on and off for pods. Its name
+// must be unique across namespaces: pods, tolerations and Nodes refer to it
+// by name alone.
 type RcNode struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -49,6 +61,9 @@ type RcNodeSpec struct {
 	Memory  int64                 `json:"memoryBytes"` // bytes to avoid GiB rounding issues
 	Storage []RcNodeStorageSpec   `json:"storages,omitempty"`
 	Network []RcNodeInterfaceSpec `json:"interfaces,omitempty"`
+	// ExtendedResources the machine offers besides CPU, memory and storage
+	// (nvidia.com/gpu: 2, …); only pods requesting no more of them fit.
+	ExtendedResources corev1.ResourceList `json:"extendedResources,omitempty"`
 
 	/* ---------- power‑consumption model ---------- */
 	MinPowerConsumption            int                   `json:"minPowerConsumption,omitempty"`
@@ -60,6 +75,10 @@ type RcNodeSpec struct {
 	/* ---------- lifecycle control ---------- */
 	BootSeconds  int    `json:"bootSeconds,omitempty"`  // 0 = powered off
 	DesiredState string `json:"desiredState,omitempty"` // "Running" | "Stopped" | etc.
+	// Driver names the power backend (kwok | prod | redfish | test, or a
+	// plugin registered with the manager); empty falls back to the pool's
+	// driver, then to the manager's default.
+	Driver string `json:"driver,omitempty"`
 }
 
 /* -------------------------------------------------------------------------- */
@@ -77,8 +96,47 @@ type RcNodeStatus struct {
 	UtilizationPct      float64      `json:"utilizationPct,omitempty"`      // derived percentage (0–100)
 	PredictedPowerWatts int          `json:"predictedPowerWatts,omitempty"` // interpolated from curve
 	ObservedPowerWatts  *int         `json:"observedPowerWatts,omitempty"`  // optional real‑time reading
+
+	// Driver is the power backend the RcNode was last reconciled with.
+	Driver string `json:"driver,omitempty"`
+	// ObservedGeneration is the .metadata.generation the conditions describe.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions: PoweredOn, NodeRegistered, Ready, BackendError, DriverResolved,
+	// DrainBlocked.
+	// +listType=map
+	// +listMapKey=type
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
+// Condition types on RcNodeStatus.Conditions.
+const (
+	// RcNodeConditionPoweredOn – the machine is (being) powered on.
+	RcNodeConditionPoweredOn = "PoweredOn"
+	// RcNodeConditionNodeRegistered – a Kubernetes Node exists for the RcNode.
+	RcNodeConditionNodeRegistered = "NodeRegistered"
+	// RcNodeConditionReady – that Node is Ready.
+	RcNodeConditionReady = "Ready"
+	// RcNodeConditionBackendError – the last backend call failed.
+	RcNodeConditionBackendError = "BackendError"
+	// RcNodeConditionDriverResolved – the RcNode's driver exists and is usable.
+	RcNodeConditionDriverResolved = "DriverResolved"
+	// RcNodeConditionDrainBlocked – pods no controller would recreate keep
+	// the Node from draining; the machine stays on until they are gone.
+	RcNodeConditionDrainBlocked = "DrainBlocked"
+)
+
+const (
+	// RcNodeFinalizer holds a deleted RcNode until its Node is drained and
+	// removed and its machine is powered off.
+	RcNodeFinalizer = "recluster.io/cleanup"
+	// AnnotationForceDelete set to "true" releases a deleted RcNode at once,
+	// leaving its Node and machine as they are.
+	AnnotationForceDelete = "recluster.io/force-delete"
+)
+
 /* -------------------------------------------------------------------------- */
 /*                         Power‑consumption modelling                        */
 /* -------------------------------------------------------------------------- */
@@ -150,6 +208,12 @@ const (
 	NodePermissionUnknown NodePermission = "UNKNOWN"
 )
 
+// Values understood in RcNodeSpec.DesiredState.
+const (
+	DesiredStateRunning = "Running"
+	DesiredStateStopped = "Stopped"
+)
+
 type NodeStatus string
 
 const (
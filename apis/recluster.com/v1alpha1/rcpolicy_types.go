@@ -20,12 +20,17 @@ package v1alpha1
 import (
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 )
 
 // +genclient
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Valid",type=string,JSONPath=`.status.conditions[?(@.type=="Valid")].status`
+// +kubebuilder:printcolumn:name="Feeds",type=string,JSONPath=`.status.conditions[?(@.type=="FeedsHealthy")].status`
+// +kubebuilder:printcolumn:name="Pods",type=integer,JSONPath=`.status.matchedPods`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 //
 // RcPolicy describes *how* the scheduler/extender should rank candidate
@@ -68,11 +73,34 @@ type RcPolicySpec struct {
 	// List of metrics that feed the scoring function.
 	Metrics []PolicyMetric `json:"metrics"`
 
+	// Mode is how the metrics rank the candidate nodes: weightedSum (the
+	// default), lexicographic (metrics in list order, a later one only
+	// breaking the ties of the earlier ones) or pareto (the nodes no other
+	// node beats on every metric, ordered by TieBreaker). In the last two
+	// only the sign of a weight matters. Remaining ties go to the node
+	// whose name sorts first.
+	// +kubebuilder:validation:Enum=weightedSum;lexicographic;pareto
+	// +optional
+	Mode RankingMode `json:"mode,omitempty"`
+
+	// TieBreaker orders the Pareto front: weightedSum (default) or
+	// lexicographic.
+	// +kubebuilder:validation:Enum=weightedSum;lexicographic
+	// +optional
+	TieBreaker RankingMode `json:"tieBreaker,omitempty"`
+
 	// Hard constraints – CEL expressions evaluated per candidate assignment.
 	// If any evaluates to *false* the node is rejected.
 	// +optional
 	HardConstraints []PolicyConstraint `json:"hardConstraints,omitempty"`
 
+	// Soft constraints – CEL expressions a node should, but need not,
+	// satisfy. Each one that does not evaluate to *true* adds its Penalty
+	// to the node's score, so the best node is still picked when none is
+	// perfect.
+	// +optional
+	SoftConstraints []PolicySoftConstraint `json:"softConstraints,omitempty"`
+
 	// Optional time‑based overrides.
 	// The first entry whose window contains *now()* overrides the base metric
 	// definitions (weight/multiplier). Think of them as “profiles”.
@@ -88,6 +116,15 @@ type RcPolicySpec struct {
 
 /* --------------------------- Metrics & helpers ---------------------------- */
 
+// RankingMode selects how RcPolicySpec.Metrics rank the candidate nodes.
+type RankingMode string
+
+const (
+	RankWeightedSum   RankingMode = "weightedSum"
+	RankLexicographic RankingMode = "lexicographic"
+	RankPareto        RankingMode = "pareto"
+)
+
 // ValueFrom declares where a metric is read from inside RcNode.
 // • jsonPath  – default, evaluated against the *whole* RcNode object.
 // • fieldPath – uses the downward‑API syntax (metadata.labels['x'] …).
@@ -101,13 +138,25 @@ const (
 	ValueFromFieldPath ValueFrom = "fieldPath"
 )
 
+// Normalization rescales a metric across the candidate nodes of one pod
+// before it is weighted.
+type Normalization string
+
+const (
+	NormalizeNone   Normalization = "none"   // the value as is (default)
+	NormalizeMinMax Normalization = "minMax" // (x − min) / (max − min): 0 … 1
+	NormalizeZScore Normalization = "zScore" // (x − mean) / standard deviation
+	NormalizeRank   Normalization = "rank"   // position of x, lowest 0 … highest 1
+	NormalizeLog    Normalization = "log"    // log(1 + x), sign kept
+)
+
 type PolicyMetric struct {
 	// Key is just a symbolic handle used by policies/schedule/feed mappings.
 	Key string `json:"key"`
 
-	// Weight – if you stick to the weighted‑sum model, positive means
-	// “*minimise* this metric”, negative means “*maximise*”.
-	// If you switch to lexicographic ordering the runtime can ignore it.
+	// Weight – in the weighted‑sum model, positive means “*minimise* this
+	// metric”, negative means “*maximise*”. The lexicographic and pareto
+	// modes only look at its sign; a zero weight leaves the metric out.
 	Weight float64 `json:"weight"`
 
 	// Source + Selector tell the runtime *where* to fetch the value.
@@ -122,6 +171,20 @@ type PolicyMetric struct {
 	// e.g. "min(x, 180)" where `x` is the fetched value.
 	// +optional
 	Transform *string `json:"transform,omitempty"`
+
+	// Normalize rescales the (transformed) value over the nodes that can
+	// take the pod before it is weighted, so that metrics in different
+	// units (bytes, seconds, watts) are weighed in comparable terms.
+	// +kubebuilder:validation:Enum=none;minMax;zScore;rank;log
+	// +optional
+	Normalize Normalization `json:"normalize,omitempty"`
+
+	// Tolerance – lexicographic mode: nodes whose (normalized) value is
+	// within Tolerance of the best one tie on this metric and are told
+	// apart by the next.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Tolerance float64 `json:"tolerance,omitempty"`
 }
 
 // PolicyConstraint is unchanged: a CEL boolean that must hold true.
@@ -130,6 +193,19 @@ type PolicyConstraint struct {
 	Expression string `json:"expression"`
 }
 
+// PolicySoftConstraint is a CEL boolean that should hold true, e.g.
+// "spec.nodePool == 'edge'".
+type PolicySoftConstraint struct {
+	Expression string `json:"expression"`
+
+	// Penalty added to the score of a node the expression does not hold
+	// for, in the units of the weighted score. The lexicographic mode
+	// compares the summed penalties before any metric, the pareto mode
+	// treats them as one more metric to minimise.
+	// +kubebuilder:validation:Minimum=0
+	Penalty float64 `json:"penalty"`
+}
+
 /* --------------------------- Time‑based overrides ------------------------- */
 
 // PolicyScheduleEntry replaces/adjusts metric weights in a given window.
@@ -172,7 +248,42 @@ type MetricAdjustment struct {
 
 type ExternalFeedRef struct {
 	Name string `json:"name"` // unique identifier inside the policy
-	URL  string `json:"url"`  // pull endpoint (or k8s://cm/secret/... later)
+	// URL is either an http(s) pull endpoint or a cluster-local object:
+	//   k8s://configmap/<namespace>/<name>/<key>
+	//   k8s://secret/<namespace>/<name>/<key>
+	// Cluster-local objects are only read from the namespaces the operator
+	// opened to feeds.
+	URL string `json:"url"`
+
+	// BearerTokenSecretRef, for http(s) feeds, names a Secret key in the
+	// policy namespace whose content is sent as "Authorization: Bearer …";
+	// that namespace must be open to feeds.
+	// +optional
+	BearerTokenSecretRef *corev1.SecretKeySelector `json:"bearerTokenSecretRef,omitempty"`
+
+	// How the fetched body is read (first one set wins):
+	//   prometheus → text exposition format, see PrometheusSelector
+	//   jsonPath   → number inside a JSON document, e.g. "$.data.price"
+	//   neither    → the whole body is the number (JSON or plain text)
+	// +optional
+	Prometheus *PrometheusSelector `json:"prometheus,omitempty"`
+	// +optional
+	JSONPath string `json:"jsonPath,omitempty"`
+
+	// Interval between two fetches (default 5m).
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// Timeout of a single fetch (default 10s).
+	// +optional
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+	// TTL after which the stored value is stale (default 3 × interval).
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+	// FallbackMultiplier replaces every mapping while the feed is stale, has
+	// never been read or a mapping fails on its value. Unset → such a feed is
+	// simply ignored.
+	// +optional
+	FallbackMultiplier *float64 `json:"fallbackMultiplier,omitempty"`
 
 	// How each metric reacts to the feed value – a CEL expression that receives
 	// the variable `$value` (float64) and outputs the multiplier.
@@ -180,6 +291,53 @@ type ExternalFeedRef struct {
 	Mappings []FeedMetricMapping `json:"mappings"`
 }
 
+// PrometheusSelector picks one sample out of a Prometheus text exposition.
+type PrometheusSelector struct {
+	// Metric is the metric (family) name, e.g. "grid_carbon_intensity".
+	Metric string `json:"metric"`
+	// Labels must all match; exactly one sample may remain.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Feed defaults.
+const (
+	DefaultFeedInterval = 5 * time.Minute
+	DefaultFeedTimeout  = 10 * time.Second
+)
+
+// IntervalOrDefault returns Interval, or DefaultFeedInterval when unset.
+func (f *ExternalFeedRef) IntervalOrDefault() time.Duration {
+	if f.Interval != nil && f.Interval.Duration > 0 {
+		return f.Interval.Duration
+	}
+	return DefaultFeedInterval
+}
+
+// TimeoutOrDefault returns Timeout, or DefaultFeedTimeout when unset.
+func (f *ExternalFeedRef) TimeoutOrDefault() time.Duration {
+	if f.Timeout != nil && f.Timeout.Duration > 0 {
+		return f.Timeout.Duration
+	}
+	return DefaultFeedTimeout
+}
+
+// TTLOrDefault returns TTL, or three intervals when unset.
+func (f *ExternalFeedRef) TTLOrDefault() time.Duration {
+	if f.TTL != nil && f.TTL.Duration > 0 {
+		return f.TTL.Duration
+	}
+	return 3 * f.IntervalOrDefault()
+}
+
+// Fresh reports whether st holds a value younger than the feed's TTL.
+func (f *ExternalFeedRef) Fresh(st *FeedStatus, now time.Time) bool {
+	if st == nil || st.Value == nil || st.LastUpdated == nil {
+		return false
+	}
+	return now.Sub(st.LastUpdated.Time) <= f.TTLOrDefault()
+}
+
 type FeedMetricMapping struct {
 	Key       string `json:"key"`       // metric to alter
 	Transform string `json:"transform"` // CEL producing the multiplier
@@ -193,6 +351,54 @@ type RcPolicyStatus struct {
 	LastResolved metav1.Time `json:"lastResolved,omitempty"`
 	// Last time an external feed was updated.
 	LastFeedSync *metav1.Time `json:"lastFeedSync,omitempty"`
+
+	// ObservedGeneration is the .metadata.generation the controller last
+	// compiled.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// CompileErrors lists every CEL expression that failed to type-check.
+	// A policy with compile errors is never used for scoring.
+	CompileErrors []string `json:"compileErrors,omitempty"`
+
+	// Feeds holds the latest reading of every spec.externalFeeds entry.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Feeds []FeedStatus `json:"feeds,omitempty"`
+
+	// Conditions: Valid, FeedsHealthy, InUse.
+	// +listType=map
+	// +listMapKey=type
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// Condition types on RcPolicyStatus.Conditions.
+const (
+	// RcPolicyConditionValid – every CEL expression compiles.
+	RcPolicyConditionValid = "Valid"
+	// RcPolicyConditionFeedsHealthy – every external feed has a fresh value.
+	RcPolicyConditionFeedsHealthy = "FeedsHealthy"
+	// RcPolicyConditionInUse – at least one managed Pod resolves to the policy.
+	RcPolicyConditionInUse = "InUse"
+)
+
+// FeedStatus is the last known value of one ExternalFeedRef (matched by name).
+type FeedStatus struct {
+	Name string `json:"name"`
+	// Value is the last numeric value read from the feed.
+	// +optional
+	Value *float64 `json:"value,omitempty"`
+	// LastUpdated is when Value was last refreshed.
+	// +optional
+	LastUpdated *metav1.Time `json:"lastUpdated,omitempty"`
+	// LastAttempt is when the feed was last fetched, successfully or not.
+	// +optional
+	LastAttempt *metav1.Time `json:"lastAttempt,omitempty"`
+	// LastError is the error of the last attempt ("" when it succeeded).
+	// +optional
+	LastError string `json:"lastError,omitempty"`
 }
 
 /* ------------------------------ Runtime helpers -------------------------- */
@@ -208,8 +414,8 @@ func (p *RcPolicy) CompiledSelector() (labels.Selector, error) {
 // ActiveSchedule returns the first schedule window that contains *now*.
 // Returns (nil, false) if no window matches.
 func (p *RcPolicy) ActiveSchedule(now time.Time) (*PolicyScheduleEntry, bool) {
-	local := now
 	for _, s := range p.Spec.Schedule {
+		local := now
 		start, err := time.Parse("15:04", s.Start)
 		if err != nil {
 			continue // ignore malformed windows
@@ -234,6 +440,16 @@ func (p *RcPolicy) ActiveSchedule(now time.Time) (*PolicyScheduleEntry, bool) {
 	return nil, false
 }
 
+// Feed returns the stored status of the named feed, or nil.
+func (s *RcPolicyStatus) Feed(name string) *FeedStatus {
+	for i := range s.Feeds {
+		if s.Feeds[i].Name == name {
+			return &s.Feeds[i]
+		}
+	}
+	return nil
+}
+
 /* ------------------------------ List type -------------------------------- */
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -21,6 +21,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -28,6 +29,36 @@ import (
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExternalFeedRef) DeepCopyInto(out *ExternalFeedRef) {
 	*out = *in
+	if in.BearerTokenSecretRef != nil {
+		in, out := &in.BearerTokenSecretRef, &out.BearerTokenSecretRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Prometheus != nil {
+		in, out := &in.Prometheus, &out.Prometheus
+		*out = new(PrometheusSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.FallbackMultiplier != nil {
+		in, out := &in.FallbackMultiplier, &out.FallbackMultiplier
+		*out = new(float64)
+		**out = **in
+	}
 	if in.Mappings != nil {
 		in, out := &in.Mappings, &out.Mappings
 		*out = make([]FeedMetricMapping, len(*in))
@@ -60,6 +91,34 @@ func (in *FeedMetricMapping) DeepCopy() *FeedMetricMapping {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FeedStatus) DeepCopyInto(out *FeedStatus) {
+	*out = *in
+	if in.Value != nil {
+		in, out := &in.Value, &out.Value
+		*out = new(float64)
+		**out = **in
+	}
+	if in.LastUpdated != nil {
+		in, out := &in.LastUpdated, &out.LastUpdated
+		*out = (*in).DeepCopy()
+	}
+	if in.LastAttempt != nil {
+		in, out := &in.LastAttempt, &out.LastAttempt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FeedStatus.
+func (in *FeedStatus) DeepCopy() *FeedStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FeedStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricAdjustment) DeepCopyInto(out *MetricAdjustment) {
 	*out = *in
@@ -142,6 +201,43 @@ func (in *PolicyScheduleEntry) DeepCopy() *PolicyScheduleEntry {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicySoftConstraint) DeepCopyInto(out *PolicySoftConstraint) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicySoftConstraint.
+func (in *PolicySoftConstraint) DeepCopy() *PolicySoftConstraint {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicySoftConstraint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrometheusSelector) DeepCopyInto(out *PrometheusSelector) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrometheusSelector.
+func (in *PrometheusSelector) DeepCopy() *PrometheusSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(PrometheusSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RcNode) DeepCopyInto(out *RcNode) {
 	*out = *in
@@ -317,6 +413,13 @@ func (in *RcNodeSpec) DeepCopyInto(out *RcNodeSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ExtendedResources != nil {
+		in, out := &in.ExtendedResources, &out.ExtendedResources
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
 	if in.MaxEfficiencyPowerConsumption != nil {
 		in, out := &in.MaxEfficiencyPowerConsumption, &out.MaxEfficiencyPowerConsumption
 		*out = new(int)
@@ -360,6 +463,13 @@ func (in *RcNodeStatus) DeepCopyInto(out *RcNodeStatus) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RcNodeStatus.
@@ -466,6 +576,11 @@ func (in *RcPolicySpec) DeepCopyInto(out *RcPolicySpec) {
 		*out = make([]PolicyConstraint, len(*in))
 		copy(*out, *in)
 	}
+	if in.SoftConstraints != nil {
+		in, out := &in.SoftConstraints, &out.SoftConstraints
+		*out = make([]PolicySoftConstraint, len(*in))
+		copy(*out, *in)
+	}
 	if in.Schedule != nil {
 		in, out := &in.Schedule, &out.Schedule
 		*out = make([]PolicyScheduleEntry, len(*in))
@@ -500,6 +615,25 @@ func (in *RcPolicyStatus) DeepCopyInto(out *RcPolicyStatus) {
 		in, out := &in.LastFeedSync, &out.LastFeedSync
 		*out = (*in).DeepCopy()
 	}
+	if in.CompileErrors != nil {
+		in, out := &in.CompileErrors, &out.CompileErrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Feeds != nil {
+		in, out := &in.Feeds, &out.Feeds
+		*out = make([]FeedStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RcPolicyStatus.
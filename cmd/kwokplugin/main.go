@@ -0,0 +1,61 @@
+// cmd/kwokplugin/main.go
+//
+// Reference out-of-process driver: the in-tree KWOK simulation served over
+// the PowerDriver gRPC protocol (apis/plugin/v1). Vendor drivers implement
+// the same service in their own repositories.
+//
+//	go run ./cmd/kwokplugin -listen unix:///run/recluster/kwok-plugin.sock
+//
+// and register it with the manager under a driver name of your choice:
+//
+//	RECLUSTER_PLUGINS=kwok-plugin=unix:///run/recluster/kwok-plugin.sock
+//
+// The simulation is tuned by the same RECLUSTER_KWOK_* variables as the
+// built-in driver.
+
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	pluginv1 "github.com/lcereser6/recluster-sync/apis/plugin/v1"
+	"github.com/lcereser6/recluster-sync/internal/backend"
+)
+
+func main() {
+	var listen, name string
+	flag.StringVar(&listen, "listen", "unix:///run/recluster/kwok-plugin.sock",
+		"unix://<path> or <host>:<port> to serve on")
+	flag.StringVar(&name, "name", "kwok-plugin", "driver name reported to the manager")
+	flag.Parse()
+
+	cfg, err := backend.KwokConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	k8s := kubernetes.NewForConfigOrDie(ctrl.GetConfigOrDie())
+	srv := backend.NewPluginServer(name, backend.NewKwokBackend(k8s, cfg),
+		pluginv1.Capability_CAPABILITY_POWER_READING, pluginv1.Capability_CAPABILITY_MANAGES_NODE)
+
+	network, addr := "tcp", listen
+	if path, ok := strings.CutPrefix(listen, "unix://"); ok {
+		network, addr = "unix", path
+		_ = os.Remove(path) // stale socket of a previous run
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	g := grpc.NewServer()
+	pluginv1.RegisterPowerDriverServer(g, srv)
+	log.Printf("kwok plugin %q: serving %s on %s", name, pluginv1.ProtocolVersion, ln.Addr())
+	log.Fatal(g.Serve(ln))
+}
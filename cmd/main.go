@@ -19,11 +19,14 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth" // enable e.g. GCP, OIDC, Azure …
@@ -43,6 +46,9 @@ import (
 	reclusterv1alpha1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
 	"github.com/lcereser6/recluster-sync/internal/backend"
 	"github.com/lcereser6/recluster-sync/internal/controller"
+	"github.com/lcereser6/recluster-sync/internal/drain"
+	"github.com/lcereser6/recluster-sync/internal/executor"
+	"github.com/lcereser6/recluster-sync/internal/graph"
 	"github.com/lcereser6/recluster-sync/internal/state"
 	wh "github.com/lcereser6/recluster-sync/internal/webhook"
 )
@@ -187,22 +193,81 @@ func main() {
 		os.Exit(1)
 	}
 	log.Info("live state cache registered")
-	// 1. Pick backend from env injected by Helm
-	mode := os.Getenv("RECLUSTER_BACKEND_MODE") // kwok | prod | test
-	be, err := backend.New(mode, kubernetes.NewForConfigOrDie(ctrl.GetConfigOrDie()))
+	// 1. Backend registry from env injected by Helm: default driver
+	//    (RECLUSTER_BACKEND_MODE = kwok | prod | redfish | test), per-pool
+	//    drivers and gRPC plugins (RECLUSTER_PLUGINS); RcNodes may also
+	//    name their own
+	backends, err := backend.RegistryFromEnv(kubernetes.NewForConfigOrDie(ctrl.GetConfigOrDie()))
 	if err != nil {
-		log.Error(err, "invalid backend mode")
+		log.Error(err, "invalid backend configuration")
 		os.Exit(1)
 	}
+	log.Info("backend registry ready", "default", os.Getenv("RECLUSTER_BACKEND_MODE"),
+		"drivers", backends.Drivers())
 
-	// 2. RcNode controller gets the backend
-	if err := controller.NewRcNodeReconciler(mgr, be).SetupWithManager(mgr); err != nil {
+	// pods by node and by assigned RcNode, for the RcNode and Pod controllers
+	if err := drain.IndexPods(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		log.Error(err, "cannot index pods")
+		os.Exit(1)
+	}
+
+	// 2. RcNode controller dispatches to each node's driver, re-observes
+	//    every RECLUSTER_OBSERVE_INTERVAL seconds, drains Nodes for up to
+	//    RECLUSTER_DRAIN_TIMEOUT seconds before powering them off and tears
+	//    deleted RcNodes down within RECLUSTER_DELETION_TIMEOUT seconds
+	rcNodes := controller.NewRcNodeReconciler(mgr, backends)
+	if s := os.Getenv("RECLUSTER_OBSERVE_INTERVAL"); s != "" {
+		secs, err := strconv.Atoi(s)
+		if err != nil {
+			log.Error(err, "invalid RECLUSTER_OBSERVE_INTERVAL", "value", s)
+			os.Exit(1)
+		}
+		rcNodes.Resync = time.Duration(secs) * time.Second
+	}
+	if s := os.Getenv("RECLUSTER_DELETION_TIMEOUT"); s != "" {
+		secs, err := strconv.Atoi(s)
+		if err != nil {
+			log.Error(err, "invalid RECLUSTER_DELETION_TIMEOUT", "value", s)
+			os.Exit(1)
+		}
+		rcNodes.DeletionTimeout = time.Duration(secs) * time.Second
+	}
+	if s := os.Getenv("RECLUSTER_DRAIN_TIMEOUT"); s != "" {
+		secs, err := strconv.Atoi(s)
+		if err != nil {
+			log.Error(err, "invalid RECLUSTER_DRAIN_TIMEOUT", "value", s)
+			os.Exit(1)
+		}
+		rcNodes.DrainTimeout = time.Duration(secs) * time.Second
+	}
+	if err := rcNodes.SetupWithManager(mgr); err != nil {
 		log.Error(err, "cannot set up RcNode controller")
 		os.Exit(1)
 	}
 
-	// 3. (unchanged) add PodReconciler and Planner
-	if err := controller.NewPodReconciler(mgr).SetupWithManager(mgr); err != nil {
+	// 3. RcPolicy controller compiles policies and reports CEL errors
+	if err := controller.NewRcPolicyReconciler(mgr).SetupWithManager(mgr); err != nil {
+		log.Error(err, "cannot set up RcPolicy controller")
+		os.Exit(1)
+	}
+
+	// 3b. Feed controller keeps status.feeds of every RcPolicy up to date;
+	//     ConfigMaps and Secrets are only read from the namespaces listed
+	//     in RECLUSTER_FEED_NAMESPACES (comma-separated)
+	var feedNamespaces []string
+	for _, ns := range strings.Split(os.Getenv("RECLUSTER_FEED_NAMESPACES"), ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			feedNamespaces = append(feedNamespaces, ns)
+		}
+	}
+	if err := controller.NewFeedReconciler(mgr, feedNamespaces).SetupWithManager(mgr); err != nil {
+		log.Error(err, "cannot set up external feed controller")
+		os.Exit(1)
+	}
+
+	// 4. Pod controller lifts the scheduling gate of assigned pods once
+	//    their Node is Ready; the planner assigns them
+	if err := controller.NewPodReconciler(mgr, backends).SetupWithManager(mgr); err != nil {
 		log.Error(err, "cannot set up Pod controller")
 		os.Exit(1)
 	}
@@ -213,13 +278,34 @@ func main() {
 		cooldown = "5" // default cooldown
 	}
 	cooldownInt, err := strconv.Atoi(cooldown)
+	if err != nil {
+		log.Error(err, "invalid RECLUSTER_PLANNER_COOLDOWN", "value", cooldown)
+		os.Exit(1)
+	}
 
 	log.Info("cooldown for planner set to", "seconds", cooldownInt)
 
-	// if err := mgr.Add(graph.NewPlanner(mgr, st, cooldownInt)); err != nil {
-	// 	log.Error(err, "cannot add planner runnable")
-	// 	os.Exit(1)
-	// }
+	// 5. Executor turns planner actions into API calls (dry-run: only record)
+	dryRun := os.Getenv("RECLUSTER_DRY_RUN") == "true"
+	exec := executor.New(mgr, dryRun)
+	log.Info("action executor configured", "dryRun", dryRun)
+
+	// the planner stops a running RcNode once it has had no pods for
+	// RECLUSTER_IDLE_TIMEOUT seconds
+	planner := graph.NewPlanner(mgr, st, cooldownInt)
+	if s := os.Getenv("RECLUSTER_IDLE_TIMEOUT"); s != "" {
+		secs, err := strconv.Atoi(s)
+		if err != nil {
+			log.Error(err, "invalid RECLUSTER_IDLE_TIMEOUT", "value", s)
+			os.Exit(1)
+		}
+		planner.IdleTimeout = time.Duration(secs) * time.Second
+	}
+	planner.Sink = exec.Apply
+	if err := mgr.Add(planner); err != nil {
+		log.Error(err, "cannot add planner runnable")
+		os.Exit(1)
+	}
 	/* =================== extra runnables (certs) ===================== */
 
 	if metricsWatcher != nil {
@@ -0,0 +1,55 @@
+// cmd/redfishmock/main.go
+//
+// Stand-alone Redfish BMC mock, for trying the `redfish` backend without
+// hardware:
+//
+//	go run ./cmd/redfishmock -addr :8443 -systems node-1,node-2
+//
+// then annotate an RcNode with
+//
+//	recluster.io/bmc-endpoint: https://<host>:8443
+//	recluster.io/bmc-system: /redfish/v1/Systems/node-1
+//	recluster.io/bmc-insecure-skip-verify: "true"
+//
+// and point it at a Secret holding the same username / password.
+
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/lcereser6/recluster-sync/internal/backend/redfishmock"
+)
+
+func main() {
+	var addr, user, password, systems string
+	var plain bool
+	flag.StringVar(&addr, "addr", ":8443", "listen address")
+	flag.StringVar(&user, "user", "admin", "BMC username")
+	flag.StringVar(&password, "password", "admin", "BMC password")
+	flag.StringVar(&systems, "systems", "1", "comma-separated ComputerSystem ids")
+	flag.BoolVar(&plain, "plain-http", false, "serve HTTP instead of HTTPS (self-signed)")
+	flag.Parse()
+
+	srv := redfishmock.New(user, password, strings.Split(systems, ",")...)
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("redfish mock: systems %s on %s", systems, ln.Addr())
+	if plain {
+		log.Fatal(http.Serve(ln, srv))
+	}
+	// httptest brings a self-signed certificate for 127.0.0.1 / example.com
+	ts := httptest.NewUnstartedServer(srv)
+	ts.Listener.Close()
+	ts.Listener = ln
+	ts.StartTLS()
+	select {}
+}
@@ -3,8 +3,11 @@ package backend
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 )
@@ -14,22 +17,85 @@ type Backend interface {
 	// Reconcile must make the real world match rc.Spec.DesiredState
 	// – create / patch / delete resources as needed.
 	Reconcile(ctx context.Context, rc *rcv1.RcNode) error
+	// Observe reports what the driver currently sees of rc's machine:
+	// power state, boot progress, power draw and the last Reconcile error.
+	Observe(ctx context.Context, rc *rcv1.RcNode) (lifecycle.Observation, error)
+}
+
+// NodeManager is implemented by drivers that register and remove the
+// Kubernetes Node of a machine themselves (KWOK, the test driver, plugins
+// announcing CAPABILITY_MANAGES_NODE); real machines join the cluster on
+// their own. The manager leaves the Nodes of such drivers to them.
+type NodeManager interface {
+	ManagesNode(ctx context.Context) (bool, error)
+}
+
+// ManagesNode reports whether be registers and removes its Nodes itself.
+func ManagesNode(ctx context.Context, be Backend) (bool, error) {
+	m, ok := be.(NodeManager)
+	if !ok {
+		return false, nil
+	}
+	return m.ManagesNode(ctx)
+}
+
+// ProviderID is the spec.providerID every backend sets on the Kubernetes
+// Node it brings up for rc; the RcNode controller uses it to find that Node.
+func ProviderID(rc *rcv1.RcNode) string {
+	return "recluster://" + rc.Name
+}
+
+// MatchesNode reports whether node is the Kubernetes Node backing rc: it
+// carries our providerID or, if byName (drivers whose machines join the
+// cluster under their hostname, see Registry.ByHostname), it carries no
+// providerID and is named like the RcNode. A Node with another providerID
+// is never rc's.
+func MatchesNode(rc *rcv1.RcNode, node *corev1.Node, byName bool) bool {
+	if node.Spec.ProviderID != "" {
+		return node.Spec.ProviderID == ProviderID(rc)
+	}
+	return byName && node.Name == rc.Name
+}
+
+// lastErrors remembers the outcome of the last Reconcile per RcNode, for
+// Observation.LastError. The zero value is ready to use.
+type lastErrors struct {
+	mu sync.Mutex
+	m  map[string]string // RcNode namespace/name → error
+}
+
+// record stores err (nil clears it) and returns it unchanged.
+func (l *lastErrors) record(rc *rcv1.RcNode, err error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := rc.Namespace + "/" + rc.Name
+	if err == nil {
+		delete(l.m, key)
+		return nil
+	}
+	if l.m == nil {
+		l.m = map[string]string{}
+	}
+	l.m[key] = err.Error()
+	return err
+}
+
+func (l *lastErrors) get(rc *rcv1.RcNode) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.m[rc.Namespace+"/"+rc.Name]
 }
 
 // -----------------------------------------------------------------------------
-// Factory helper – returns the concrete impl selected by MODE env var
+// Factory helper – returns the built-in driver named mode (see Registry for
+// per-node selection)
 // -----------------------------------------------------------------------------
 func New(mode string, k8s kubernetes.Interface) (Backend, error) {
 
 	klog.Infof("Creating Backend using %q mode", mode)
-	switch mode {
-	case "kwok":
-		return NewKwokBackend(k8s), nil
-	case "test":
-		return nil, nil
-	case "prod":
-		return nil, nil
-	default:
+	f, ok := builtins[mode]
+	if !ok {
 		return nil, fmt.Errorf("unknown MODE=%q", mode)
 	}
+	return f(k8s)
 }
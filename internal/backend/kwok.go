@@ -4,12 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -21,26 +26,88 @@ import (
 
 const (
 	kwokManagedAnnotation = "kwok.x-k8s.io/node" // value = "fake"
+
+	// Simulation bookkeeping on the fake Node. While booting or shutting
+	// down the Node is NOT handed to the kwok controller (no
+	// kwokManagedAnnotation), so nothing else flips it Ready.
+	kwokReadyAtAnnotation    = "recluster.io/kwok-ready-at"    // RFC 3339: boot completes
+	kwokBootFailedAnnotation = "recluster.io/kwok-boot-failed" // "true": never gets Ready
+	kwokOffAtAnnotation      = "recluster.io/kwok-off-at"      // RFC 3339: shutdown completes
+
+	// Labels set on fake Nodes besides the well-known ones.
+	LabelCPUVendor   = "recluster.io/cpu-vendor"
+	LabelPool        = "recluster.io/pool"
+	labelCPUIDPrefix = "feature.node.kubernetes.io/cpu-cpuid." // + FLAG, as node-feature-discovery
 )
 
+// ----------------------------------------------------------------------------
+// Configuration
+// ----------------------------------------------------------------------------
+
+// KwokConfig tunes the simulation. Boot takes Spec.BootSeconds.
+type KwokConfig struct {
+	// ShutdownDelay is how long a Node stays NotReady before it is deleted.
+	ShutdownDelay time.Duration
+	// BootFailureRate is the probability (0–1) that a boot hangs: the Node
+	// registers but never becomes Ready.
+	BootFailureRate float64
+}
+
+// KwokConfigFromEnv reads the settings injected by the Helm chart:
+//
+//	RECLUSTER_KWOK_SHUTDOWN_SECONDS    default 0 (instant)
+//	RECLUSTER_KWOK_BOOT_FAILURE_RATE   0–1, default 0
+func KwokConfigFromEnv() (KwokConfig, error) {
+	var cfg KwokConfig
+	if s := os.Getenv("RECLUSTER_KWOK_SHUTDOWN_SECONDS"); s != "" {
+		secs, err := strconv.Atoi(s)
+		if err != nil {
+			return cfg, fmt.Errorf("RECLUSTER_KWOK_SHUTDOWN_SECONDS=%q: %w", s, err)
+		}
+		cfg.ShutdownDelay = time.Duration(secs) * time.Second
+	}
+	if s := os.Getenv("RECLUSTER_KWOK_BOOT_FAILURE_RATE"); s != "" {
+		rate, err := strconv.ParseFloat(s, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return cfg, fmt.Errorf("RECLUSTER_KWOK_BOOT_FAILURE_RATE=%q: want a number in [0,1]", s)
+		}
+		cfg.BootFailureRate = rate
+	}
+	return cfg, nil
+}
+
 // ----------------------------------------------------------------------------
 // Constructor
 // ----------------------------------------------------------------------------
 type kwokBackend struct {
 	core typedcore.CoreV1Interface
+	cfg  KwokConfig
+	now  func() time.Time
+	rand func() float64
+	errs lastErrors
 }
 
-func NewKwokBackend(k8s kubernetes.Interface) *kwokBackend {
-	return &kwokBackend{core: k8s.CoreV1()}
+func NewKwokBackend(k8s kubernetes.Interface, cfg KwokConfig) *kwokBackend {
+	return &kwokBackend{
+		core: k8s.CoreV1(),
+		cfg:  cfg,
+		now:  time.Now,
+		rand: rand.Float64,
+	}
 }
 
 // ----------------------------------------------------------------------------
 // Reconcile
 // ----------------------------------------------------------------------------
 func (b *kwokBackend) Reconcile(ctx context.Context, rc *rcv1.RcNode) error {
+	return b.errs.record(rc, b.reconcile(ctx, rc))
+}
+
+func (b *kwokBackend) reconcile(ctx context.Context, rc *rcv1.RcNode) error {
 	wantRunning := rc.Spec.DesiredState == "Running"
 	nodeName := templateNodeName(rc) // "kwok-fake-<rcname>"
-	providerID := fmt.Sprintf("recluster://%s", rc.Name)
+	providerID := ProviderID(rc)
+	now := b.now()
 
 	klog.Infof("KWOK: reconcile RcNode %q (%s) -> %q", rc.Name, rc.Spec.DesiredState, nodeName)
 	// does the Node already exist?
@@ -48,105 +115,292 @@ func (b *kwokBackend) Reconcile(ctx context.Context, rc *rcv1.RcNode) error {
 	if err != nil && !isNotFound(err) {
 		return err
 	}
+	exists := err == nil
+	if exists && !ownsNode(rc, node) {
+		return errNotOurs(rc, node)
+	}
 
 	switch {
 	// ----------------------------------------------------------------------
-	// 1) RcNode says it should run  ►  ensure Node exists & Ready=True
+	// 1) shutting down  ►  delete once the shutdown delay is over (a
+	//    Running request boots it again afterwards)
+	// ----------------------------------------------------------------------
+	case exists && node.Annotations[kwokOffAtAnnotation] != "":
+		if now.Before(annotationTime(node, kwokOffAtAnnotation)) {
+			return nil
+		}
+		klog.Infof("KWOK: fake node %q powered off", nodeName)
+		return deleteNode(ctx, b.core, nodeName)
+
+	// ----------------------------------------------------------------------
+	// 2) should run, no Node  ►  power on: Node registers NotReady
+	// ----------------------------------------------------------------------
+	case wantRunning && !exists:
+		fail := b.cfg.BootFailureRate > 0 && b.rand() < b.cfg.BootFailureRate
+		klog.Infof("KWOK: creating fake node %q for RcNode %q (boot %ds, failure=%t)",
+			nodeName, rc.Name, rc.Spec.BootSeconds, fail)
+		_, err = b.core.Nodes().Create(ctx, buildKwokNode(rc, nodeName, providerID, now, fail),
+			metav1.CreateOptions{})
+		return err
+
+	// ----------------------------------------------------------------------
+	// 3) should run, Node exists  ►  finish the boot, keep it Ready
 	// ----------------------------------------------------------------------
 	case wantRunning:
-		if isNotFound(err) {
-			// create brand-new node
-			klog.Infof("KWOK: creating fake node %q for RcNode %q", nodeName, rc.Name)
-			_, err = b.core.Nodes().Create(ctx, buildKwokNode(rc, nodeName, providerID),
-				metav1.CreateOptions{})
-			return err
+		after := node.DeepCopy()
+		if after.Annotations == nil {
+			after.Annotations = map[string]string{}
+		}
+		after.Spec.ProviderID = providerID
+		switch {
+		case node.Annotations[kwokBootFailedAnnotation] == "true":
+			return patchNode(ctx, b.core, node, after) // stuck until Stopped
+		case node.Annotations[kwokReadyAtAnnotation] != "":
+			if now.Before(annotationTime(node, kwokReadyAtAnnotation)) {
+				return patchNode(ctx, b.core, node, after)
+			}
+			klog.Infof("KWOK: fake node %q finished booting", nodeName)
+			delete(after.Annotations, kwokReadyAtAnnotation)
 		}
+		after.Annotations[kwokManagedAnnotation] = "fake"
+		if cond := getReadyCond(after); cond == nil || cond.Status != corev1.ConditionTrue {
+			after.Status.Conditions = mergeReady(after.Status.Conditions,
+				readyCond(corev1.ConditionTrue, "KwokReady", "kwok simulated node ready", now))
+		}
+		return patchNode(ctx, b.core, node, after)
 
-		// exists – patch ProviderID + Ready=True if needed
-		return b.ensureReady(ctx, node, providerID)
+	// ----------------------------------------------------------------------
+	// 4) should be off, no Node  ►  nothing to do
+	// ----------------------------------------------------------------------
+	case !exists:
+		return nil
 
 	// ----------------------------------------------------------------------
-	// 2) RcNode wants it stopped  ►  delete Node if present
+	// 5) should be off  ►  NotReady for ShutdownDelay, then delete
 	// ----------------------------------------------------------------------
 	default:
-		if isNotFound(err) {
-			return nil // nothing to do
+		if b.cfg.ShutdownDelay <= 0 {
+			klog.Infof("KWOK: deleting fake node %q (RcNode %q wants Stopped)", nodeName, rc.Name)
+			return deleteNode(ctx, b.core, nodeName)
+		}
+		klog.Infof("KWOK: shutting down fake node %q (RcNode %q wants Stopped)", nodeName, rc.Name)
+		after := node.DeepCopy()
+		if after.Annotations == nil {
+			after.Annotations = map[string]string{}
 		}
-		klog.Infof("KWOK: deleting fake node %q (RcNode %q wants Stopped)", nodeName, rc.Name)
-		return b.core.Nodes().Delete(ctx, nodeName, metav1.DeleteOptions{})
+		delete(after.Annotations, kwokManagedAnnotation)
+		delete(after.Annotations, kwokReadyAtAnnotation)
+		after.Annotations[kwokOffAtAnnotation] = now.Add(b.cfg.ShutdownDelay).UTC().Format(time.RFC3339)
+		after.Status.Conditions = mergeReady(after.Status.Conditions,
+			readyCond(corev1.ConditionFalse, "KwokShuttingDown", "kwok simulated shutdown", now))
+		return patchNode(ctx, b.core, node, after)
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Observe
+// ----------------------------------------------------------------------------
+
+// ManagesNode: the fake Node is the machine.
+func (b *kwokBackend) ManagesNode(context.Context) (bool, error) { return true, nil }
+
+// Observe treats the fake Node as the machine: it exists ⇔ powered on. The
+// simulated draw is the idle power (MinPowerConsumption) while on.
+func (b *kwokBackend) Observe(ctx context.Context, rc *rcv1.RcNode) (lifecycle.Observation, error) {
+	obs := lifecycle.Observation{Power: lifecycle.PowerOff, LastError: b.errs.get(rc)}
+	node, err := b.core.Nodes().Get(ctx, templateNodeName(rc), metav1.GetOptions{})
+	if isNotFound(err) {
+		zero := 0
+		obs.PowerWatts = &zero
+		return obs, nil
+	}
+	if err != nil {
+		return obs, err
+	}
+	obs.Power = lifecycle.PowerOn
+	obs.Boot, obs.Heartbeat = lifecycle.NodeProgress(node)
+	idle := rc.Spec.MinPowerConsumption
+	obs.PowerWatts = &idle
+
+	now := b.now()
+	switch {
+	case node.DeletionTimestamp != nil:
+		obs.Power = lifecycle.PowerPoweringOff
+	case node.Annotations[kwokOffAtAnnotation] != "":
+		obs.Power = lifecycle.PowerPoweringOff
+		obs.Recheck = untilAnnotation(node, kwokOffAtAnnotation, now)
+	case node.Annotations[kwokBootFailedAnnotation] == "true":
+		// hung boot: powered, never Ready
+	case node.Annotations[kwokReadyAtAnnotation] != "":
+		obs.Recheck = untilAnnotation(node, kwokReadyAtAnnotation, now)
 	}
+	return obs, nil
 }
 
 // ----------------------------------------------------------------------------
 // helpers
 // ----------------------------------------------------------------------------
-func buildKwokNode(rc *rcv1.RcNode, nodeName, providerID string) *corev1.Node {
-	cpuMillicores := rc.Spec.CPU.Cores * 1000
-	memBytes := int64(rc.Spec.Memory) * 1024 * 1024 * 1024
-	klog.Infof("KWOK: creating fake node (RcNode %q wants %d cores, %d GiB)", rc.Name, rc.Spec.CPU.Cores, rc.Spec.Memory)
+
+// buildKwokNode is the fake Node of rc right after power-on: Ready at once
+// when BootSeconds is 0, NotReady until now+BootSeconds otherwise, and
+// NotReady for good if the boot is to fail.
+func buildKwokNode(rc *rcv1.RcNode, nodeName, providerID string, now time.Time, fail bool) *corev1.Node {
+	node := fakeNode(rc, nodeName, providerID)
+	klog.Infof("KWOK: creating fake node (RcNode %q wants %d cores, %s memory, %s)",
+		rc.Name, rc.Spec.CPU.Cores, node.Status.Capacity.Memory(), node.Status.NodeInfo.Architecture)
+
+	ann := map[string]string{}
+	ready := readyCond(corev1.ConditionTrue, "KwokReady", "kwok simulated node ready", now)
+	boot := time.Duration(rc.Spec.BootSeconds) * time.Second
+	switch {
+	case fail:
+		ann[kwokBootFailedAnnotation] = "true"
+		ready = readyCond(corev1.ConditionFalse, "KwokBootFailed", "kwok simulated boot failure", now)
+	case boot > 0:
+		ann[kwokReadyAtAnnotation] = now.Add(boot).UTC().Format(time.RFC3339)
+		ready = readyCond(corev1.ConditionFalse, "KwokBooting",
+			fmt.Sprintf("kwok simulated boot, ready in %s", boot), now)
+	default:
+		ann[kwokManagedAnnotation] = "fake"
+	}
+	node.Annotations = ann
+	node.Status.Conditions = []corev1.NodeCondition{ready}
+	return node
+}
+
+// fakeNode is a Node shaped like rc's hardware (capacity, arch, labels),
+// without annotations or conditions.
+func fakeNode(rc *rcv1.RcNode, nodeName, providerID string) *corev1.Node {
+	cpu := *resource.NewMilliQuantity(int64(rc.Spec.CPU.Cores)*1000, resource.DecimalSI)
+	mem := *resource.NewQuantity(rc.Spec.Memory, resource.BinarySI) // Spec.Memory is bytes
+	arch := kwokArch(rc.Spec.CPU.Architecture)
+
+	labels := map[string]string{
+		"kubernetes.io/cores":    fmt.Sprintf("%d", rc.Spec.CPU.Cores),
+		"kubernetes.io/os":       "linux",
+		"kubernetes.io/arch":     arch,
+		"kubernetes.io/hostname": nodeName,
+	}
+	if rc.Spec.CPU.Vendor != "" {
+		labels[LabelCPUVendor] = string(rc.Spec.CPU.Vendor)
+	}
+	if rc.Spec.NodePool != "" {
+		labels[LabelPool] = rc.Spec.NodePool
+	}
+	for _, f := range rc.Spec.CPU.Flags {
+		labels[labelCPUIDPrefix+strings.ToUpper(f)] = "true"
+	}
+
+	capacity := corev1.ResourceList{corev1.ResourceCPU: cpu, corev1.ResourceMemory: mem}
+	for name, q := range rc.Spec.ExtendedResources {
+		capacity[name] = q.DeepCopy()
+	}
+
 	return &corev1.Node{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: nodeName,
-			Labels: map[string]string{
-				"kubernetes.io/cores": fmt.Sprintf("%d", rc.Spec.CPU.Cores), // "amd64" or "arm64"
-				"kubernetes.io/os":    "linux",
-				"kubernetes.io/arch":  "amd64",
-			},
-			Annotations: map[string]string{
-				kwokManagedAnnotation: "fake",
-			},
+			Name:   nodeName,
+			Labels: labels,
 		},
 		Spec: corev1.NodeSpec{
 			ProviderID: providerID,
 		},
 		Status: corev1.NodeStatus{
-			Capacity: corev1.ResourceList{
-				corev1.ResourceCPU:    *resource.NewMilliQuantity(int64(cpuMillicores), resource.DecimalSI),
-				corev1.ResourceMemory: *resource.NewQuantity(memBytes, resource.BinarySI),
-			},
-			Allocatable: corev1.ResourceList{
-				corev1.ResourceCPU:    *resource.NewMilliQuantity(int64(cpuMillicores), resource.DecimalSI),
-				corev1.ResourceMemory: *resource.NewQuantity(memBytes, resource.BinarySI),
+			Capacity:    capacity,
+			Allocatable: capacity.DeepCopy(),
+			NodeInfo: corev1.NodeSystemInfo{
+				Architecture:    arch,
+				OperatingSystem: "linux",
+				KubeletVersion:  "fake",
 			},
-			Conditions: []corev1.NodeCondition{readyCond(corev1.ConditionTrue)},
 		},
 	}
 }
 
-func readyCond(status corev1.ConditionStatus) corev1.NodeCondition {
+// kwokArch maps the CPU architecture onto the GOARCH-style value of the
+// kubernetes.io/arch label (amd64 if unset).
+func kwokArch(a rcv1.CpuArchitecture) string {
+	if a == "" {
+		return "amd64"
+	}
+	return strings.ToLower(string(a))
+}
+
+func readyCond(status corev1.ConditionStatus, reason, msg string, now time.Time) corev1.NodeCondition {
 	return corev1.NodeCondition{
 		Type:               corev1.NodeReady,
 		Status:             status,
-		LastHeartbeatTime:  metav1.Time{Time: time.Now()},
-		LastTransitionTime: metav1.Time{Time: time.Now()},
-		Reason:             "KwokReady",
-		Message:            "kwok instant ready",
+		LastHeartbeatTime:  metav1.Time{Time: now},
+		LastTransitionTime: metav1.Time{Time: now},
+		Reason:             reason,
+		Message:            msg,
 	}
 }
 
-func (b *kwokBackend) ensureReady(ctx context.Context, node *corev1.Node, providerID string) error {
-	needPatch := false
-	after := node.DeepCopy()
-
-	if after.Spec.ProviderID != providerID {
-		after.Spec.ProviderID = providerID
-		needPatch = true
+// patchNode sends the difference between node and after: metadata and
+// spec to the Node, conditions to its status subresource.
+func patchNode(ctx context.Context, core typedcore.CoreV1Interface, node, after *corev1.Node) error {
+	if !equality.Semantic.DeepEqual(node.ObjectMeta, after.ObjectMeta) ||
+		!equality.Semantic.DeepEqual(node.Spec, after.Spec) {
+		before, next := node.DeepCopy(), after.DeepCopy()
+		before.Status, next.Status = corev1.NodeStatus{}, corev1.NodeStatus{}
+		if err := patch(ctx, core, before, next, ""); err != nil {
+			return err
+		}
 	}
-	if cond := getReadyCond(after); cond == nil || cond.Status != corev1.ConditionTrue {
-		after.Status.Conditions = mergeReady(after.Status.Conditions, readyCond(corev1.ConditionTrue))
-		needPatch = true
+	if !equality.Semantic.DeepEqual(node.Status, after.Status) {
+		return patch(ctx, core, node, after, "status")
 	}
+	return nil
+}
+
+func patch(ctx context.Context, core typedcore.CoreV1Interface, before, after *corev1.Node, sub string) error {
+	patch, _ := strategicpatch.CreateTwoWayMergePatch(
+		[]byte(mustJSON(before)), []byte(mustJSON(after)), corev1.Node{})
+	var subresources []string
+	if sub != "" {
+		subresources = append(subresources, sub)
+	}
+	_, err := core.Nodes().Patch(ctx, before.Name, types.StrategicMergePatchType, patch,
+		metav1.PatchOptions{}, subresources...)
+	return err
+}
 
-	if !needPatch {
+// ownsNode reports whether node was registered for rc by a driver, i.e.
+// carries its providerID. Drivers registering Nodes must not touch others.
+func ownsNode(rc *rcv1.RcNode, node *corev1.Node) bool {
+	return node.Spec.ProviderID == ProviderID(rc)
+}
+
+func errNotOurs(rc *rcv1.RcNode, node *corev1.Node) error {
+	return fmt.Errorf("node %q exists but is not the Node of RcNode %q (providerID %q, want %q)",
+		node.Name, rc.Name, node.Spec.ProviderID, ProviderID(rc))
+}
+
+func deleteNode(ctx context.Context, core typedcore.CoreV1Interface, name string) error {
+	err := core.Nodes().Delete(ctx, name, metav1.DeleteOptions{})
+	if isNotFound(err) {
 		return nil
 	}
-	patch, _ := strategicpatch.CreateTwoWayMergePatch(
-		[]byte(mustJSON(node)), []byte(mustJSON(after)), corev1.Node{})
-	_, err := b.core.Nodes().Patch(ctx, node.Name, types.StrategicMergePatchType, patch,
-		metav1.PatchOptions{}, "status")
 	return err
 }
 
+// annotationTime parses an RFC 3339 annotation (zero time if unparsable,
+// i.e. already due).
+func annotationTime(n *corev1.Node, key string) time.Time {
+	t, _ := time.Parse(time.RFC3339, n.Annotations[key])
+	return t
+}
+
+// untilAnnotation is how long until the time in annotation key (≥ 1s, so
+// the caller comes back right after it).
+func untilAnnotation(n *corev1.Node, key string, now time.Time) time.Duration {
+	d := annotationTime(n, key).Sub(now)
+	if d < time.Second {
+		d = time.Second
+	}
+	return d
+}
+
 func getReadyCond(n *corev1.Node) *corev1.NodeCondition {
 	for i := range n.Status.Conditions {
 		if n.Status.Conditions[i].Type == corev1.NodeReady {
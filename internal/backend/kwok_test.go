@@ -0,0 +1,156 @@
+package backend
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+)
+
+var _ = Describe("kwok backend", func() {
+	var (
+		ctx   = context.Background()
+		k8s   *fake.Clientset
+		be    *kwokBackend
+		clock time.Time
+		dice  float64
+		rc    *rcv1.RcNode
+	)
+
+	BeforeEach(func() {
+		k8s = fake.NewSimpleClientset()
+		be = NewKwokBackend(k8s, KwokConfig{ShutdownDelay: 10 * time.Second, BootFailureRate: 0.2})
+		clock = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		dice = 0.5
+		be.now = func() time.Time { return clock }
+		be.rand = func() float64 { return dice }
+
+		rc = &rcv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "n1"},
+			Spec: rcv1.RcNodeSpec{
+				NodePool: "edge",
+				CPU: rcv1.RcNodeCPUSpec{Architecture: rcv1.CpuArchARM64, Vendor: rcv1.CpuVendorAMD,
+					Cores: 4, Flags: []string{"avx2", "sse4_2"}},
+				Memory:              8 << 30,
+				MinPowerConsumption: 35,
+				BootSeconds:         30,
+				DesiredState:        rcv1.DesiredStateRunning,
+			},
+		}
+	})
+
+	getNode := func() *corev1.Node {
+		n, err := k8s.CoreV1().Nodes().Get(ctx, "kwok-fake-n1", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		return n
+	}
+	ready := func(n *corev1.Node) corev1.ConditionStatus { return getReadyCond(n).Status }
+
+	It("describes the hardware on the fake Node", func() {
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		n := getNode()
+		Expect(n.Status.Capacity.Memory().Value()).To(Equal(int64(8 << 30)))
+		Expect(n.Status.Allocatable.Cpu().MilliValue()).To(Equal(int64(4000)))
+		Expect(n.Status.NodeInfo.Architecture).To(Equal("arm64"))
+		Expect(n.Spec.ProviderID).To(Equal("recluster://n1"))
+		Expect(n.Labels).To(HaveKeyWithValue("kubernetes.io/arch", "arm64"))
+		Expect(n.Labels).To(HaveKeyWithValue(LabelCPUVendor, "AMD"))
+		Expect(n.Labels).To(HaveKeyWithValue(LabelPool, "edge"))
+		Expect(n.Labels).To(HaveKeyWithValue("feature.node.kubernetes.io/cpu-cpuid.AVX2", "true"))
+		Expect(n.Labels).To(HaveKeyWithValue("feature.node.kubernetes.io/cpu-cpuid.SSE4_2", "true"))
+	})
+
+	It("becomes Ready only after BootSeconds", func() {
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(ready(getNode())).To(Equal(corev1.ConditionFalse))
+		Expect(getNode().Annotations).NotTo(HaveKey(kwokManagedAnnotation))
+
+		obs, err := be.Observe(ctx, rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obs.Power).To(Equal(lifecycle.PowerOn))
+		Expect(obs.Boot).To(Equal(lifecycle.BootNodeRegistered))
+		Expect(obs.Recheck).To(Equal(30 * time.Second))
+		Expect(*obs.PowerWatts).To(Equal(35))
+
+		clock = clock.Add(20 * time.Second)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(ready(getNode())).To(Equal(corev1.ConditionFalse))
+
+		clock = clock.Add(10 * time.Second)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		n := getNode()
+		Expect(ready(n)).To(Equal(corev1.ConditionTrue))
+		Expect(n.Annotations).To(HaveKeyWithValue(kwokManagedAnnotation, "fake"))
+		Expect(n.Annotations).NotTo(HaveKey(kwokReadyAtAnnotation))
+		obs, _ = be.Observe(ctx, rc)
+		Expect(obs.Boot).To(Equal(lifecycle.BootReady))
+		Expect(obs.Recheck).To(BeZero())
+	})
+
+	It("is Ready at once without BootSeconds", func() {
+		rc.Spec.BootSeconds = 0
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(ready(getNode())).To(Equal(corev1.ConditionTrue))
+	})
+
+	It("goes NotReady for the shutdown delay before the Node is deleted", func() {
+		rc.Spec.BootSeconds = 0
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		n := getNode()
+		Expect(ready(n)).To(Equal(corev1.ConditionFalse))
+		Expect(n.Annotations).NotTo(HaveKey(kwokManagedAnnotation))
+		obs, _ := be.Observe(ctx, rc)
+		Expect(obs.Power).To(Equal(lifecycle.PowerPoweringOff))
+		Expect(obs.Recheck).To(Equal(10 * time.Second))
+
+		clock = clock.Add(10 * time.Second)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		obs, _ = be.Observe(ctx, rc)
+		Expect(obs.Power).To(Equal(lifecycle.PowerOff))
+		Expect(*obs.PowerWatts).To(BeZero())
+	})
+
+	It("injects boot failures at the configured rate", func() {
+		dice = 0.1
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		clock = clock.Add(time.Hour)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		n := getNode()
+		Expect(ready(n)).To(Equal(corev1.ConditionFalse))
+		Expect(getReadyCond(n).Reason).To(Equal("KwokBootFailed"))
+
+		// power-cycling draws again
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		clock = clock.Add(time.Minute)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		dice = 0.9
+		rc.Spec.DesiredState = rcv1.DesiredStateRunning
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		clock = clock.Add(time.Minute)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(ready(getNode())).To(Equal(corev1.ConditionTrue))
+	})
+
+	It("reads its configuration from the chart's environment", func() {
+		GinkgoT().Setenv("RECLUSTER_KWOK_SHUTDOWN_SECONDS", "15")
+		GinkgoT().Setenv("RECLUSTER_KWOK_BOOT_FAILURE_RATE", "0.05")
+		cfg, err := KwokConfigFromEnv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg).To(Equal(KwokConfig{ShutdownDelay: 15 * time.Second, BootFailureRate: 0.05}))
+
+		GinkgoT().Setenv("RECLUSTER_KWOK_BOOT_FAILURE_RATE", "2")
+		_, err = KwokConfigFromEnv()
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,243 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	pluginv1 "github.com/lcereser6/recluster-sync/apis/plugin/v1"
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+)
+
+// DefaultPluginTimeout bounds one call to a plugin.
+const DefaultPluginTimeout = 30 * time.Second
+
+// pluginBackend is a driver living in another process, spoken to over the
+// PowerDriver gRPC service (apis/plugin/v1). Plugins run as sidecars on a
+// Unix socket (unix:///run/recluster/<name>.sock) or as Services
+// (dns:///<svc>.<ns>:<port>); the connection is plain-text.
+type pluginBackend struct {
+	name    string
+	target  string
+	conn    *grpc.ClientConn
+	client  pluginv1.PowerDriverClient
+	timeout time.Duration
+
+	mu sync.Mutex
+	// caps is nil until the handshake succeeded, and again once the
+	// plugin is unavailable.
+	caps *pluginv1.CapabilitiesResponse
+}
+
+// NewPluginBackend connects (lazily) to the plugin at target, a gRPC target
+// such as unix:///run/recluster/vendor.sock or dns:///vendor-plugin:9000.
+func NewPluginBackend(name, target string) (*pluginBackend, error) {
+	conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("plugin %q: %w", name, err)
+	}
+	return &pluginBackend{
+		name:    name,
+		target:  target,
+		conn:    conn,
+		client:  pluginv1.NewPowerDriverClient(conn),
+		timeout: DefaultPluginTimeout,
+	}, nil
+}
+
+// parsePlugins parses RECLUSTER_PLUGINS: <driver>=<target>[,…].
+func parsePlugins(s string) (map[string]string, error) {
+	plugins := map[string]string{}
+	for _, kv := range strings.Split(s, ",") {
+		if kv = strings.TrimSpace(kv); kv == "" {
+			continue
+		}
+		name, target, ok := strings.Cut(kv, "=")
+		if !ok || name == "" || target == "" {
+			return nil, fmt.Errorf("RECLUSTER_PLUGINS: %q: want <driver>=<target>", kv)
+		}
+		plugins[name] = target
+	}
+	return plugins, nil
+}
+
+// Reconcile calls PowerOn or PowerOff; the plugin keeps any error for
+// Observe.
+func (b *pluginBackend) Reconcile(ctx context.Context, rc *rcv1.RcNode) error {
+	if err := b.handshake(ctx); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	req := &pluginv1.PowerRequest{Machine: machineOf(rc)}
+	var err error
+	if rc.Spec.DesiredState == rcv1.DesiredStateRunning {
+		_, err = b.client.PowerOn(ctx, req)
+	} else {
+		_, err = b.client.PowerOff(ctx, req)
+	}
+	return b.wrap(err)
+}
+
+// Observe asks the plugin, if it can observe; otherwise it reports the
+// power state as unknown and Step goes by the Node alone.
+func (b *pluginBackend) Observe(ctx context.Context, rc *rcv1.RcNode) (lifecycle.Observation, error) {
+	if err := b.handshake(ctx); err != nil {
+		return lifecycle.Observation{}, err
+	}
+	if !b.can(pluginv1.Capability_CAPABILITY_OBSERVE) {
+		return lifecycle.Observation{Power: lifecycle.PowerUnknown}, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	resp, err := b.client.Observe(ctx, &pluginv1.ObserveRequest{Machine: machineOf(rc)})
+	if err != nil {
+		return lifecycle.Observation{}, b.wrap(err)
+	}
+	return observationOf(resp), nil
+}
+
+// ManagesNode reports whether the plugin announced CAPABILITY_MANAGES_NODE.
+func (b *pluginBackend) ManagesNode(ctx context.Context) (bool, error) {
+	if err := b.handshake(ctx); err != nil {
+		return false, err
+	}
+	return b.can(pluginv1.Capability_CAPABILITY_MANAGES_NODE), nil
+}
+
+// handshake checks the plugin's protocol version and learns its
+// capabilities. A failure is not remembered: a plugin that starts after
+// the manager is picked up on a later call. The result is dropped as soon
+// as the plugin is unavailable (see wrap), so a plugin restarted as
+// another version is asked again once it is back.
+func (b *pluginBackend) handshake(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.caps != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+	caps, err := b.client.Capabilities(ctx, &pluginv1.CapabilitiesRequest{})
+	if err != nil {
+		return b.statusError(err)
+	}
+	if caps.GetProtocolVersion() != pluginv1.ProtocolVersion {
+		return fmt.Errorf("plugin %q at %s speaks protocol %q, want %q",
+			b.name, b.target, caps.GetProtocolVersion(), pluginv1.ProtocolVersion)
+	}
+	klog.Infof("plugin %q: connected to %q at %s (%v)", b.name, caps.GetName(), b.target, caps.GetCapabilities())
+	b.caps = caps
+	return nil
+}
+
+func (b *pluginBackend) can(c pluginv1.Capability) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Contains(b.caps.GetCapabilities(), c)
+}
+
+// wrap names the plugin in err, forgetting the handshake if the plugin is
+// unavailable. Errors of the driver behind the plugin come with other codes
+// and leave the handshake alone.
+func (b *pluginBackend) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	if status.Code(err) == codes.Unavailable {
+		b.mu.Lock()
+		b.caps = nil
+		b.mu.Unlock()
+	}
+	return b.statusError(err)
+}
+
+func (b *pluginBackend) statusError(err error) error {
+	s := status.Convert(err)
+	return fmt.Errorf("plugin %q: %s: %s", b.name, s.Code(), s.Message())
+}
+
+// ----------------------------------------------------------------------------
+// conversions
+// ----------------------------------------------------------------------------
+
+func machineOf(rc *rcv1.RcNode) *pluginv1.Machine {
+	m := &pluginv1.Machine{
+		Namespace:   rc.Namespace,
+		Name:        rc.Name,
+		ProviderId:  ProviderID(rc),
+		NodePool:    rc.Spec.NodePool,
+		Address:     rc.Spec.Address,
+		BootSeconds: int32(rc.Spec.BootSeconds),
+		Annotations: rc.Annotations,
+		RcnodeJson:  []byte(mustJSON(rc)),
+	}
+	for _, nic := range rc.Spec.Network {
+		i := &pluginv1.NetworkInterface{Name: nic.Name, Address: nic.Address}
+		for _, f := range nic.WoL {
+			i.Wol = append(i.Wol, string(f))
+		}
+		m.Interfaces = append(m.Interfaces, i)
+	}
+	return m
+}
+
+// rcNodeOf recovers the RcNode a plugin call is about.
+func rcNodeOf(m *pluginv1.Machine) (*rcv1.RcNode, error) {
+	rc := &rcv1.RcNode{}
+	if len(m.GetRcnodeJson()) > 0 {
+		if err := json.Unmarshal(m.GetRcnodeJson(), rc); err != nil {
+			return nil, fmt.Errorf("rcnode_json: %w", err)
+		}
+	}
+	rc.Namespace, rc.Name = m.GetNamespace(), m.GetName()
+	return rc, nil
+}
+
+var powerStates = map[pluginv1.PowerState]lifecycle.PowerState{
+	pluginv1.PowerState_POWER_STATE_ON:           lifecycle.PowerOn,
+	pluginv1.PowerState_POWER_STATE_OFF:          lifecycle.PowerOff,
+	pluginv1.PowerState_POWER_STATE_POWERING_ON:  lifecycle.PowerPoweringOn,
+	pluginv1.PowerState_POWER_STATE_POWERING_OFF: lifecycle.PowerPoweringOff,
+	pluginv1.PowerState_POWER_STATE_UNKNOWN:      lifecycle.PowerUnknown,
+}
+
+var bootProgress = map[pluginv1.BootProgress]lifecycle.BootProgress{
+	pluginv1.BootProgress_BOOT_PROGRESS_UNSPECIFIED:     lifecycle.BootNone,
+	pluginv1.BootProgress_BOOT_PROGRESS_POWER_ON:        lifecycle.BootPowerOn,
+	pluginv1.BootProgress_BOOT_PROGRESS_NODE_REGISTERED: lifecycle.BootNodeRegistered,
+	pluginv1.BootProgress_BOOT_PROGRESS_READY:           lifecycle.BootReady,
+}
+
+func observationOf(r *pluginv1.ObserveResponse) lifecycle.Observation {
+	obs := lifecycle.Observation{
+		Power:     lifecycle.PowerUnknown,
+		Boot:      bootProgress[r.GetBoot()],
+		LastError: r.GetLastError(),
+		Recheck:   r.GetRecheck().AsDuration(),
+	}
+	if p, ok := powerStates[r.GetPower()]; ok {
+		obs.Power = p
+	}
+	if r.PowerWatts != nil {
+		w := int(r.GetPowerWatts())
+		obs.PowerWatts = &w
+	}
+	if r.GetHeartbeat() != nil {
+		hb := metav1.NewTime(r.GetHeartbeat().AsTime())
+		obs.Heartbeat = &hb
+	}
+	return obs
+}
@@ -0,0 +1,107 @@
+package backend
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pluginv1 "github.com/lcereser6/recluster-sync/apis/plugin/v1"
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+)
+
+// PluginServer serves an in-tree Backend over the PowerDriver protocol;
+// cmd/kwokplugin is the reference plugin built on it.
+type PluginServer struct {
+	pluginv1.UnimplementedPowerDriverServer
+	name string
+	be   Backend
+	caps []pluginv1.Capability
+}
+
+// NewPluginServer serves be as driver name, announcing caps (Observe is
+// always implemented).
+func NewPluginServer(name string, be Backend, caps ...pluginv1.Capability) *PluginServer {
+	return &PluginServer{
+		name: name,
+		be:   be,
+		caps: append([]pluginv1.Capability{pluginv1.Capability_CAPABILITY_OBSERVE}, caps...),
+	}
+}
+
+func (s *PluginServer) Capabilities(context.Context, *pluginv1.CapabilitiesRequest) (*pluginv1.CapabilitiesResponse, error) {
+	return &pluginv1.CapabilitiesResponse{
+		Name:            s.name,
+		ProtocolVersion: pluginv1.ProtocolVersion,
+		Capabilities:    s.caps,
+	}, nil
+}
+
+func (s *PluginServer) PowerOn(ctx context.Context, req *pluginv1.PowerRequest) (*pluginv1.PowerResponse, error) {
+	return s.power(ctx, req, rcv1.DesiredStateRunning)
+}
+
+func (s *PluginServer) PowerOff(ctx context.Context, req *pluginv1.PowerRequest) (*pluginv1.PowerResponse, error) {
+	return s.power(ctx, req, rcv1.DesiredStateStopped)
+}
+
+func (s *PluginServer) power(ctx context.Context, req *pluginv1.PowerRequest, desired string) (*pluginv1.PowerResponse, error) {
+	rc, err := rcNodeOf(req.GetMachine())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	rc.Spec.DesiredState = desired
+	if err := s.be.Reconcile(ctx, rc); err != nil {
+		return nil, driverError(err)
+	}
+	return &pluginv1.PowerResponse{}, nil
+}
+
+func (s *PluginServer) Observe(ctx context.Context, req *pluginv1.ObserveRequest) (*pluginv1.ObserveResponse, error) {
+	rc, err := rcNodeOf(req.GetMachine())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	obs, err := s.be.Observe(ctx, rc)
+	if err != nil {
+		return nil, driverError(err)
+	}
+	return observeResponseOf(obs), nil
+}
+
+// driverError reports a failure of the driver behind the server as Unknown:
+// Unavailable tells the manager the plugin itself is gone.
+func driverError(err error) error {
+	return status.Error(codes.Unknown, err.Error())
+}
+
+func observeResponseOf(obs lifecycle.Observation) *pluginv1.ObserveResponse {
+	r := &pluginv1.ObserveResponse{
+		Power:     pluginv1.PowerState_POWER_STATE_UNKNOWN,
+		LastError: obs.LastError,
+	}
+	for p, o := range powerStates {
+		if o == obs.Power {
+			r.Power = p
+		}
+	}
+	for b, o := range bootProgress {
+		if o == obs.Boot {
+			r.Boot = b
+		}
+	}
+	if obs.PowerWatts != nil {
+		w := int32(*obs.PowerWatts)
+		r.PowerWatts = &w
+	}
+	if obs.Heartbeat != nil {
+		r.Heartbeat = timestamppb.New(obs.Heartbeat.Time)
+	}
+	if obs.Recheck > 0 {
+		r.Recheck = durationpb.New(obs.Recheck)
+	}
+	return r
+}
@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	pluginv1 "github.com/lcereser6/recluster-sync/apis/plugin/v1"
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+)
+
+// v2Server claims a protocol version the manager does not speak.
+type v2Server struct{ *PluginServer }
+
+func (v2Server) Capabilities(context.Context, *pluginv1.CapabilitiesRequest) (*pluginv1.CapabilitiesResponse, error) {
+	return &pluginv1.CapabilitiesResponse{Name: "future", ProtocolVersion: "v2"}, nil
+}
+
+// blindServer cannot observe.
+type blindServer struct{ *PluginServer }
+
+func (blindServer) Capabilities(context.Context, *pluginv1.CapabilitiesRequest) (*pluginv1.CapabilitiesResponse, error) {
+	return &pluginv1.CapabilitiesResponse{Name: "blind", ProtocolVersion: pluginv1.ProtocolVersion}, nil
+}
+
+var _ = Describe("plugin backend", func() {
+	var (
+		ctx    = context.Background()
+		sock   string
+		fake   *FakeBackend
+		client *pluginBackend
+		rc     *rcv1.RcNode
+		key    = types.NamespacedName{Namespace: "lab", Name: "n1"}
+	)
+
+	serve := func(srv pluginv1.PowerDriverServer) *grpc.Server {
+		ln, err := net.Listen("unix", sock)
+		Expect(err).NotTo(HaveOccurred())
+		g := grpc.NewServer()
+		pluginv1.RegisterPowerDriverServer(g, srv)
+		go func() { _ = g.Serve(ln) }()
+		DeferCleanup(g.Stop)
+		return g
+	}
+
+	BeforeEach(func() {
+		dir, err := os.MkdirTemp("", "plugin")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+		sock = filepath.Join(dir, "vendor.sock")
+
+		fake = NewFakeBackend(nil)
+		fake.SetClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+		client, err = NewPluginBackend("vendor", "unix://"+sock)
+		Expect(err).NotTo(HaveOccurred())
+		client.timeout = time.Second
+		DeferCleanup(client.conn.Close)
+
+		rc = &rcv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "lab", Name: "n1",
+				Annotations: map[string]string{"vendor.example/ipmi": "10.0.0.7"}},
+			Spec: rcv1.RcNodeSpec{
+				Address:             "10.0.1.7",
+				MinPowerConsumption: 40,
+				BootSeconds:         20,
+				DesiredState:        rcv1.DesiredStateRunning,
+				Network: []rcv1.RcNodeInterfaceSpec{
+					{Name: "eth0", Address: "aa:bb:cc:dd:ee:ff", WoL: []rcv1.WoLFlag{rcv1.WoLFlagG}}},
+			},
+		}
+	})
+
+	It("powers on and off through the plugin and relays its observation", func() {
+		serve(NewPluginServer("fake", fake))
+
+		Expect(client.Reconcile(ctx, rc)).To(Succeed())
+		Expect(fake.Power(key)).To(Equal(lifecycle.PowerPoweringOn))
+		obs, err := client.Observe(ctx, rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obs.Power).To(Equal(lifecycle.PowerPoweringOn))
+		Expect(obs.Boot).To(Equal(lifecycle.BootPowerOn))
+		Expect(obs.Recheck).To(Equal(20 * time.Second))
+		Expect(*obs.PowerWatts).To(Equal(40))
+
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		Expect(client.Reconcile(ctx, rc)).To(Succeed())
+		Expect(fake.Power(key)).To(Equal(lifecycle.PowerOff))
+		Expect(fake.CallsFor(key)).To(HaveLen(2))
+	})
+
+	It("relays the plugin's errors", func() {
+		serve(NewPluginServer("fake", fake))
+		fake.InjectFault(key, FakeFault{Err: errors.New("ipmi timeout")})
+		Expect(client.Reconcile(ctx, rc)).To(MatchError(`plugin "vendor": Unknown: ipmi timeout`))
+		obs, err := client.Observe(ctx, rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obs.LastError).To(Equal("ipmi timeout"))
+	})
+
+	It("connects to a plugin that starts late", func() {
+		Expect(client.Reconcile(ctx, rc)).To(MatchError(ContainSubstring(`plugin "vendor": Unavailable`)))
+		serve(NewPluginServer("fake", fake))
+		Eventually(func() error { return client.Reconcile(ctx, rc) }, 5*time.Second).Should(Succeed())
+	})
+
+	It("refuses a plugin speaking another protocol version", func() {
+		serve(v2Server{NewPluginServer("fake", fake)})
+		Expect(client.Reconcile(ctx, rc)).To(MatchError(ContainSubstring(`speaks protocol "v2", want "v1"`)))
+		Expect(fake.Calls()).To(BeEmpty())
+	})
+
+	It("reports an unknown power state for plugins that cannot observe", func() {
+		serve(blindServer{NewPluginServer("fake", fake)})
+		obs, err := client.Observe(ctx, rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obs.Power).To(Equal(lifecycle.PowerUnknown))
+	})
+
+	It("asks a plugin for its capabilities again once it is back", func() {
+		g := serve(NewPluginServer("kwok", fake, pluginv1.Capability_CAPABILITY_MANAGES_NODE))
+		Expect(client.ManagesNode(ctx)).To(BeTrue())
+		Expect(ManagesNode(ctx, client)).To(BeTrue())
+
+		g.Stop()
+		Expect(client.Reconcile(ctx, rc)).To(MatchError(ContainSubstring(`plugin "vendor": Unavailable`)))
+		serve(NewPluginServer("fake", fake))
+		Eventually(func() (bool, error) { return client.ManagesNode(ctx) }, 5*time.Second).Should(BeFalse())
+	})
+
+	It("keeps the capabilities when the driver behind the plugin fails", func() {
+		serve(NewPluginServer("kwok", fake, pluginv1.Capability_CAPABILITY_MANAGES_NODE))
+		Expect(client.ManagesNode(ctx)).To(BeTrue())
+
+		fake.InjectFault(key, FakeFault{Err: errors.New("ipmi timeout")})
+		Expect(client.Reconcile(ctx, rc)).To(MatchError(`plugin "vendor": Unknown: ipmi timeout`))
+		Expect(client.can(pluginv1.Capability_CAPABILITY_MANAGES_NODE)).To(BeTrue())
+	})
+
+	It("sends the whole RcNode along", func() {
+		m := machineOf(rc)
+		Expect(m.GetProviderId()).To(Equal("recluster://n1"))
+		Expect(m.GetAnnotations()).To(HaveKeyWithValue("vendor.example/ipmi", "10.0.0.7"))
+		Expect(m.GetInterfaces()[0].GetWol()).To(Equal([]string{"g"}))
+		back, err := rcNodeOf(m)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(back.Spec).To(Equal(rc.Spec))
+	})
+
+	It("parses plugin targets", func() {
+		plugins, err := parsePlugins("vendor=unix:///run/recluster/vendor.sock, ipmi=dns:///ipmi:9000")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plugins).To(Equal(map[string]string{
+			"vendor": "unix:///run/recluster/vendor.sock", "ipmi": "dns:///ipmi:9000"}))
+		_, err = parsePlugins("vendor")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -1 +1,300 @@
 package backend
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	typedcore "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// ----------------------------------------------------------------------------
+// Configuration
+// ----------------------------------------------------------------------------
+
+// ProdConfig configures the bare-metal backend.
+type ProdConfig struct {
+	// WoLAddr is where magic packets are sent (DefaultWoLAddr if empty).
+	WoLAddr string
+	// SSHSecret holds the shutdown credentials (see ssh.go for the keys).
+	SSHSecret types.NamespacedName
+	// SSHPort is used when Spec.Address carries no port (22 if 0).
+	SSHPort int
+	// SSHTimeout bounds one shutdown attempt (30s if 0).
+	SSHTimeout time.Duration
+	// Retry is how long a power action may take to show an effect before it
+	// is sent again (the node's boot time if 0).
+	Retry time.Duration
+}
+
+// ProdConfigFromEnv reads the settings injected by the Helm chart:
+//
+//	RECLUSTER_WOL_ADDR    host:port for magic packets
+//	RECLUSTER_SSH_SECRET  <namespace>/<name> of the SSH credentials
+//	RECLUSTER_SSH_PORT    default SSH port
+func ProdConfigFromEnv() (ProdConfig, error) {
+	cfg := ProdConfig{WoLAddr: os.Getenv("RECLUSTER_WOL_ADDR")}
+	if s := os.Getenv("RECLUSTER_SSH_SECRET"); s != "" {
+		ns, name, ok := strings.Cut(s, "/")
+		if !ok || ns == "" || name == "" {
+			return cfg, fmt.Errorf("RECLUSTER_SSH_SECRET=%q: want <namespace>/<name>", s)
+		}
+		cfg.SSHSecret = types.NamespacedName{Namespace: ns, Name: name}
+	}
+	if s := os.Getenv("RECLUSTER_SSH_PORT"); s != "" {
+		port, err := strconv.Atoi(s)
+		if err != nil {
+			return cfg, fmt.Errorf("RECLUSTER_SSH_PORT=%q: %w", s, err)
+		}
+		cfg.SSHPort = port
+	}
+	return cfg, nil
+}
+
+// ----------------------------------------------------------------------------
+// Constructor
+// ----------------------------------------------------------------------------
+
+// prodBackend drives real machines: Wake-on-LAN to power on, `systemctl
+// poweroff` over SSH to power off. Whether a machine is up is read from its
+// Kubernetes Node, which the kubelet registers on boot.
+type prodBackend struct {
+	core typedcore.CoreV1Interface
+	cfg  ProdConfig
+	now  func() time.Time
+
+	mu   sync.Mutex
+	last map[string]powerAttempt // RcNode namespace/name → last action sent
+	errs lastErrors
+}
+
+type powerAttempt struct {
+	on bool
+	at time.Time
+}
+
+func NewProdBackend(k8s kubernetes.Interface, cfg ProdConfig) *prodBackend {
+	return &prodBackend{
+		core: k8s.CoreV1(),
+		cfg:  cfg,
+		now:  time.Now,
+		last: map[string]powerAttempt{},
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Reconcile
+// ----------------------------------------------------------------------------
+func (b *prodBackend) Reconcile(ctx context.Context, rc *rcv1.RcNode) error {
+	return b.errs.record(rc, b.reconcile(ctx, rc))
+}
+
+func (b *prodBackend) reconcile(ctx context.Context, rc *rcv1.RcNode) error {
+	node, err := findNode(ctx, b.core, rc)
+	if err != nil {
+		return err
+	}
+	wantRunning := rc.Spec.DesiredState == rcv1.DesiredStateRunning
+
+	switch {
+	// ----------------------------------------------------------------------
+	// 1) should run and has joined the cluster  ►  done
+	// ----------------------------------------------------------------------
+	case wantRunning && node != nil:
+		b.forget(rc)
+		return nil
+
+	// ----------------------------------------------------------------------
+	// 2) should run, no Node yet  ►  magic packet (again after Retry)
+	// ----------------------------------------------------------------------
+	case wantRunning:
+		if !b.due(rc, true) {
+			return nil
+		}
+		mac, err := wolMAC(rc)
+		if err != nil {
+			return err
+		}
+		klog.Infof("prod: waking RcNode %q (%s) via %s", rc.Name, mac, b.wolAddr())
+		if err := sendWoL(b.wolAddr(), mac); err != nil {
+			return fmt.Errorf("wake-on-lan %s: %w", mac, err)
+		}
+		b.record(rc, true)
+		return nil
+
+	// ----------------------------------------------------------------------
+	// 3) should be off and has left the cluster  ►  done
+	// ----------------------------------------------------------------------
+	case node == nil:
+		b.forget(rc)
+		return nil
+
+	// ----------------------------------------------------------------------
+	// 4) should be off, still Ready  ►  graceful shutdown over SSH
+	// ----------------------------------------------------------------------
+	case isReady(node):
+		if !b.due(rc, false) {
+			return nil
+		}
+		creds, err := b.sshCreds(ctx)
+		if err != nil {
+			return err
+		}
+		addr := b.sshAddr(rc)
+		klog.Infof("prod: powering off RcNode %q via ssh %s", rc.Name, addr)
+		sctx, cancel := context.WithTimeout(ctx, b.sshTimeout())
+		defer cancel()
+		if err := runSSH(sctx, addr, creds); err != nil {
+			return fmt.Errorf("ssh %s: %w", addr, err)
+		}
+		b.record(rc, false)
+		return nil
+
+	// ----------------------------------------------------------------------
+	// 5) should be off, Node went NotReady  ►  machine is down, drop the Node
+	// ----------------------------------------------------------------------
+	default:
+		klog.Infof("prod: RcNode %q is down, removing node %q", rc.Name, node.Name)
+		err := b.core.Nodes().Delete(ctx, node.Name, metav1.DeleteOptions{})
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+		b.forget(rc)
+		return nil
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Observe
+// ----------------------------------------------------------------------------
+
+// Observe has no power sensor to read: the Node says whether the machine
+// is up, the last action sent whether it is on its way up or down.
+func (b *prodBackend) Observe(ctx context.Context, rc *rcv1.RcNode) (lifecycle.Observation, error) {
+	obs := lifecycle.Observation{Power: lifecycle.PowerOff, LastError: b.errs.get(rc)}
+	node, err := findNode(ctx, b.core, rc)
+	if err != nil {
+		return obs, err
+	}
+	b.mu.Lock()
+	last, sent := b.last[rc.Namespace+"/"+rc.Name]
+	b.mu.Unlock()
+
+	switch {
+	case node != nil && sent && !last.on:
+		obs.Power = lifecycle.PowerPoweringOff
+	case node != nil:
+		obs.Power = lifecycle.PowerOn
+	case sent && last.on:
+		obs.Power = lifecycle.PowerPoweringOn
+	default:
+		return obs, nil
+	}
+	obs.Boot, obs.Heartbeat = lifecycle.NodeProgress(node)
+	return obs, nil
+}
+
+// ----------------------------------------------------------------------------
+// helpers
+// ----------------------------------------------------------------------------
+
+// findNode returns the Node backing rc, preferring a providerID match; the
+// machines of prod and redfish join under their hostname.
+func findNode(ctx context.Context, core typedcore.CoreV1Interface, rc *rcv1.RcNode) (*corev1.Node, error) {
+	nodes, err := core.Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var byName *corev1.Node
+	for i := range nodes.Items {
+		n := &nodes.Items[i]
+		if n.Spec.ProviderID == ProviderID(rc) {
+			return n, nil
+		}
+		if MatchesNode(rc, n, true) {
+			byName = n
+		}
+	}
+	return byName, nil
+}
+
+func isReady(n *corev1.Node) bool {
+	c := getReadyCond(n)
+	return c != nil && c.Status == corev1.ConditionTrue
+}
+
+func (b *prodBackend) sshCreds(ctx context.Context) (*sshCreds, error) {
+	ref := b.cfg.SSHSecret
+	if ref.Name == "" {
+		return nil, fmt.Errorf("no SSH credentials configured (RECLUSTER_SSH_SECRET)")
+	}
+	sec, err := b.core.Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return parseSSHSecret(sec)
+}
+
+// sshAddr is Spec.Address, with the default port unless it carries one.
+func (b *prodBackend) sshAddr(rc *rcv1.RcNode) string {
+	if _, _, err := net.SplitHostPort(rc.Spec.Address); err == nil {
+		return rc.Spec.Address
+	}
+	port := b.cfg.SSHPort
+	if port == 0 {
+		port = 22
+	}
+	return net.JoinHostPort(rc.Spec.Address, strconv.Itoa(port))
+}
+
+func (b *prodBackend) wolAddr() string {
+	if b.cfg.WoLAddr != "" {
+		return b.cfg.WoLAddr
+	}
+	return DefaultWoLAddr
+}
+
+func (b *prodBackend) sshTimeout() time.Duration {
+	if b.cfg.SSHTimeout > 0 {
+		return b.cfg.SSHTimeout
+	}
+	return 30 * time.Second
+}
+
+// due reports whether a power action in direction on may be sent now: not
+// if the same action was sent less than Retry ago.
+func (b *prodBackend) due(rc *rcv1.RcNode, on bool) bool {
+	retry := b.cfg.Retry
+	if retry == 0 {
+		retry = lifecycle.BootTime(rc)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	last, ok := b.last[rc.Namespace+"/"+rc.Name]
+	return !ok || last.on != on || b.now().Sub(last.at) >= retry
+}
+
+func (b *prodBackend) record(rc *rcv1.RcNode, on bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.last[rc.Namespace+"/"+rc.Name] = powerAttempt{on: on, at: b.now()}
+}
+
+func (b *prodBackend) forget(rc *rcv1.RcNode) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.last, rc.Namespace+"/"+rc.Name)
+}
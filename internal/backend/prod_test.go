@@ -0,0 +1,286 @@
+package backend
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+)
+
+// sshStub is an in-process SSH server that records every exec'd command.
+type sshStub struct {
+	addr    string
+	hostKey ssh.PublicKey
+	cmds    chan string
+}
+
+func startSSHStub(user, password string) *sshStub {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+	signer, err := ssh.NewSignerFromKey(priv)
+	Expect(err).NotTo(HaveOccurred())
+
+	cfg := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, pw []byte) (*ssh.Permissions, error) {
+			if c.User() == user && string(pw) == password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("denied")
+		},
+	}
+	cfg.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).NotTo(HaveOccurred())
+	DeferCleanup(ln.Close)
+
+	s := &sshStub{addr: ln.Addr().String(), hostKey: signer.PublicKey(), cmds: make(chan string, 10)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, cfg)
+		}
+	}()
+	return s
+}
+
+func (s *sshStub) serve(conn net.Conn, cfg *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			_ = nc.Reject(ssh.UnknownChannelType, "session only")
+			continue
+		}
+		ch, creqs, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer ch.Close()
+			for req := range creqs {
+				if req.Type != "exec" {
+					_ = req.Reply(false, nil)
+					continue
+				}
+				var p struct{ Command string }
+				_ = ssh.Unmarshal(req.Payload, &p)
+				s.cmds <- p.Command
+				_ = req.Reply(true, nil)
+				_, _ = ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+				return
+			}
+		}()
+	}
+}
+
+var _ = Describe("prod backend", func() {
+	const mac = "aa:bb:cc:dd:ee:ff"
+	var (
+		ctx   = context.Background()
+		udp   net.PacketConn
+		sshd  *sshStub
+		k8s   *fake.Clientset
+		be    *prodBackend
+		clock time.Time
+		rc    *rcv1.RcNode
+	)
+
+	readPacket := func() []byte {
+		buf := make([]byte, 256)
+		_ = udp.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, _, err := udp.ReadFrom(buf)
+		if err != nil {
+			return nil
+		}
+		return buf[:n]
+	}
+
+	node := func(ready corev1.ConditionStatus) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "n1"},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: ready}}},
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		udp, err = net.ListenPacket("udp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(udp.Close)
+
+		sshd = startSSHStub("ops", "pw")
+		k8s = fake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "recluster", Name: "ssh"},
+			Data: map[string][]byte{
+				"username":   []byte("ops"),
+				"password":   []byte("pw"),
+				"knownHosts": []byte("127.0.0.1 " + string(ssh.MarshalAuthorizedKey(sshd.hostKey))),
+			},
+		})
+		clock = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		be = NewProdBackend(k8s, ProdConfig{
+			WoLAddr:   udp.LocalAddr().String(),
+			SSHSecret: types.NamespacedName{Namespace: "recluster", Name: "ssh"},
+		})
+		be.now = func() time.Time { return clock }
+
+		rc = &rcv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "n1"},
+			Spec: rcv1.RcNodeSpec{
+				Address:      sshd.addr,
+				BootSeconds:  30,
+				DesiredState: rcv1.DesiredStateRunning,
+				Network: []rcv1.RcNodeInterfaceSpec{
+					{Name: "wlan0", Address: "11:22:33:44:55:66"},
+					{Name: "eth0", Address: mac, WoL: []rcv1.WoLFlag{rcv1.WoLFlagP, rcv1.WoLFlagG}},
+				},
+			},
+		}
+	})
+
+	It("sends a magic packet to the WoL interface and repeats it after the boot time", func() {
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		pkt := readPacket()
+		Expect(pkt).To(HaveLen(102))
+		hw, _ := net.ParseMAC(mac)
+		Expect(pkt[:6]).To(Equal([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}))
+		Expect(pkt[6:12]).To(Equal([]byte(hw)))
+		Expect(pkt[96:]).To(Equal([]byte(hw)))
+
+		clock = clock.Add(10 * time.Second)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(readPacket()).To(BeNil())
+
+		clock = clock.Add(30 * time.Second)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(readPacket()).To(HaveLen(102))
+	})
+
+	It("observes power from the actions sent and the Node", func() {
+		obs, err := be.Observe(ctx, rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obs.Power).To(Equal(lifecycle.PowerOff))
+
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		obs, _ = be.Observe(ctx, rc)
+		Expect(obs.Power).To(Equal(lifecycle.PowerPoweringOn))
+		Expect(obs.Boot).To(Equal(lifecycle.BootPowerOn))
+
+		_, err = k8s.CoreV1().Nodes().Create(ctx, node(corev1.ConditionTrue), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		obs, _ = be.Observe(ctx, rc)
+		Expect(obs.Power).To(Equal(lifecycle.PowerOn))
+		Expect(obs.Boot).To(Equal(lifecycle.BootReady))
+		Expect(obs.PowerWatts).To(BeNil())
+
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		obs, _ = be.Observe(ctx, rc)
+		Expect(obs.Power).To(Equal(lifecycle.PowerPoweringOff))
+	})
+
+	It("stops waking once the Node has joined", func() {
+		_, err := k8s.CoreV1().Nodes().Create(ctx, node(corev1.ConditionFalse), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(readPacket()).To(BeNil())
+	})
+
+	It("fails without a Wake-on-LAN capable interface", func() {
+		rc.Spec.Network[1].WoL = nil
+		Expect(be.Reconcile(ctx, rc)).To(MatchError(ContainSubstring("no interface")))
+		obs, err := be.Observe(ctx, rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obs.LastError).To(ContainSubstring("no interface"))
+	})
+
+	It("shuts a Ready node down over SSH, then removes the Node once it is NotReady", func() {
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		_, err := k8s.CoreV1().Nodes().Create(ctx, node(corev1.ConditionTrue), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Eventually(sshd.cmds).Should(Receive(Equal(DefaultPoweroffCommand)))
+
+		// still Ready right after: no second shutdown yet
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Consistently(sshd.cmds, 100*time.Millisecond).ShouldNot(Receive())
+
+		_, err = k8s.CoreV1().Nodes().Update(ctx, node(corev1.ConditionFalse), metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		nodes, err := k8s.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes.Items).To(BeEmpty())
+	})
+
+	It("refuses hosts whose key is not in knownHosts", func() {
+		_, other, _ := ed25519.GenerateKey(rand.Reader)
+		signer, _ := ssh.NewSignerFromKey(other)
+		sec, _ := k8s.CoreV1().Secrets("recluster").Get(ctx, "ssh", metav1.GetOptions{})
+		sec.Data["knownHosts"] = []byte("127.0.0.1 " + string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+		_, err := k8s.CoreV1().Secrets("recluster").Update(ctx, sec, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		_, err = k8s.CoreV1().Nodes().Create(ctx, node(corev1.ConditionTrue), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.Reconcile(ctx, rc)).To(MatchError(ContainSubstring("host key")))
+		Expect(sshd.cmds).NotTo(Receive())
+	})
+
+	It("requires knownHosts unless host key checks are explicitly skipped", func() {
+		sec, _ := k8s.CoreV1().Secrets("recluster").Get(ctx, "ssh", metav1.GetOptions{})
+		delete(sec.Data, "knownHosts")
+		_, err := k8s.CoreV1().Secrets("recluster").Update(ctx, sec, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		_, err = k8s.CoreV1().Nodes().Create(ctx, node(corev1.ConditionTrue), metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.Reconcile(ctx, rc)).To(MatchError(ContainSubstring(`missing "knownHosts"`)))
+		Expect(sshd.cmds).NotTo(Receive())
+
+		sec.Data["insecureIgnoreHostKey"] = []byte("true")
+		_, err = k8s.CoreV1().Secrets("recluster").Update(ctx, sec, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Eventually(sshd.cmds).Should(Receive(Equal(DefaultPoweroffCommand)))
+	})
+
+	It("reads its configuration from the chart's environment", func() {
+		GinkgoT().Setenv("RECLUSTER_WOL_ADDR", "10.0.0.255:7")
+		GinkgoT().Setenv("RECLUSTER_SSH_SECRET", "recluster/ssh")
+		GinkgoT().Setenv("RECLUSTER_SSH_PORT", "2222")
+		cfg, err := ProdConfigFromEnv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.WoLAddr).To(Equal("10.0.0.255:7"))
+		Expect(cfg.SSHSecret).To(Equal(types.NamespacedName{Namespace: "recluster", Name: "ssh"}))
+		Expect(cfg.SSHPort).To(Equal(2222))
+
+		GinkgoT().Setenv("RECLUSTER_SSH_SECRET", "ssh")
+		_, err = ProdConfigFromEnv()
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,349 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	typedcore "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// RcNode annotations locating the node's BMC. AnnBMCEndpoint and
+// AnnBMCInsecure are only honoured next to AnnBMCSecret: the default
+// credentials are only ever sent to the endpoint of their own Secret.
+const (
+	AnnBMCEndpoint = "recluster.io/bmc-endpoint"             // https://10.0.0.5[:port]
+	AnnBMCSystem   = "recluster.io/bmc-system"               // /redfish/v1/Systems/<id> (a path on the BMC); default: first member
+	AnnBMCSecret   = "recluster.io/bmc-secret"               // Secret in the RcNode's namespace
+	AnnBMCInsecure = "recluster.io/bmc-insecure-skip-verify" // "true"
+)
+
+// Keys read from the BMC credentials Secret.
+const (
+	bmcKeyUsername = "username"
+	bmcKeyPassword = "password"
+	bmcKeyEndpoint = "endpoint"           // used when the annotation is unset
+	bmcKeyCABundle = "caBundle"           // PEM CA for the BMC certificate
+	bmcKeyInsecure = "insecureSkipVerify" // "true"
+)
+
+// ----------------------------------------------------------------------------
+// Configuration
+// ----------------------------------------------------------------------------
+
+// RedfishConfig configures the out-of-band backend.
+type RedfishConfig struct {
+	// Secret holds the BMC credentials of RcNodes without AnnBMCSecret.
+	Secret types.NamespacedName
+	// Timeout bounds one HTTP request to a BMC (10s if 0).
+	Timeout time.Duration
+	// ShutdownGrace is how long the OS gets to honour GracefulShutdown
+	// before ForceOff is sent (2m if 0).
+	ShutdownGrace time.Duration
+}
+
+// RedfishConfigFromEnv reads the settings injected by the Helm chart:
+//
+//	RECLUSTER_BMC_SECRET          <namespace>/<name> of the default credentials
+//	RECLUSTER_BMC_SHUTDOWN_GRACE  e.g. "90s"
+func RedfishConfigFromEnv() (RedfishConfig, error) {
+	var cfg RedfishConfig
+	if s := os.Getenv("RECLUSTER_BMC_SECRET"); s != "" {
+		ns, name, ok := strings.Cut(s, "/")
+		if !ok || ns == "" || name == "" {
+			return cfg, fmt.Errorf("RECLUSTER_BMC_SECRET=%q: want <namespace>/<name>", s)
+		}
+		cfg.Secret = types.NamespacedName{Namespace: ns, Name: name}
+	}
+	if s := os.Getenv("RECLUSTER_BMC_SHUTDOWN_GRACE"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return cfg, fmt.Errorf("RECLUSTER_BMC_SHUTDOWN_GRACE=%q: %w", s, err)
+		}
+		cfg.ShutdownGrace = d
+	}
+	return cfg, nil
+}
+
+// ----------------------------------------------------------------------------
+// Constructor
+// ----------------------------------------------------------------------------
+
+// redfishBackend powers machines through their BMC: ComputerSystem.Reset
+// On to start, GracefulShutdown (then ForceOff) to stop. Unlike prod it
+// sees the real PowerState, so it needs no Node to know a machine is up.
+type redfishBackend struct {
+	core typedcore.CoreV1Interface
+	cfg  RedfishConfig
+	now  func() time.Time
+
+	mu        sync.Mutex
+	conns     map[string]*bmcConn  // RcNode namespace/name → BMC connection
+	shutdowns map[string]time.Time // RcNode namespace/name → GracefulShutdown sent
+	errs      lastErrors
+}
+
+// bmcConn is a logged-in client, valid while its key (endpoint, Secret
+// version, TLS options) does not change.
+type bmcConn struct {
+	key    string
+	client *redfishClient
+	system string // ComputerSystem URI
+}
+
+func NewRedfishBackend(k8s kubernetes.Interface, cfg RedfishConfig) *redfishBackend {
+	return &redfishBackend{
+		core:      k8s.CoreV1(),
+		cfg:       cfg,
+		now:       time.Now,
+		conns:     map[string]*bmcConn{},
+		shutdowns: map[string]time.Time{},
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Reconcile
+// ----------------------------------------------------------------------------
+func (b *redfishBackend) Reconcile(ctx context.Context, rc *rcv1.RcNode) error {
+	return b.errs.record(rc, b.reconcile(ctx, rc))
+}
+
+func (b *redfishBackend) reconcile(ctx context.Context, rc *rcv1.RcNode) error {
+	conn, err := b.connect(ctx, rc)
+	if err != nil {
+		return err
+	}
+	sys, err := conn.client.system(ctx, conn.system)
+	if err != nil {
+		return err
+	}
+	key := rc.Namespace + "/" + rc.Name
+
+	if rc.Spec.DesiredState == rcv1.DesiredStateRunning {
+		b.clearShutdown(key)
+		if sys.PowerState != PowerStateOff {
+			return nil // On, or already PoweringOn
+		}
+		klog.Infof("redfish: powering on RcNode %q (%s)", rc.Name, conn.system)
+		return conn.client.reset(ctx, conn.system, sys, "On")
+	}
+
+	switch sys.PowerState {
+	// ----------------------------------------------------------------------
+	// 1) off  ►  drop the Node the kubelet left behind
+	// ----------------------------------------------------------------------
+	case PowerStateOff:
+		b.clearShutdown(key)
+		node, err := findNode(ctx, b.core, rc)
+		if err != nil || node == nil {
+			return err
+		}
+		klog.Infof("redfish: RcNode %q is off, removing node %q", rc.Name, node.Name)
+		err = b.core.Nodes().Delete(ctx, node.Name, metav1.DeleteOptions{})
+		if err != nil && !isNotFound(err) {
+			return err
+		}
+		return nil
+
+	// ----------------------------------------------------------------------
+	// 2) on  ►  GracefulShutdown, ForceOff once ShutdownGrace has passed
+	// ----------------------------------------------------------------------
+	case PowerStateOn, PowerStatePoweringOn:
+		sent, ok := b.shutdownSent(key)
+		resetType := "GracefulShutdown"
+		switch {
+		case !sys.supports(resetType):
+			resetType = "ForceOff"
+		case ok && b.now().Sub(sent) < b.shutdownGrace():
+			return nil // give the OS time
+		case ok:
+			resetType = "ForceOff"
+		}
+		klog.Infof("redfish: %s RcNode %q (%s)", resetType, rc.Name, conn.system)
+		if err := conn.client.reset(ctx, conn.system, sys, resetType); err != nil {
+			return err
+		}
+		b.recordShutdown(key)
+		return nil
+
+	// ----------------------------------------------------------------------
+	// 3) PoweringOff  ►  wait
+	// ----------------------------------------------------------------------
+	default:
+		return nil
+	}
+}
+
+// ----------------------------------------------------------------------------
+// Observe
+// ----------------------------------------------------------------------------
+
+// Observe reads PowerState from the BMC and, when the chassis exposes it,
+// the power draw; boot progress comes from the Node.
+func (b *redfishBackend) Observe(ctx context.Context, rc *rcv1.RcNode) (lifecycle.Observation, error) {
+	obs := lifecycle.Observation{Power: lifecycle.PowerUnknown, LastError: b.errs.get(rc)}
+	conn, err := b.connect(ctx, rc)
+	if err != nil {
+		return obs, err
+	}
+	sys, err := conn.client.system(ctx, conn.system)
+	if err != nil {
+		return obs, err
+	}
+	switch p := lifecycle.PowerState(sys.PowerState); p {
+	case lifecycle.PowerOn, lifecycle.PowerOff, lifecycle.PowerPoweringOn, lifecycle.PowerPoweringOff:
+		obs.Power = p
+	}
+
+	if w, err := conn.client.powerWatts(ctx, sys); err != nil {
+		klog.V(2).Infof("redfish: RcNode %q: no power reading: %v", rc.Name, err)
+	} else {
+		obs.PowerWatts = w
+	}
+
+	if obs.Power == lifecycle.PowerOff || obs.Power == lifecycle.PowerUnknown {
+		return obs, nil
+	}
+	node, err := findNode(ctx, b.core, rc)
+	if err != nil {
+		return obs, err
+	}
+	obs.Boot, obs.Heartbeat = lifecycle.NodeProgress(node)
+	return obs, nil
+}
+
+// ----------------------------------------------------------------------------
+// BMC connection
+// ----------------------------------------------------------------------------
+
+// connect returns the cached connection for rc, (re)creating it when the
+// annotations or the credentials Secret changed.
+func (b *redfishBackend) connect(ctx context.Context, rc *rcv1.RcNode) (*bmcConn, error) {
+	sec, err := b.bmcSecret(ctx, rc)
+	if err != nil {
+		return nil, err
+	}
+	// whoever may annotate an RcNode must not be able to send the
+	// default credentials to a host of their choosing
+	if rc.Annotations[AnnBMCSecret] == "" {
+		for _, ann := range []string{AnnBMCEndpoint, AnnBMCInsecure} {
+			if _, set := rc.Annotations[ann]; set {
+				return nil, fmt.Errorf("RcNode %q: annotation %s needs %s (the default credentials only go to their Secret's %q)",
+					rc.Name, ann, AnnBMCSecret, bmcKeyEndpoint)
+			}
+		}
+	}
+	if system, set := rc.Annotations[AnnBMCSystem]; set {
+		if err := checkRedfishPath(system); err != nil {
+			return nil, fmt.Errorf("RcNode %q: annotation %s: %w", rc.Name, AnnBMCSystem, err)
+		}
+	}
+	endpoint := rc.Annotations[AnnBMCEndpoint]
+	if endpoint == "" {
+		endpoint = string(sec.Data[bmcKeyEndpoint])
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("RcNode %q: no BMC endpoint (annotation %s or secret key %q)",
+			rc.Name, AnnBMCEndpoint, bmcKeyEndpoint)
+	}
+	tlsOpts := redfishTLS{
+		CABundle: sec.Data[bmcKeyCABundle],
+		InsecureSkipVerify: rc.Annotations[AnnBMCInsecure] == "true" ||
+			string(sec.Data[bmcKeyInsecure]) == "true",
+	}
+	connKey := strings.Join([]string{endpoint, rc.Annotations[AnnBMCSystem],
+		sec.Namespace, sec.Name, sec.ResourceVersion, fmt.Sprint(tlsOpts.InsecureSkipVerify)}, "|")
+
+	rcKey := rc.Namespace + "/" + rc.Name
+	b.mu.Lock()
+	old := b.conns[rcKey]
+	b.mu.Unlock()
+	if old != nil && old.key == connKey {
+		return old, nil
+	}
+	if old != nil {
+		old.client.close(ctx)
+	}
+
+	user, pass := string(sec.Data[bmcKeyUsername]), string(sec.Data[bmcKeyPassword])
+	if user == "" {
+		return nil, fmt.Errorf("secret %s/%s: missing %q", sec.Namespace, sec.Name, bmcKeyUsername)
+	}
+	client, err := newRedfishClient(endpoint, user, pass, tlsOpts, b.timeout())
+	if err != nil {
+		return nil, fmt.Errorf("secret %s/%s: %w", sec.Namespace, sec.Name, err)
+	}
+	system := rc.Annotations[AnnBMCSystem]
+	if system == "" {
+		if system, err = client.firstSystem(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	conn := &bmcConn{key: connKey, client: client, system: system}
+	b.mu.Lock()
+	b.conns[rcKey] = conn
+	b.mu.Unlock()
+	return conn, nil
+}
+
+// bmcSecret fetches the Secret named by AnnBMCSecret, or the default one.
+func (b *redfishBackend) bmcSecret(ctx context.Context, rc *rcv1.RcNode) (*corev1.Secret, error) {
+	ref := b.cfg.Secret
+	if name := rc.Annotations[AnnBMCSecret]; name != "" {
+		ref = types.NamespacedName{Namespace: rc.Namespace, Name: name}
+	}
+	if ref.Name == "" {
+		return nil, fmt.Errorf("RcNode %q: no BMC credentials (annotation %s or RECLUSTER_BMC_SECRET)",
+			rc.Name, AnnBMCSecret)
+	}
+	return b.core.Secrets(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+}
+
+// ----------------------------------------------------------------------------
+// helpers
+// ----------------------------------------------------------------------------
+
+func (b *redfishBackend) timeout() time.Duration {
+	if b.cfg.Timeout > 0 {
+		return b.cfg.Timeout
+	}
+	return 10 * time.Second
+}
+
+func (b *redfishBackend) shutdownGrace() time.Duration {
+	if b.cfg.ShutdownGrace > 0 {
+		return b.cfg.ShutdownGrace
+	}
+	return 2 * time.Minute
+}
+
+func (b *redfishBackend) shutdownSent(key string) (time.Time, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t, ok := b.shutdowns[key]
+	return t, ok
+}
+
+func (b *redfishBackend) recordShutdown(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.shutdowns[key] = b.now()
+}
+
+func (b *redfishBackend) clearShutdown(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.shutdowns, key)
+}
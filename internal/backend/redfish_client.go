@@ -0,0 +1,335 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	redfishSystems  = "/redfish/v1/Systems"
+	redfishSessions = "/redfish/v1/SessionService/Sessions"
+	redfishReset    = "/Actions/ComputerSystem.Reset"
+)
+
+// Redfish PowerState values.
+const (
+	PowerStateOn          = "On"
+	PowerStateOff         = "Off"
+	PowerStatePoweringOn  = "PoweringOn"
+	PowerStatePoweringOff = "PoweringOff"
+)
+
+// redfishTLS is how the BMC's (usually self-signed) certificate is checked.
+type redfishTLS struct {
+	CABundle           []byte // PEM; empty → system roots
+	InsecureSkipVerify bool
+}
+
+// redfishClient talks to one BMC. It logs in through the SessionService and
+// re-logs in once when the session has expired; BMCs without a
+// SessionService get HTTP basic auth on every request.
+type redfishClient struct {
+	base       string // scheme://host[:port]
+	host       string // host[:port] of base, the only one requests go to
+	user, pass string
+	http       *http.Client
+
+	mu      sync.Mutex
+	token   string // X-Auth-Token of the current session
+	session string // its URI, deleted on close
+	basic   bool   // no SessionService
+}
+
+func newRedfishClient(endpoint, user, pass string, tlsOpts redfishTLS, timeout time.Duration) (*redfishClient, error) {
+	tc := &tls.Config{InsecureSkipVerify: tlsOpts.InsecureSkipVerify}
+	if len(tlsOpts.CABundle) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(tlsOpts.CABundle) {
+			return nil, fmt.Errorf("caBundle: no PEM certificates")
+		}
+		tc.RootCAs = pool
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || u.User != nil {
+		return nil, fmt.Errorf("endpoint %q: want scheme://host[:port]", endpoint)
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = tc
+	return &redfishClient{
+		base: strings.TrimSuffix(endpoint, "/"),
+		host: u.Host,
+		user: user,
+		pass: pass,
+		http: &http.Client{Transport: tr, Timeout: timeout},
+	}, nil
+}
+
+// redfishError is a non-2xx answer, with the BMC's own message if any.
+type redfishError struct {
+	Status  int
+	Message string
+}
+
+func (e *redfishError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("redfish: HTTP %d", e.Status)
+	}
+	return fmt.Sprintf("redfish: HTTP %d: %s", e.Status, e.Message)
+}
+
+// do sends one request (in, out may be nil) with session handling.
+func (c *redfishClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	for attempt := 0; ; attempt++ {
+		if err := c.ensureSession(ctx); err != nil {
+			return err
+		}
+		resp, err := c.send(ctx, method, path, in)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 && !c.usesBasic() {
+			resp.Body.Close()
+			c.dropSession()
+			continue
+		}
+		return decodeRedfish(resp, out)
+	}
+}
+
+func (c *redfishClient) send(ctx context.Context, method, path string, in interface{}) (*http.Response, error) {
+	if err := checkRedfishPath(path); err != nil {
+		return nil, err
+	}
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL.Host != c.host || req.URL.User != nil {
+		return nil, fmt.Errorf("redfish: %q leads away from the BMC at %s", path, c.host)
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	c.mu.Lock()
+	switch {
+	case c.basic:
+		req.SetBasicAuth(c.user, c.pass)
+	case c.token != "":
+		req.Header.Set("X-Auth-Token", c.token)
+	}
+	c.mu.Unlock()
+	return c.http.Do(req)
+}
+
+// checkRedfishPath accepts absolute paths on the BMC only: paths are
+// appended to the endpoint, so "@host/x" or "//host/x" would take the
+// credentials to another host.
+func checkRedfishPath(p string) error {
+	u, err := url.Parse(p)
+	if err != nil || !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") ||
+		strings.ContainsAny(p, "@\\") || u.Scheme != "" || u.Host != "" || u.User != nil {
+		return fmt.Errorf("redfish: %q is not a path on the BMC", p)
+	}
+	return nil
+}
+
+func decodeRedfish(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error struct {
+				Message  string `json:"message"`
+				Extended []struct {
+					Message string `json:"Message"`
+				} `json:"@Message.ExtendedInfo"`
+			} `json:"error"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		msg := e.Error.Message
+		if len(e.Error.Extended) > 0 && e.Error.Extended[0].Message != "" {
+			msg = e.Error.Extended[0].Message
+		}
+		return &redfishError{Status: resp.StatusCode, Message: msg}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// ----------------------------------------------------------------------------
+// Sessions
+// ----------------------------------------------------------------------------
+
+func (c *redfishClient) usesBasic() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.basic
+}
+
+func (c *redfishClient) ensureSession(ctx context.Context) error {
+	c.mu.Lock()
+	ready := c.basic || c.token != ""
+	c.mu.Unlock()
+	if ready {
+		return nil
+	}
+
+	resp, err := c.send(ctx, http.MethodPost, redfishSessions,
+		map[string]string{"UserName": c.user, "Password": c.pass})
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		resp.Body.Close()
+		c.mu.Lock()
+		c.basic = true
+		c.mu.Unlock()
+		return nil
+	}
+	tok, loc := resp.Header.Get("X-Auth-Token"), resp.Header.Get("Location")
+	if err := decodeRedfish(resp, nil); err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	if tok == "" {
+		return fmt.Errorf("login: BMC returned no X-Auth-Token")
+	}
+	// Location may be absolute
+	loc = strings.TrimPrefix(loc, c.base)
+
+	c.mu.Lock()
+	c.token, c.session = tok, loc
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *redfishClient) dropSession() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token, c.session = "", ""
+}
+
+// close ends the session, if there is one; BMCs only allow a handful.
+func (c *redfishClient) close(ctx context.Context) {
+	c.mu.Lock()
+	session := c.session
+	c.mu.Unlock()
+	if session == "" {
+		return
+	}
+	if resp, err := c.send(ctx, http.MethodDelete, session, nil); err == nil {
+		resp.Body.Close()
+	}
+	c.dropSession()
+}
+
+// ----------------------------------------------------------------------------
+// ComputerSystem
+// ----------------------------------------------------------------------------
+
+// redfishSystem is the part of a ComputerSystem we use.
+type redfishSystem struct {
+	ID         string `json:"Id"`
+	PowerState string `json:"PowerState"`
+	Actions    struct {
+		Reset struct {
+			Target  string   `json:"target"`
+			Allowed []string `json:"ResetType@Redfish.AllowableValues"`
+		} `json:"#ComputerSystem.Reset"`
+	} `json:"Actions"`
+	Links struct {
+		Chassis []struct {
+			ID string `json:"@odata.id"`
+		} `json:"Chassis"`
+	} `json:"Links"`
+}
+
+// firstSystem returns the URI of the first member of the Systems collection.
+func (c *redfishClient) firstSystem(ctx context.Context) (string, error) {
+	var coll struct {
+		Members []struct {
+			ID string `json:"@odata.id"`
+		} `json:"Members"`
+	}
+	if err := c.do(ctx, http.MethodGet, redfishSystems, nil, &coll); err != nil {
+		return "", err
+	}
+	if len(coll.Members) == 0 {
+		return "", fmt.Errorf("redfish: no ComputerSystem on %s", c.base)
+	}
+	return coll.Members[0].ID, nil
+}
+
+func (c *redfishClient) system(ctx context.Context, uri string) (*redfishSystem, error) {
+	var sys redfishSystem
+	if err := c.do(ctx, http.MethodGet, uri, nil, &sys); err != nil {
+		return nil, err
+	}
+	return &sys, nil
+}
+
+// reset posts ComputerSystem.Reset with resetType to sys (found at uri).
+func (c *redfishClient) reset(ctx context.Context, uri string, sys *redfishSystem, resetType string) error {
+	target := sys.Actions.Reset.Target
+	if target == "" {
+		target = uri + redfishReset
+	}
+	return c.do(ctx, http.MethodPost, target, map[string]string{"ResetType": resetType}, nil)
+}
+
+// powerWatts reads PowerConsumedWatts from the Power resource of the
+// system's first chassis (nil if the system links none).
+func (c *redfishClient) powerWatts(ctx context.Context, sys *redfishSystem) (*int, error) {
+	if len(sys.Links.Chassis) == 0 {
+		return nil, nil
+	}
+	var pw struct {
+		PowerControl []struct {
+			PowerConsumedWatts *float64 `json:"PowerConsumedWatts"`
+		} `json:"PowerControl"`
+	}
+	if err := c.do(ctx, http.MethodGet, sys.Links.Chassis[0].ID+"/Power", nil, &pw); err != nil {
+		return nil, err
+	}
+	if len(pw.PowerControl) == 0 || pw.PowerControl[0].PowerConsumedWatts == nil {
+		return nil, nil
+	}
+	w := int(math.Round(*pw.PowerControl[0].PowerConsumedWatts))
+	return &w, nil
+}
+
+// supports reports whether the BMC advertises resetType (an empty list
+// means it does not say, so assume yes).
+func (s *redfishSystem) supports(resetType string) bool {
+	if len(s.Actions.Reset.Allowed) == 0 {
+		return true
+	}
+	for _, t := range s.Actions.Reset.Allowed {
+		if t == resetType {
+			return true
+		}
+	}
+	return false
+}
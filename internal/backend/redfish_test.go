@@ -0,0 +1,198 @@
+package backend
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/backend/redfishmock"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+)
+
+var _ = Describe("redfish backend", func() {
+	var (
+		ctx   = context.Background()
+		bmc   *redfishmock.Server
+		srv   *httptest.Server
+		k8s   *fake.Clientset
+		be    *redfishBackend
+		clock time.Time
+		rc    *rcv1.RcNode
+		sec   *corev1.Secret
+	)
+
+	BeforeEach(func() {
+		bmc = redfishmock.New("admin", "s3cret", "blade-1", "blade-2")
+		srv = httptest.NewTLSServer(bmc)
+		DeferCleanup(srv.Close)
+
+		sec = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "lab", Name: "bmc", ResourceVersion: "1"},
+			Data: map[string][]byte{
+				"username": []byte("admin"),
+				"password": []byte("s3cret"),
+				"caBundle": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}),
+			},
+		}
+		k8s = fake.NewSimpleClientset(sec)
+		clock = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+		be = NewRedfishBackend(k8s, RedfishConfig{ShutdownGrace: time.Minute})
+		be.now = func() time.Time { return clock }
+
+		rc = &rcv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "lab", Name: "n1", Annotations: map[string]string{
+				AnnBMCEndpoint: srv.URL,
+				AnnBMCSecret:   "bmc",
+				AnnBMCSystem:   "/redfish/v1/Systems/blade-2",
+			}},
+			Spec: rcv1.RcNodeSpec{DesiredState: rcv1.DesiredStateRunning},
+		}
+	})
+
+	It("powers the system on once and then only polls", func() {
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(bmc.PowerState("blade-2")).To(Equal(redfishmock.PowerOn))
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(bmc.Resets()).To(Equal([]string{"blade-2:On"}))
+		Expect(bmc.PowerState("blade-1")).To(Equal(redfishmock.PowerOff))
+	})
+
+	It("observes power state and draw", func() {
+		bmc.Update("blade-2", func(s *redfishmock.System) { s.Watts = 212 })
+		obs, err := be.Observe(ctx, rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obs.Power).To(Equal(lifecycle.PowerOff))
+		Expect(*obs.PowerWatts).To(Equal(0))
+
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		obs, err = be.Observe(ctx, rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obs.Power).To(Equal(lifecycle.PowerOn))
+		Expect(obs.Boot).To(Equal(lifecycle.BootPowerOn))
+		Expect(*obs.PowerWatts).To(Equal(212))
+	})
+
+	It("uses the first system when none is annotated", func() {
+		delete(rc.Annotations, AnnBMCSystem)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(bmc.Resets()).To(Equal([]string{"blade-1:On"}))
+	})
+
+	It("shuts down gracefully, escalates to ForceOff and removes the Node", func() {
+		bmc.Update("blade-2", func(s *redfishmock.System) {
+			s.PowerState = redfishmock.PowerOn
+			s.IgnoreGraceful = true
+		})
+		_, err := k8s.CoreV1().Nodes().Create(ctx,
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}}, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		clock = clock.Add(30 * time.Second)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(bmc.Resets()).To(Equal([]string{"blade-2:GracefulShutdown"}))
+
+		clock = clock.Add(time.Minute)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(bmc.Resets()).To(Equal([]string{"blade-2:GracefulShutdown", "blade-2:ForceOff"}))
+		Expect(bmc.PowerState("blade-2")).To(Equal(redfishmock.PowerOff))
+
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		nodes, err := k8s.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes.Items).To(BeEmpty())
+	})
+
+	It("reuses its session and logs in again after it expired", func() {
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(bmc.Logins()).To(Equal(1))
+
+		bmc.ExpireSessions()
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(bmc.Logins()).To(Equal(2))
+	})
+
+	It("closes the old session when the credentials Secret changes", func() {
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(bmc.Sessions()).To(Equal(1))
+
+		sec.ResourceVersion = "2"
+		_, err := k8s.CoreV1().Secrets("lab").Update(ctx, sec, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(bmc.Logins()).To(Equal(2))
+		Expect(bmc.Sessions()).To(Equal(1))
+	})
+
+	It("verifies the BMC certificate unless told not to", func() {
+		delete(sec.Data, "caBundle")
+		_, err := k8s.CoreV1().Secrets("lab").Update(ctx, sec, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.Reconcile(ctx, rc)).To(MatchError(ContainSubstring("certificate")))
+
+		rc.Annotations[AnnBMCInsecure] = "true"
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+	})
+
+	It("sends the default credentials only to the endpoint of their Secret", func() {
+		be = NewRedfishBackend(k8s, RedfishConfig{Secret: types.NamespacedName{Namespace: "lab", Name: "bmc"}})
+		delete(rc.Annotations, AnnBMCSecret)
+		Expect(be.Reconcile(ctx, rc)).To(MatchError(ContainSubstring("annotation recluster.io/bmc-endpoint needs recluster.io/bmc-secret")))
+
+		delete(rc.Annotations, AnnBMCEndpoint)
+		rc.Annotations[AnnBMCInsecure] = "true"
+		sec.Data["endpoint"] = []byte(srv.URL)
+		_, err := k8s.CoreV1().Secrets("lab").Update(ctx, sec, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.Reconcile(ctx, rc)).To(MatchError(ContainSubstring("annotation recluster.io/bmc-insecure-skip-verify needs")))
+		Expect(bmc.Logins()).To(BeZero())
+
+		delete(rc.Annotations, AnnBMCInsecure)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(bmc.Logins()).To(Equal(1))
+	})
+
+	It("refuses a system annotation leading to another host", func() {
+		hits := 0
+		evil := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { hits++ }))
+		DeferCleanup(evil.Close)
+		evilHost := strings.TrimPrefix(evil.URL, "https://")
+
+		be = NewRedfishBackend(k8s, RedfishConfig{Secret: types.NamespacedName{Namespace: "lab", Name: "bmc"}})
+		delete(rc.Annotations, AnnBMCSecret)
+		delete(rc.Annotations, AnnBMCEndpoint)
+		sec.Data["endpoint"] = []byte(srv.URL)
+		_, err := k8s.CoreV1().Secrets("lab").Update(ctx, sec, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, system := range []string{"@" + evilHost + "/x", "//" + evilHost + "/x", evil.URL + "/x", "redfish/v1"} {
+			rc.Annotations[AnnBMCSystem] = system
+			Expect(be.Reconcile(ctx, rc)).To(MatchError(ContainSubstring("is not a path on the BMC")), system)
+		}
+		Expect(hits).To(BeZero())
+		Expect(bmc.Logins()).To(BeZero())
+	})
+
+	It("reports bad credentials and a missing endpoint", func() {
+		sec.Data["password"] = []byte("wrong")
+		_, err := k8s.CoreV1().Secrets("lab").Update(ctx, sec, metav1.UpdateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be.Reconcile(ctx, rc)).To(MatchError(ContainSubstring("401")))
+
+		delete(rc.Annotations, AnnBMCEndpoint)
+		Expect(be.Reconcile(ctx, rc)).To(MatchError(ContainSubstring("no BMC endpoint")))
+	})
+})
@@ -0,0 +1,324 @@
+// Package redfishmock is a minimal Redfish BMC: just enough of the service
+// root, SessionService, ComputerSystem (PowerState, ComputerSystem.Reset)
+// and the chassis Power resource to exercise the redfish backend without
+// real hardware.
+//
+//	srv := httptest.NewTLSServer(redfishmock.New("admin", "secret", "1"))
+package redfishmock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Power states reported in ComputerSystem.PowerState.
+const (
+	PowerOn          = "On"
+	PowerOff         = "Off"
+	PowerPoweringOn  = "PoweringOn"
+	PowerPoweringOff = "PoweringOff"
+)
+
+// ResetTypes the mock accepts.
+var ResetTypes = []string{"On", "ForceOff", "GracefulShutdown", "ForceRestart", "PushPowerButton"}
+
+const (
+	rootPath     = "/redfish/v1"
+	sessionsPath = rootPath + "/SessionService/Sessions"
+	systemsPath  = rootPath + "/Systems"
+	chassisPath  = rootPath + "/Chassis"
+	resetAction  = "/Actions/ComputerSystem.Reset"
+)
+
+// System is one mocked ComputerSystem.
+type System struct {
+	PowerState string
+	// IgnoreGraceful makes GracefulShutdown a no-op, like an OS that does
+	// not react to ACPI power-button events.
+	IgnoreGraceful bool
+	// Watts is reported as PowerConsumedWatts of the chassis while On.
+	Watts int
+}
+
+// Server is an http.Handler serving the mock BMC. All methods are safe for
+// concurrent use.
+type Server struct {
+	user, password string
+
+	mu       sync.Mutex
+	systems  map[string]*System
+	order    []string
+	sessions map[string]string // token → session id
+	logins   int
+	resets   []string // "<system id>:<ResetType>"
+}
+
+// New returns a BMC accepting user/password that manages the given
+// systems, all powered off.
+func New(user, password string, systemIDs ...string) *Server {
+	s := &Server{
+		user:     user,
+		password: password,
+		systems:  map[string]*System{},
+		sessions: map[string]string{},
+	}
+	for _, id := range systemIDs {
+		s.systems[id] = &System{PowerState: PowerOff}
+		s.order = append(s.order, id)
+	}
+	return s
+}
+
+// ----------------------------------------------------------------------------
+// Inspection / manipulation from tests
+// ----------------------------------------------------------------------------
+
+// Update runs fn on system id under the server lock.
+func (s *Server) Update(id string, fn func(*System)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.systems[id])
+}
+
+// PowerState returns the power state of system id.
+func (s *Server) PowerState(id string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.systems[id].PowerState
+}
+
+// Resets returns every accepted reset as "<system id>:<ResetType>".
+func (s *Server) Resets() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.resets...)
+}
+
+// Logins is the number of sessions created so far.
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// Sessions is the number of sessions currently open.
+func (s *Server) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// ExpireSessions drops every session, as a BMC does after its idle timeout.
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = map[string]string{}
+}
+
+// ----------------------------------------------------------------------------
+// HTTP
+// ----------------------------------------------------------------------------
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case path == rootPath && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id":      rootPath,
+			"RedfishVersion": "1.6.0",
+			"Systems":        odataID(systemsPath),
+			"SessionService": odataID(rootPath + "/SessionService"),
+		})
+		return
+	case path == sessionsPath && r.Method == http.MethodPost:
+		s.login(w, r)
+		return
+	}
+
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "Base.1.0.NoValidSession", "authentication required")
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(path, sessionsPath+"/") && r.Method == http.MethodDelete:
+		id := strings.TrimPrefix(path, sessionsPath+"/")
+		for tok, sid := range s.sessions {
+			if sid == id {
+				delete(s.sessions, tok)
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case path == systemsPath && r.Method == http.MethodGet:
+		members := make([]interface{}, 0, len(s.order))
+		for _, id := range s.order {
+			members = append(members, odataID(systemsPath+"/"+id))
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id":           systemsPath,
+			"Members@odata.count": len(members),
+			"Members":             members,
+		})
+
+	case strings.HasPrefix(path, systemsPath+"/") && strings.HasSuffix(path, resetAction):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, systemsPath+"/"), resetAction)
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "Base.1.0.ActionNotSupported", r.Method)
+			return
+		}
+		s.reset(w, r, id)
+
+	case strings.HasPrefix(path, chassisPath+"/") && strings.HasSuffix(path, "/Power") &&
+		r.Method == http.MethodGet:
+		id := strings.TrimSuffix(strings.TrimPrefix(path, chassisPath+"/"), "/Power")
+		sys, ok := s.systems[id]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Base.1.0.ResourceMissingAtURI", path)
+			return
+		}
+		watts := 0
+		if sys.PowerState == PowerOn {
+			watts = sys.Watts
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id":    path,
+			"PowerControl": []map[string]interface{}{{"PowerConsumedWatts": watts}},
+		})
+
+	case strings.HasPrefix(path, systemsPath+"/") && r.Method == http.MethodGet:
+		id := strings.TrimPrefix(path, systemsPath+"/")
+		sys, ok := s.systems[id]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Base.1.0.ResourceMissingAtURI", path)
+			return
+		}
+		self := systemsPath + "/" + id
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"@odata.id":  self,
+			"Id":         id,
+			"PowerState": sys.PowerState,
+			"Links": map[string]interface{}{
+				"Chassis": []interface{}{odataID(chassisPath + "/" + id)},
+			},
+			"Actions": map[string]interface{}{
+				"#ComputerSystem.Reset": map[string]interface{}{
+					"target":                            self + resetAction,
+					"ResetType@Redfish.AllowableValues": ResetTypes,
+				},
+			},
+		})
+
+	default:
+		writeError(w, http.StatusNotFound, "Base.1.0.ResourceMissingAtURI", path)
+	}
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	var body struct{ UserName, Password string }
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Base.1.0.MalformedJSON", err.Error())
+		return
+	}
+	if body.UserName != s.user || body.Password != s.password {
+		writeError(w, http.StatusUnauthorized, "Base.1.0.InvalidCredentials", "bad credentials")
+		return
+	}
+	s.logins++
+	id := fmt.Sprint(s.logins)
+	tok := randomToken()
+	s.sessions[tok] = id
+	w.Header().Set("X-Auth-Token", tok)
+	w.Header().Set("Location", sessionsPath+"/"+id)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"@odata.id": sessionsPath + "/" + id,
+		"Id":        id,
+		"UserName":  body.UserName,
+	})
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if tok := r.Header.Get("X-Auth-Token"); tok != "" {
+		_, ok := s.sessions[tok]
+		return ok
+	}
+	u, p, ok := r.BasicAuth()
+	return ok && u == s.user && p == s.password
+}
+
+func (s *Server) reset(w http.ResponseWriter, r *http.Request, id string) {
+	sys, ok := s.systems[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, "Base.1.0.ResourceMissingAtURI", r.URL.Path)
+		return
+	}
+	var body struct{ ResetType string }
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "Base.1.0.MalformedJSON", err.Error())
+		return
+	}
+
+	switch body.ResetType {
+	case "On":
+		sys.PowerState = PowerOn
+	case "ForceOff":
+		sys.PowerState = PowerOff
+	case "GracefulShutdown":
+		if !sys.IgnoreGraceful {
+			sys.PowerState = PowerOff
+		}
+	case "ForceRestart":
+		sys.PowerState = PowerOn
+	case "PushPowerButton":
+		if sys.PowerState == PowerOn {
+			sys.PowerState = PowerOff
+		} else {
+			sys.PowerState = PowerOn
+		}
+	default:
+		writeError(w, http.StatusBadRequest, "Base.1.0.ActionParameterValueNotInList", body.ResetType)
+		return
+	}
+	s.resets = append(s.resets, id+":"+body.ResetType)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ----------------------------------------------------------------------------
+// helpers
+// ----------------------------------------------------------------------------
+
+func odataID(p string) map[string]string { return map[string]string{"@odata.id": p} }
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers with a Redfish error body (code + ExtendedInfo).
+func writeError(w http.ResponseWriter, code int, msgID, msg string) {
+	writeJSON(w, code, map[string]interface{}{
+		"error": map[string]interface{}{
+			"code":    msgID,
+			"message": msg,
+			"@Message.ExtendedInfo": []map[string]string{
+				{"MessageId": msgID, "Message": msg},
+			},
+		},
+	})
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,209 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// Built-in driver names, as used in RcNode.Spec.Driver.
+const (
+	DriverKwok    = "kwok"
+	DriverProd    = "prod"
+	DriverRedfish = "redfish"
+	DriverTest    = "test"
+)
+
+// ErrUnknownDriver is returned for a driver name nobody registered.
+var ErrUnknownDriver = errors.New("unknown driver")
+
+// Factory builds a driver. It is called once, on first use.
+type Factory func(k8s kubernetes.Interface) (Backend, error)
+
+// builtins are registered in every Registry. The test driver is not one
+// of them, see EnableTestDriver.
+var builtins = map[string]Factory{
+	DriverKwok: func(k8s kubernetes.Interface) (Backend, error) {
+		cfg, err := KwokConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewKwokBackend(k8s, cfg), nil
+	},
+	DriverProd: func(k8s kubernetes.Interface) (Backend, error) {
+		cfg, err := ProdConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewProdBackend(k8s, cfg), nil
+	},
+	DriverRedfish: func(k8s kubernetes.Interface) (Backend, error) {
+		cfg, err := RedfishConfigFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return NewRedfishBackend(k8s, cfg), nil
+	},
+}
+
+// Registry picks the driver of each RcNode in a mixed fleet:
+// Spec.Driver, else the driver of its pool, else the default.
+type Registry struct {
+	k8s     kubernetes.Interface
+	def     string
+	pools   map[string]string // NodePool → driver
+	mu      sync.Mutex
+	factory map[string]Factory
+	built   map[string]builtDriver
+}
+
+type builtDriver struct {
+	be  Backend
+	err error
+}
+
+// NewRegistry returns a Registry holding the built-in drivers.
+func NewRegistry(k8s kubernetes.Interface, def string, pools map[string]string) *Registry {
+	r := &Registry{
+		k8s:     k8s,
+		def:     def,
+		pools:   pools,
+		factory: map[string]Factory{},
+		built:   map[string]builtDriver{},
+	}
+	for name, f := range builtins {
+		r.factory[name] = f
+	}
+	return r
+}
+
+// RegistryFromEnv reads the settings injected by the Helm chart:
+//
+//	RECLUSTER_BACKEND_MODE   default driver
+//	RECLUSTER_POOL_DRIVERS   <pool>=<driver>[,<pool>=<driver>…]
+//	RECLUSTER_PLUGINS        <driver>=<gRPC target>[,…] out-of-process drivers
+//	RECLUSTER_TEST_DRIVER    "true" registers the test driver
+func RegistryFromEnv(k8s kubernetes.Interface) (*Registry, error) {
+	pools, err := parsePoolDrivers(os.Getenv("RECLUSTER_POOL_DRIVERS"))
+	if err != nil {
+		return nil, err
+	}
+	plugins, err := parsePlugins(os.Getenv("RECLUSTER_PLUGINS"))
+	if err != nil {
+		return nil, err
+	}
+	r := NewRegistry(k8s, os.Getenv("RECLUSTER_BACKEND_MODE"), pools)
+	if os.Getenv("RECLUSTER_TEST_DRIVER") == "true" {
+		r.EnableTestDriver()
+	}
+	for name, target := range plugins {
+		r.Register(name, func(kubernetes.Interface) (Backend, error) {
+			return NewPluginBackend(name, target)
+		})
+	}
+	return r, nil
+}
+
+func parsePoolDrivers(s string) (map[string]string, error) {
+	pools := map[string]string{}
+	for _, kv := range strings.Split(s, ",") {
+		if kv = strings.TrimSpace(kv); kv == "" {
+			continue
+		}
+		pool, driver, ok := strings.Cut(kv, "=")
+		if !ok || pool == "" || driver == "" {
+			return nil, fmt.Errorf("RECLUSTER_POOL_DRIVERS: %q: want <pool>=<driver>", kv)
+		}
+		pools[pool] = driver
+	}
+	return pools, nil
+}
+
+// Register adds (or replaces) a driver.
+func (r *Registry) Register(name string, f Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factory[name] = f
+	delete(r.built, name)
+}
+
+// EnableTestDriver registers the test driver (FakeBackend). It is off by
+// default: any RcNode naming it gets a Node registered under its own name,
+// so it only belongs in CI clusters and test suites.
+func (r *Registry) EnableTestDriver() {
+	r.Register(DriverTest, func(k8s kubernetes.Interface) (Backend, error) {
+		return NewFakeBackend(k8s), nil
+	})
+}
+
+// Drivers lists the registered driver names.
+func (r *Registry) Drivers() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.namesLocked()
+}
+
+// DriverName is the driver rc asks for.
+func (r *Registry) DriverName(rc *rcv1.RcNode) string {
+	if rc.Spec.Driver != "" {
+		return rc.Spec.Driver
+	}
+	if d := r.pools[rc.Spec.NodePool]; d != "" {
+		return d
+	}
+	return r.def
+}
+
+// For returns the backend for rc and its driver name. The error wraps
+// ErrUnknownDriver, or is the driver's construction error.
+func (r *Registry) For(rc *rcv1.RcNode) (Backend, string, error) {
+	name := r.DriverName(rc)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b, ok := r.built[name]; ok {
+		return b.be, name, b.err
+	}
+	f, ok := r.factory[name]
+	if !ok {
+		return nil, name, fmt.Errorf("%w %q (have %s)", ErrUnknownDriver, name,
+			strings.Join(r.namesLocked(), ", "))
+	}
+	klog.Infof("Creating Backend for driver %q", name)
+	be, err := f(r.k8s)
+	if err != nil {
+		err = fmt.Errorf("driver %q: %w", name, err)
+	}
+	r.built[name] = builtDriver{be: be, err: err}
+	return be, name, err
+}
+
+// ByHostname reports whether the machines of rc's driver join the cluster
+// on their own, under their hostname, so that their Node may be found by
+// name (see MatchesNode). It is false for drivers registering the Node
+// themselves (see NodeManager) and for drivers that are unavailable.
+func (r *Registry) ByHostname(ctx context.Context, rc *rcv1.RcNode) bool {
+	be, _, err := r.For(rc)
+	if err != nil || be == nil {
+		return false
+	}
+	managed, err := ManagesNode(ctx, be)
+	return err == nil && !managed
+}
+
+func (r *Registry) namesLocked() []string {
+	names := make([]string, 0, len(r.factory))
+	for n := range r.factory {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
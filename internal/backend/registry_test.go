@@ -0,0 +1,107 @@
+package backend
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+)
+
+// nopBackend counts its calls.
+type nopBackend struct{ calls int }
+
+func (n *nopBackend) Reconcile(context.Context, *rcv1.RcNode) error { n.calls++; return nil }
+
+func (n *nopBackend) Observe(context.Context, *rcv1.RcNode) (lifecycle.Observation, error) {
+	return lifecycle.Observation{}, nil
+}
+
+var _ = Describe("backend registry", func() {
+	var reg *Registry
+	BeforeEach(func() {
+		reg = NewRegistry(fake.NewSimpleClientset(), DriverKwok, map[string]string{"rack": DriverRedfish})
+	})
+
+	rcNode := func(pool, driver string) *rcv1.RcNode {
+		return &rcv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "n1"},
+			Spec:       rcv1.RcNodeSpec{NodePool: pool, Driver: driver},
+		}
+	}
+
+	It("prefers the RcNode's driver, then its pool's, then the default", func() {
+		Expect(reg.DriverName(rcNode("rack", DriverProd))).To(Equal(DriverProd))
+		Expect(reg.DriverName(rcNode("rack", ""))).To(Equal(DriverRedfish))
+		Expect(reg.DriverName(rcNode("other", ""))).To(Equal(DriverKwok))
+	})
+
+	It("finds Nodes by name only for drivers whose machines join on their own", func() {
+		ctx := context.Background()
+		reg.Register("custom", func(kubernetes.Interface) (Backend, error) { return &nopBackend{}, nil })
+		Expect(reg.ByHostname(ctx, rcNode("", "custom"))).To(BeTrue())
+		Expect(reg.ByHostname(ctx, rcNode("", DriverKwok))).To(BeFalse())
+		Expect(reg.ByHostname(ctx, rcNode("", "missing"))).To(BeFalse())
+
+		rc := rcNode("", "")
+		Expect(MatchesNode(rc, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}}, true)).To(BeTrue())
+		Expect(MatchesNode(rc, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}}, false)).To(BeFalse())
+		Expect(MatchesNode(rc, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"},
+			Spec: corev1.NodeSpec{ProviderID: "aws:///i-0abc"}}, true)).To(BeFalse())
+		Expect(MatchesNode(rc, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "kwok-fake-n1"},
+			Spec: corev1.NodeSpec{ProviderID: ProviderID(rc)}}, false)).To(BeTrue())
+	})
+
+	It("builds each driver once and shares it", func() {
+		built := 0
+		nop := &nopBackend{}
+		reg.Register("custom", func(kubernetes.Interface) (Backend, error) { built++; return nop, nil })
+
+		a, name, err := reg.For(rcNode("", "custom"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("custom"))
+		b, _, _ := reg.For(rcNode("x", "custom"))
+		Expect(a).To(BeIdenticalTo(b))
+		Expect(built).To(Equal(1))
+
+		be, _, err := reg.For(rcNode("", ""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be).To(BeAssignableToTypeOf(&kwokBackend{}))
+	})
+
+	It("reports unknown and broken drivers without failing the others", func() {
+		_, name, err := reg.For(rcNode("", "ipmi"))
+		Expect(name).To(Equal("ipmi"))
+		Expect(errors.Is(err, ErrUnknownDriver)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("(have kwok, prod, redfish)"))
+
+		_, _, err = reg.For(rcNode("", DriverTest))
+		Expect(errors.Is(err, ErrUnknownDriver)).To(BeTrue())
+		reg.EnableTestDriver()
+		be, _, err := reg.For(rcNode("", DriverTest))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(be).To(BeAssignableToTypeOf(&FakeBackend{}))
+
+		reg.Register("flaky", func(kubernetes.Interface) (Backend, error) { return nil, errors.New("no config") })
+		_, _, err = reg.For(rcNode("", "flaky"))
+		Expect(err).To(MatchError(ContainSubstring(`driver "flaky": no config`)))
+
+		_, _, err = reg.For(rcNode("", DriverKwok))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("parses per-pool drivers", func() {
+		pools, err := parsePoolDrivers("sim=kwok, rack=redfish,")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pools).To(Equal(map[string]string{"sim": "kwok", "rack": "redfish"}))
+		_, err = parsePoolDrivers("sim")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,146 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// Keys read from the SSH credentials Secret.
+const (
+	sshKeyUsername   = "username"
+	sshKeyPrivateKey = "privateKey" // PEM, preferred
+	sshKeyPassword   = "password"
+	sshKeyKnownHosts = "knownHosts"            // known_hosts lines, required…
+	sshKeyInsecure   = "insecureIgnoreHostKey" // …unless this is "true"
+	sshKeyCommand    = "command"               // default DefaultPoweroffCommand
+
+	DefaultPoweroffCommand = "sudo systemctl poweroff"
+)
+
+// sshCreds is the parsed content of the credentials Secret.
+type sshCreds struct {
+	config  *ssh.ClientConfig
+	command string
+}
+
+func parseSSHSecret(sec *corev1.Secret) (*sshCreds, error) {
+	user := string(sec.Data[sshKeyUsername])
+	if user == "" {
+		return nil, fmt.Errorf("secret %s/%s: missing %q", sec.Namespace, sec.Name, sshKeyUsername)
+	}
+
+	var auth []ssh.AuthMethod
+	if pem := sec.Data[sshKeyPrivateKey]; len(pem) > 0 {
+		signer, err := ssh.ParsePrivateKey(pem)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s/%s: %s: %w", sec.Namespace, sec.Name, sshKeyPrivateKey, err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if pw := sec.Data[sshKeyPassword]; len(pw) > 0 {
+		auth = append(auth, ssh.Password(string(pw)))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("secret %s/%s: need %q or %q", sec.Namespace, sec.Name,
+			sshKeyPrivateKey, sshKeyPassword)
+	}
+
+	// without a verified host key anyone on the path could collect the
+	// credentials, or be told to power off in the machine's place
+	var hostKey ssh.HostKeyCallback
+	switch kh := sec.Data[sshKeyKnownHosts]; {
+	case len(kh) > 0:
+		cb, err := knownHostsCallback(kh)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s/%s: %s: %w", sec.Namespace, sec.Name, sshKeyKnownHosts, err)
+		}
+		hostKey = cb
+	case string(sec.Data[sshKeyInsecure]) == "true":
+		klog.Warningf("prod: secret %s/%s sets %s, SSH host keys are NOT verified",
+			sec.Namespace, sec.Name, sshKeyInsecure)
+		hostKey = ssh.InsecureIgnoreHostKey()
+	default:
+		return nil, fmt.Errorf("secret %s/%s: missing %q (or %s: \"true\" to skip host key checks)",
+			sec.Namespace, sec.Name, sshKeyKnownHosts, sshKeyInsecure)
+	}
+
+	cmd := strings.TrimSpace(string(sec.Data[sshKeyCommand]))
+	if cmd == "" {
+		cmd = DefaultPoweroffCommand
+	}
+	return &sshCreds{
+		config:  &ssh.ClientConfig{User: user, Auth: auth, HostKeyCallback: hostKey},
+		command: cmd,
+	}, nil
+}
+
+// knownHostsCallback accepts any host key listed in data (the host
+// patterns are ignored: the Secret is specific to this fleet).
+func knownHostsCallback(data []byte) (ssh.HostKeyCallback, error) {
+	var keys []ssh.PublicKey
+	for rest := data; len(rest) > 0; {
+		_, _, key, _, r, err := ssh.ParseKnownHosts(rest)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		rest = r
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no host keys")
+	}
+	return func(host string, _ net.Addr, key ssh.PublicKey) error {
+		for _, k := range keys {
+			if k.Type() == key.Type() && string(k.Marshal()) == string(key.Marshal()) {
+				return nil
+			}
+		}
+		return fmt.Errorf("host key of %s is not in %s", host, sshKeyKnownHosts)
+	}, nil
+}
+
+// runSSH connects to addr and runs creds.command. A connection dropped by
+// the shutdown itself counts as success.
+func runSSH(ctx context.Context, addr string, creds *sshCreds) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if dl, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(dl)
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, creds.config)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	client := ssh.NewClient(c, chans, reqs)
+	defer client.Close()
+
+	sess, err := client.NewSession()
+	if err != nil {
+		return err
+	}
+	defer sess.Close()
+
+	out, err := sess.CombinedOutput(creds.command)
+	var missing *ssh.ExitMissingError
+	switch {
+	case err == nil, errors.As(err, &missing), errors.Is(err, io.EOF):
+		return nil
+	default:
+		return fmt.Errorf("%q: %w (%s)", creds.command, err, strings.TrimSpace(string(out)))
+	}
+}
@@ -1 +1,358 @@
 package backend
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	typedcore "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+)
+
+// FakeBackend is the in-memory driver ("test"): no machines, no KWOK. It
+// keeps the power state of every RcNode it is asked about, moves it along
+// a clock the test controls, records every Reconcile call and fails on
+// demand (see FakeFault).
+//
+// Power-on takes Spec.BootSeconds, power-off ShutdownDelay, both on the
+// backend's clock. Given a Kubernetes client it registers a Node named like
+// the RcNode once the machine is On, and removes it once it is Off, so the
+// RcNode lifecycle runs to ACTIVE_READY in an envtest cluster.
+type FakeBackend struct {
+	core typedcore.CoreV1Interface // nil: no Nodes
+
+	// ShutdownDelay is how long PoweringOff lasts (0: off at once).
+	ShutdownDelay time.Duration
+
+	mu       sync.Mutex
+	clock    time.Time     // frozen clock; zero = real time
+	skew     time.Duration // added to real time
+	machines map[types.NamespacedName]*testMachine
+	faults   map[types.NamespacedName]*FakeFault
+	hangs    map[types.NamespacedName]chan struct{} // closed by ClearFault
+	calls    []FakeCall
+	errs     lastErrors
+}
+
+// FakeCall is one recorded Reconcile.
+type FakeCall struct {
+	RcNode       types.NamespacedName
+	DesiredState string
+	Generation   int64
+	At           time.Time // on the backend's clock
+	Err          error     // what Reconcile returned
+}
+
+// FakeFault makes the next Reconcile calls of one RcNode misbehave.
+type FakeFault struct {
+	// Err is returned by Reconcile.
+	Err error
+	// Apply carries the power action out anyway (a partial failure: the
+	// machine reacts, the driver reports an error).
+	Apply bool
+	// Hang blocks Reconcile until its context is done or the fault is
+	// cleared.
+	Hang bool
+	// StuckBoot powers the machine on but its Node never becomes Ready.
+	StuckBoot bool
+	// ObserveErr is returned by Observe.
+	ObserveErr error
+	// Times is how many Reconcile calls the fault lasts (0: until cleared).
+	Times int
+}
+
+type testMachine struct {
+	power lifecycle.PowerState
+	until time.Time // end of PoweringOn / PoweringOff
+	stuck bool      // StuckBoot: On but never Ready
+}
+
+// NewFakeBackend returns a FakeBackend on real time; k8s may be nil.
+func NewFakeBackend(k8s kubernetes.Interface) *FakeBackend {
+	b := &FakeBackend{
+		machines: map[types.NamespacedName]*testMachine{},
+		faults:   map[types.NamespacedName]*FakeFault{},
+		hangs:    map[types.NamespacedName]chan struct{}{},
+	}
+	if k8s != nil {
+		b.core = k8s.CoreV1()
+	}
+	return b
+}
+
+// ----------------------------------------------------------------------------
+// Clock
+// ----------------------------------------------------------------------------
+
+// Now is the backend's clock.
+func (b *FakeBackend) Now() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.nowLocked()
+}
+
+// SetClock freezes the clock at t.
+func (b *FakeBackend) SetClock(t time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clock = t
+}
+
+// Advance moves the clock forward by d (frozen or not). Transitions that
+// fall due are seen on the next Reconcile or Observe.
+func (b *FakeBackend) Advance(d time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.clock.IsZero() {
+		b.skew += d
+	} else {
+		b.clock = b.clock.Add(d)
+	}
+}
+
+func (b *FakeBackend) nowLocked() time.Time {
+	if b.clock.IsZero() {
+		return time.Now().Add(b.skew)
+	}
+	return b.clock
+}
+
+// ----------------------------------------------------------------------------
+// Faults and inspection
+// ----------------------------------------------------------------------------
+
+// InjectFault replaces the fault of RcNode key.
+func (b *FakeBackend) InjectFault(key types.NamespacedName, f FakeFault) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clearLocked(key)
+	b.faults[key] = &f
+	b.hangs[key] = make(chan struct{})
+}
+
+// ClearFault removes the fault of RcNode key and releases its hung calls.
+func (b *FakeBackend) ClearFault(key types.NamespacedName) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clearLocked(key)
+}
+
+func (b *FakeBackend) clearLocked(key types.NamespacedName) {
+	delete(b.faults, key)
+	if h := b.hangs[key]; h != nil {
+		close(h)
+		delete(b.hangs, key)
+	}
+}
+
+// Calls returns the Reconcile calls so far, oldest first; a hung call is
+// listed with a nil Err until it returns.
+func (b *FakeBackend) Calls() []FakeCall {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]FakeCall(nil), b.calls...)
+}
+
+// CallsFor returns the Reconcile calls for RcNode key.
+func (b *FakeBackend) CallsFor(key types.NamespacedName) []FakeCall {
+	var out []FakeCall
+	for _, c := range b.Calls() {
+		if c.RcNode == key {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Power is the power state of RcNode key's machine (Off if never seen).
+func (b *FakeBackend) Power(key types.NamespacedName) lifecycle.PowerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.machineLocked(key).power
+}
+
+// SetPower forces the power state of RcNode key's machine, e.g. to
+// simulate a power loss. Its Node follows on the next Reconcile.
+func (b *FakeBackend) SetPower(key types.NamespacedName, p lifecycle.PowerState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m := b.machineLocked(key)
+	m.power, m.until = p, time.Time{}
+}
+
+// machineLocked returns the machine of key with due transitions applied.
+func (b *FakeBackend) machineLocked(key types.NamespacedName) *testMachine {
+	m := b.machines[key]
+	if m == nil {
+		m = &testMachine{power: lifecycle.PowerOff}
+		b.machines[key] = m
+	}
+	if !m.until.IsZero() && !b.nowLocked().Before(m.until) {
+		switch m.power {
+		case lifecycle.PowerPoweringOn:
+			m.power = lifecycle.PowerOn
+		case lifecycle.PowerPoweringOff:
+			m.power = lifecycle.PowerOff
+		}
+		m.until = time.Time{}
+	}
+	return m
+}
+
+// ----------------------------------------------------------------------------
+// Backend
+// ----------------------------------------------------------------------------
+
+func (b *FakeBackend) Reconcile(ctx context.Context, rc *rcv1.RcNode) error {
+	key := types.NamespacedName{Namespace: rc.Namespace, Name: rc.Name}
+
+	b.mu.Lock()
+	i := len(b.calls)
+	b.calls = append(b.calls, FakeCall{RcNode: key, DesiredState: rc.Spec.DesiredState,
+		Generation: rc.Generation, At: b.nowLocked()})
+	var fault FakeFault
+	if f := b.faults[key]; f != nil {
+		fault = *f
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				delete(b.faults, key) // hung calls still wait for ClearFault
+			}
+		}
+	}
+	release := b.hangs[key]
+	b.mu.Unlock()
+
+	err := b.reconcile(ctx, rc, key, fault, release)
+	b.mu.Lock()
+	b.calls[i].Err = err
+	b.mu.Unlock()
+	return b.errs.record(rc, err)
+}
+
+func (b *FakeBackend) reconcile(ctx context.Context, rc *rcv1.RcNode, key types.NamespacedName,
+	fault FakeFault, release chan struct{}) error {
+
+	if fault.Hang {
+		klog.Infof("test: Reconcile of RcNode %s hangs", key)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-release:
+		}
+	}
+	if fault.Err != nil && !fault.Apply {
+		return fault.Err
+	}
+
+	b.mu.Lock()
+	m := b.machineLocked(key)
+	now := b.nowLocked()
+	if rc.Spec.DesiredState == rcv1.DesiredStateRunning {
+		if m.power == lifecycle.PowerOff || m.power == lifecycle.PowerPoweringOff {
+			boot := time.Duration(rc.Spec.BootSeconds) * time.Second
+			klog.Infof("test: powering on RcNode %s (boot %s)", key, boot)
+			m.power, m.until, m.stuck = lifecycle.PowerPoweringOn, now.Add(boot), fault.StuckBoot
+			m = b.machineLocked(key) // On at once without BootSeconds
+		}
+	} else if m.power == lifecycle.PowerOn || m.power == lifecycle.PowerPoweringOn {
+		klog.Infof("test: powering off RcNode %s (shutdown %s)", key, b.ShutdownDelay)
+		m.power, m.until = lifecycle.PowerPoweringOff, now.Add(b.ShutdownDelay)
+		m = b.machineLocked(key)
+	}
+	power, stuck := m.power, m.stuck
+	b.mu.Unlock()
+
+	if err := b.syncNode(ctx, rc, power, stuck, now); err != nil {
+		return err
+	}
+	return fault.Err
+}
+
+// syncNode makes the Node match the machine: none while Off or booting,
+// Ready while On (NotReady if the boot is stuck), NotReady while powering
+// off. A Node of the same name it did not register is left alone.
+func (b *FakeBackend) syncNode(ctx context.Context, rc *rcv1.RcNode, power lifecycle.PowerState,
+	stuck bool, now time.Time) error {
+
+	if b.core == nil {
+		return nil
+	}
+	node, err := b.core.Nodes().Get(ctx, rc.Name, metav1.GetOptions{})
+	exists := err == nil
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	if exists && !ownsNode(rc, node) {
+		return errNotOurs(rc, node)
+	}
+
+	var ready corev1.NodeCondition
+	switch {
+	case power == lifecycle.PowerOff || power == lifecycle.PowerPoweringOn:
+		if !exists {
+			return nil
+		}
+		return deleteNode(ctx, b.core, rc.Name)
+	case power == lifecycle.PowerPoweringOff:
+		ready = readyCond(corev1.ConditionFalse, "TestShuttingDown", "test driver: powering off", now)
+	case stuck:
+		ready = readyCond(corev1.ConditionFalse, "TestBootStuck", "test driver: boot stuck", now)
+	default:
+		ready = readyCond(corev1.ConditionTrue, "TestReady", "test driver: node ready", now)
+	}
+
+	if !exists {
+		node, err = b.core.Nodes().Create(ctx, fakeNode(rc, rc.Name, ProviderID(rc)), metav1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+	}
+	if c := getReadyCond(node); c != nil && c.Status == ready.Status && c.Reason == ready.Reason {
+		return nil
+	}
+	after := node.DeepCopy()
+	after.Status.Conditions = mergeReady(after.Status.Conditions, ready)
+	return patchNode(ctx, b.core, node, after)
+}
+
+// ManagesNode: given a Kubernetes client the backend registers the Nodes.
+func (b *FakeBackend) ManagesNode(context.Context) (bool, error) { return b.core != nil, nil }
+
+// Observe reports the modelled machine; the draw is MinPowerConsumption
+// while powered.
+func (b *FakeBackend) Observe(_ context.Context, rc *rcv1.RcNode) (lifecycle.Observation, error) {
+	key := types.NamespacedName{Namespace: rc.Namespace, Name: rc.Name}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if f := b.faults[key]; f != nil && f.ObserveErr != nil {
+		return lifecycle.Observation{}, f.ObserveErr
+	}
+	m := b.machineLocked(key)
+	obs := lifecycle.Observation{Power: m.power, LastError: b.errs.get(rc)}
+	watts := 0
+	if m.power != lifecycle.PowerOff {
+		watts = rc.Spec.MinPowerConsumption
+	}
+	obs.PowerWatts = &watts
+	switch {
+	case m.power == lifecycle.PowerPoweringOn:
+		obs.Boot = lifecycle.BootPowerOn
+	case m.power == lifecycle.PowerOn && m.stuck:
+		obs.Boot = lifecycle.BootNodeRegistered
+	case m.power == lifecycle.PowerOn:
+		obs.Boot = lifecycle.BootReady
+	}
+	if !m.until.IsZero() {
+		if obs.Recheck = m.until.Sub(b.nowLocked()); obs.Recheck < time.Second {
+			obs.Recheck = time.Second
+		}
+	}
+	return obs, nil
+}
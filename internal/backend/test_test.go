@@ -0,0 +1,174 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+)
+
+var _ = Describe("fake backend", func() {
+	var (
+		ctx = context.Background()
+		k8s *fake.Clientset
+		be  *FakeBackend
+		rc  *rcv1.RcNode
+		key = types.NamespacedName{Namespace: "lab", Name: "n1"}
+	)
+
+	BeforeEach(func() {
+		k8s = fake.NewSimpleClientset()
+		be = NewFakeBackend(k8s)
+		be.SetClock(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+		be.ShutdownDelay = 5 * time.Second
+		rc = &rcv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "lab", Name: "n1", Generation: 3},
+			Spec: rcv1.RcNodeSpec{
+				CPU:                 rcv1.RcNodeCPUSpec{Cores: 2},
+				Memory:              1 << 30,
+				MinPowerConsumption: 20,
+				BootSeconds:         30,
+				DesiredState:        rcv1.DesiredStateRunning,
+			},
+		}
+	})
+
+	readyStatus := func() corev1.ConditionStatus {
+		n, err := k8s.CoreV1().Nodes().Get(ctx, "n1", metav1.GetOptions{})
+		if err != nil {
+			return ""
+		}
+		return getReadyCond(n).Status
+	}
+
+	It("leaves a Node of the same name it did not register alone", func() {
+		other := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"},
+			Spec: corev1.NodeSpec{ProviderID: "aws:///i-0abc"}}
+		_, err := k8s.CoreV1().Nodes().Create(ctx, other, metav1.CreateOptions{})
+		Expect(err).NotTo(HaveOccurred())
+
+		rc.Spec.BootSeconds = 0
+		Expect(be.Reconcile(ctx, rc)).To(MatchError(ContainSubstring(`node "n1" exists but is not the Node of RcNode "n1"`)))
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		Expect(be.Reconcile(ctx, rc)).To(MatchError(ContainSubstring("is not the Node")))
+
+		n, err := k8s.CoreV1().Nodes().Get(ctx, "n1", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(n.Spec.ProviderID).To(Equal("aws:///i-0abc"))
+		Expect(n.Status.Conditions).To(BeEmpty())
+	})
+
+	It("boots and shuts down on its clock, registering the Node while on", func() {
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(be.Power(key)).To(Equal(lifecycle.PowerPoweringOn))
+		obs, err := be.Observe(ctx, rc)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(obs.Boot).To(Equal(lifecycle.BootPowerOn))
+		Expect(obs.Recheck).To(Equal(30 * time.Second))
+		Expect(*obs.PowerWatts).To(Equal(20))
+		Expect(readyStatus()).To(BeEmpty())
+
+		be.Advance(30 * time.Second)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(be.Power(key)).To(Equal(lifecycle.PowerOn))
+		Expect(readyStatus()).To(Equal(corev1.ConditionTrue))
+		n, _ := k8s.CoreV1().Nodes().Get(ctx, "n1", metav1.GetOptions{})
+		Expect(n.Spec.ProviderID).To(Equal(ProviderID(rc)))
+
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(be.Power(key)).To(Equal(lifecycle.PowerPoweringOff))
+		Expect(readyStatus()).To(Equal(corev1.ConditionFalse))
+
+		be.Advance(5 * time.Second)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		obs, _ = be.Observe(ctx, rc)
+		Expect(obs.Power).To(Equal(lifecycle.PowerOff))
+		Expect(*obs.PowerWatts).To(BeZero())
+		Expect(readyStatus()).To(BeEmpty())
+	})
+
+	It("records every call", func() {
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		be.Advance(time.Second)
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		other := rc.DeepCopy()
+		other.Name = "n2"
+		Expect(be.Reconcile(ctx, other)).To(Succeed())
+
+		calls := be.CallsFor(key)
+		Expect(calls).To(HaveLen(2))
+		Expect(calls[0].DesiredState).To(Equal(rcv1.DesiredStateRunning))
+		Expect(calls[1].DesiredState).To(Equal(rcv1.DesiredStateStopped))
+		Expect(calls[1].At.Sub(calls[0].At)).To(Equal(time.Second))
+		Expect(calls[1].Generation).To(Equal(int64(3)))
+		Expect(be.Calls()).To(HaveLen(3))
+	})
+
+	It("fails a limited number of times, with or without acting", func() {
+		boom := errors.New("boom")
+		be.InjectFault(key, FakeFault{Err: boom, Times: 1})
+		Expect(be.Reconcile(ctx, rc)).To(MatchError(boom))
+		Expect(be.Power(key)).To(Equal(lifecycle.PowerOff))
+		obs, _ := be.Observe(ctx, rc)
+		Expect(obs.LastError).To(Equal("boom"))
+		Expect(be.CallsFor(key)[0].Err).To(MatchError(boom))
+
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(be.Power(key)).To(Equal(lifecycle.PowerPoweringOn))
+
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		be.InjectFault(key, FakeFault{Err: boom, Apply: true})
+		Expect(be.Reconcile(ctx, rc)).To(MatchError(boom))
+		Expect(be.Power(key)).To(Equal(lifecycle.PowerPoweringOff))
+	})
+
+	It("hangs until the fault is cleared or the context is done", func() {
+		be.InjectFault(key, FakeFault{Hang: true})
+		short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		Expect(be.Reconcile(short, rc)).To(MatchError(context.DeadlineExceeded))
+
+		done := make(chan error)
+		go func() { done <- be.Reconcile(ctx, rc) }()
+		Consistently(done, 100*time.Millisecond).ShouldNot(Receive())
+		be.ClearFault(key)
+		Eventually(done).Should(Receive(BeNil()))
+		Expect(be.Power(key)).To(Equal(lifecycle.PowerPoweringOn))
+	})
+
+	It("models stuck boots, failed observations and power loss", func() {
+		be.InjectFault(key, FakeFault{StuckBoot: true, ObserveErr: errors.New("bmc down")})
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		be.Advance(time.Minute)
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(readyStatus()).To(Equal(corev1.ConditionFalse))
+		_, err := be.Observe(ctx, rc)
+		Expect(err).To(MatchError("bmc down"))
+
+		be.ClearFault(key)
+		obs, _ := be.Observe(ctx, rc)
+		Expect(obs.Boot).To(Equal(lifecycle.BootNodeRegistered))
+
+		be.SetPower(key, lifecycle.PowerOff)
+		obs, _ = be.Observe(ctx, rc)
+		Expect(obs.Power).To(Equal(lifecycle.PowerOff))
+	})
+
+	It("works without a Kubernetes client", func() {
+		be = NewFakeBackend(nil)
+		rc.Spec.BootSeconds = 0
+		Expect(be.Reconcile(ctx, rc)).To(Succeed())
+		Expect(be.Power(key)).To(Equal(lifecycle.PowerOn))
+	})
+})
@@ -0,0 +1,52 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+// DefaultWoLAddr is the limited broadcast on the discard port, which every
+// NIC in the same L2 segment receives.
+const DefaultWoLAddr = "255.255.255.255:9"
+
+// wolMAC returns the MAC of the first interface that advertises magic-packet
+// wake-up (WoLFlagG).
+func wolMAC(rc *rcv1.RcNode) (net.HardwareAddr, error) {
+	for _, itf := range rc.Spec.Network {
+		for _, f := range itf.WoL {
+			if f != rcv1.WoLFlagG {
+				continue
+			}
+			mac, err := net.ParseMAC(itf.Address)
+			if err != nil {
+				return nil, fmt.Errorf("interface %s: %w", itf.Name, err)
+			}
+			return mac, nil
+		}
+	}
+	return nil, fmt.Errorf("no interface with Wake-on-LAN flag %q", rcv1.WoLFlagG)
+}
+
+// magicPacket is 6 × 0xFF followed by the MAC repeated 16 times.
+func magicPacket(mac net.HardwareAddr) []byte {
+	var b bytes.Buffer
+	b.Write(bytes.Repeat([]byte{0xff}, 6))
+	for i := 0; i < 16; i++ {
+		b.Write(mac)
+	}
+	return b.Bytes()
+}
+
+// sendWoL sends one magic packet for mac to addr ("host:port").
+func sendWoL(addr string, mac net.HardwareAddr) error {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write(magicPacket(mac))
+	return err
+}
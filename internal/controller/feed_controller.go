@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	reclusterv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/feed"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// FeedReconciler polls the spec.externalFeeds of every RcPolicy and stores
+// the latest readings in status.feeds, so scoring never touches the network.
+type FeedReconciler struct {
+	client.Client
+	fetcher *feed.Fetcher
+}
+
+// NewFeedReconciler reads k8s:// feeds and bearer-token Secrets from the
+// given namespaces only.
+func NewFeedReconciler(mgr ctrl.Manager, namespaces []string) *FeedReconciler {
+	// Feeds read single ConfigMap/Secret keys: go straight to the API server
+	// instead of caching every Secret of the cluster.
+	return &FeedReconciler{Client: mgr.GetClient(),
+		fetcher: &feed.Fetcher{Reader: mgr.GetAPIReader(), Namespaces: namespaces}}
+}
+
+func (r *FeedReconciler) Reconcile(ctx context.Context,
+	req ctrl.Request) (ctrl.Result, error) {
+
+	var pol reclusterv1.RcPolicy
+	if err := r.Get(ctx, req.NamespacedName, &pol); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	orig := pol.DeepCopy()
+	now := time.Now()
+	next, _ := r.fetcher.Refresh(ctx, &pol, now)
+	for _, st := range pol.Status.Feeds {
+		if st.LastError != "" {
+			log.FromContext(ctx).Info("external feed failed",
+				"policy", pol.Name, "feed", st.Name, "error", st.LastError)
+		}
+	}
+	meta.SetStatusCondition(&pol.Status.Conditions, feedsHealthy(&pol, now))
+
+	if !equality.Semantic.DeepEqual(orig.Status, pol.Status) {
+		// the RcPolicy controller writes the same status: never clobber it
+		patch := client.MergeFromWithOptions(orig, client.MergeFromWithOptimisticLock{})
+		if err := r.Status().Patch(ctx, &pol, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{RequeueAfter: next}, nil
+}
+
+// feedsHealthy is True when every feed holds a fresh value.
+func feedsHealthy(pol *reclusterv1.RcPolicy, now time.Time) metav1.Condition {
+	c := metav1.Condition{Type: reclusterv1.RcPolicyConditionFeedsHealthy,
+		Status: metav1.ConditionTrue, Reason: "FeedsFresh", ObservedGeneration: pol.Generation}
+	if len(pol.Spec.ExternalFeeds) == 0 {
+		c.Reason = "NoFeeds"
+		return c
+	}
+	var bad []string
+	for i := range pol.Spec.ExternalFeeds {
+		ref := &pol.Spec.ExternalFeeds[i]
+		st := pol.Status.Feed(ref.Name)
+		switch {
+		case st != nil && st.LastError != "":
+			c.Reason = "FeedError"
+			bad = append(bad, fmt.Sprintf("%s: %s", ref.Name, st.LastError))
+		case !ref.Fresh(st, now):
+			if c.Reason != "FeedError" {
+				c.Reason = "FeedStale"
+			}
+			bad = append(bad, fmt.Sprintf("%s: stale", ref.Name))
+		}
+	}
+	if len(bad) > 0 {
+		c.Status = metav1.ConditionFalse
+		c.Message = strings.Join(bad, "; ")
+	}
+	return c
+}
+
+// SetupWithManager – status updates (our own included) must not trigger a
+// fetch; timing is driven by RequeueAfter.
+func (r *FeedReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("rcpolicyfeeds").
+		For(&reclusterv1.RcPolicy{}).
+		WithEventFilter(predicate.GenerationChangedPredicate{}).
+		Complete(r)
+}
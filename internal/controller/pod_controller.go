@@ -2,24 +2,37 @@ package controller
 
 import (
 	"context"
-	"log"
-	"time"
+	"fmt"
+	"slices"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-)
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-const (
-	gateKey  = "recluster-sync/waiting-for-node"
-	taintKey = "recluster.io/rcnode" // same label & annotation
+	reclusterv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/backend"
+	"github.com/lcereser6/recluster-sync/internal/drain"
+	"github.com/lcereser6/recluster-sync/internal/graph"
 )
 
-func NewPodReconciler(mgr ctrl.Manager) *PodReconciler {
-	return &PodReconciler{Client: mgr.GetClient()}
+func NewPodReconciler(mgr ctrl.Manager, backends *backend.Registry) *PodReconciler {
+	return &PodReconciler{Client: mgr.GetClient(), backends: backends}
 }
 
-type PodReconciler struct{ client.Client }
+// PodReconciler releases gated pods (see the gate contract in package
+// graph): once the Node of the RcNode a pod is assigned to is Ready and
+// schedulable, it pins the pod to that Node and lifts the gate.
+type PodReconciler struct {
+	client.Client
+	// backends tells which drivers' Nodes may be found by name.
+	backends *backend.Registry
+}
 
 func (r *PodReconciler) Reconcile(ctx context.Context,
 	req ctrl.Request) (ctrl.Result, error) {
@@ -28,37 +41,114 @@ func (r *PodReconciler) Reconcile(ctx context.Context,
 	if err := r.Get(ctx, req.NamespacedName, &pod); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
-
-	// 1 — exit early if gate already removed
-	if !hasGate(&pod) {
+	if !graph.HasGate(&pod) || pod.DeletionTimestamp != nil {
 		return ctrl.Result{}, nil
 	}
-
-	target := pod.Annotations["recluster.io/rcnode"]
+	// the planner's annotation and the Node turning Ready both bring us back
+	target := pod.Annotations[graph.AnnAssignment]
 	if target == "" {
-		// planner hasn’t annotated yet – requeue soon
-		return ctrl.Result{RequeueAfter: 1 * time.Second}, nil
+		return ctrl.Result{}, nil
+	}
+	node, err := r.nodeFor(ctx, target)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if node == nil || !nodeReady(node) || node.Spec.Unschedulable {
+		return ctrl.Result{}, nil
+	}
+
+	// one update: a half-released pod would be scheduled anywhere
+	patch := client.MergeFromWithOptions(pod.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	release(&pod, target, node)
+	if err := r.Patch(ctx, &pod, patch); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	log.FromContext(ctx).Info("Released pod", "pod", req.NamespacedName,
+		"rcnode", target, "node", node.Name)
+	return ctrl.Result{}, nil
+}
 
-	// 2 — is underlying Node Ready?
-	var node corev1.Node
-	if err := r.Get(ctx, client.ObjectKey{Name: target}, &node); err != nil {
-		return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+// nodeFor returns the Node backing the RcNode named rcNode (nil if none).
+// The assignment names the RcNode without its namespace, as do the
+// toleration and the providerID, so a name used in several namespaces is
+// refused rather than guessed.
+func (r *PodReconciler) nodeFor(ctx context.Context, rcNode string) (*corev1.Node, error) {
+	var rcs reclusterv1.RcNodeList
+	if err := r.List(ctx, &rcs); err != nil {
+		return nil, err
+	}
+	var rc *reclusterv1.RcNode
+	var namespaces []string
+	for i := range rcs.Items {
+		if rcs.Items[i].Name == rcNode {
+			rc = &rcs.Items[i]
+			namespaces = append(namespaces, rc.Namespace)
+		}
 	}
-	if !nodeReady(&node) {
-		return ctrl.Result{RequeueAfter: 2 * time.Second}, nil
+	if rc == nil {
+		return nil, nil
 	}
+	if len(namespaces) > 1 {
+		slices.Sort(namespaces)
+		return nil, fmt.Errorf("RcNode %q exists in namespaces %v: RcNode names must be unique across namespaces",
+			rcNode, namespaces)
+	}
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return nil, err
+	}
+	return nodeOf(rc, nodes.Items, r.backends.ByHostname(ctx, rc)), nil
+}
 
-	// 3 — ensure taint / toleration, then remove gate
-	//ensureToleration(&pod, taintKey, target)
-	//ensureNodeTaint(&node, taintKey, target)
-	if err := r.Patch(ctx, &pod, client.MergeFrom(pod.DeepCopy())); err != nil {
-		return ctrl.Result{}, err
+// release pins pod to node (toleration for a taint the RcNode's Node may
+// carry, see graph.TolerationKey, and required
+// node affinity on the Node's hostname) and removes the scheduling gate.
+// While a pod is gated the API server accepts these changes: tolerations
+// may be added, and node affinity set or narrowed with matchExpressions.
+func release(pod *corev1.Pod, rcNode string, node *corev1.Node) {
+	tol := graph.NodeToleration(rcNode)
+	found := false
+	for _, t := range pod.Spec.Tolerations {
+		if t.MatchToleration(&tol) {
+			found = true
+		}
 	}
-	//clearGate(&pod)
-	//log that we are patching the pod
-	log.Printf("Pod %s/%s is ready for node %s, removing gate", pod.Namespace, pod.Name, target)
-	return ctrl.Result{}, r.Patch(ctx, &pod, client.MergeFrom(pod.DeepCopy()))
+	if !found {
+		pod.Spec.Tolerations = append(pod.Spec.Tolerations, tol)
+	}
+
+	hostname := node.Labels[corev1.LabelHostname]
+	if hostname == "" {
+		hostname = node.Name
+	}
+	pin := corev1.NodeSelectorRequirement{
+		Key: corev1.LabelHostname, Operator: corev1.NodeSelectorOpIn, Values: []string{hostname},
+	}
+	if pod.Spec.Affinity == nil {
+		pod.Spec.Affinity = &corev1.Affinity{}
+	}
+	if pod.Spec.Affinity.NodeAffinity == nil {
+		pod.Spec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	na := pod.Spec.Affinity.NodeAffinity
+	if na.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		na.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	terms := &na.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(*terms) == 0 {
+		*terms = []corev1.NodeSelectorTerm{{}}
+	}
+	for i := range *terms { // terms are ORed: narrow each of them
+		(*terms)[i].MatchExpressions = append((*terms)[i].MatchExpressions, pin)
+	}
+
+	gates := pod.Spec.SchedulingGates[:0:0]
+	for _, g := range pod.Spec.SchedulingGates {
+		if g.Name != graph.GateKey {
+			gates = append(gates, g)
+		}
+	}
+	pod.Spec.SchedulingGates = gates
 }
 
 func nodeReady(node *corev1.Node) bool {
@@ -70,17 +160,43 @@ func nodeReady(node *corev1.Node) bool {
 	return false
 }
 
-func hasGate(pod *corev1.Pod) bool {
-	for _, g := range pod.Spec.SchedulingGates {
-		if g.Name == gateKey {
-			return true
+// podsForNode maps a Node event onto the gated pods assigned to the
+// RcNode(s) behind it, so that they are released as soon as it is Ready.
+func (r *PodReconciler) podsForNode(ctx context.Context, obj client.Object) []reconcile.Request {
+	node, ok := obj.(*corev1.Node)
+	if !ok || !nodeReady(node) || node.Spec.Unschedulable {
+		return nil
+	}
+	var rcs reclusterv1.RcNodeList
+	if err := r.List(ctx, &rcs); err != nil {
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i := range rcs.Items {
+		if !backend.MatchesNode(&rcs.Items[i], node, r.backends.ByHostname(ctx, &rcs.Items[i])) {
+			continue
+		}
+		var pods corev1.PodList
+		if err := r.List(ctx, &pods, client.MatchingFields{drain.AssignmentField: rcs.Items[i].Name}); err != nil {
+			return nil
+		}
+		for j := range pods.Items {
+			if graph.HasGate(&pods.Items[j]) {
+				reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{
+					Namespace: pods.Items[j].Namespace, Name: pods.Items[j].Name}})
+			}
 		}
 	}
-	return false
+	return reqs
 }
 
+// SetupWithManager needs the pod indexes of drain.IndexPods.
 func (r *PodReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	gated := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return graph.HasGate(o.(*corev1.Pod))
+	})
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Pod{}).
+		For(&corev1.Pod{}, builder.WithPredicates(gated)).
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.podsForNode)).
 		Complete(r)
 }
@@ -0,0 +1,181 @@
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	reclusterv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/backend"
+	"github.com/lcereser6/recluster-sync/internal/graph"
+)
+
+var _ = Describe("PodReconciler", func() {
+	var (
+		c       client.Client
+		r       *PodReconciler
+		patches int
+	)
+
+	rc := &reclusterv1.RcNode{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "lab", Name: "n1"},
+		Spec:       reclusterv1.RcNodeSpec{Driver: backend.DriverTest},
+	}
+	nodeOf := func(ready bool, mutate ...func(*corev1.Node)) *corev1.Node {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		n := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "n1-host", Labels: map[string]string{corev1.LabelHostname: "n1-host"}},
+			Spec:       corev1.NodeSpec{ProviderID: backend.ProviderID(rc)},
+			Status:     corev1.NodeStatus{Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}}},
+		}
+		for _, m := range mutate {
+			m(n)
+		}
+		return n
+	}
+	gated := func(name, rcNode string, mutate ...func(*corev1.Pod)) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: name},
+			Spec:       corev1.PodSpec{SchedulingGates: []corev1.PodSchedulingGate{{Name: graph.GateKey}}},
+		}
+		if rcNode != "" {
+			p.Annotations = map[string]string{graph.AnnAssignment: rcNode}
+		}
+		for _, m := range mutate {
+			m(p)
+		}
+		return p
+	}
+	setup := func(objs ...client.Object) {
+		patches = 0
+		c = newFakeClient(append(objs, rc.DeepCopy())...).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch,
+				opts ...client.PatchOption) error {
+				patches++
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).Build()
+		reg := backend.NewRegistry(nil, backend.DriverTest, nil)
+		reg.EnableTestDriver()
+		r = &PodReconciler{Client: c, backends: reg}
+	}
+	reconcile := func(pod *corev1.Pod) *corev1.Pod {
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+		Expect(err).NotTo(HaveOccurred())
+		out := &corev1.Pod{}
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(pod), out)).To(Succeed())
+		return out
+	}
+	pin := corev1.NodeSelectorRequirement{
+		Key: corev1.LabelHostname, Operator: corev1.NodeSelectorOpIn, Values: []string{"n1-host"},
+	}
+
+	It("pins the pod to the Ready Node and lifts the gate in one patch", func() {
+		zone := corev1.NodeSelectorRequirement{
+			Key: corev1.LabelTopologyZone, Operator: corev1.NodeSelectorOpIn, Values: []string{"a"},
+		}
+		arch := corev1.NodeSelectorRequirement{
+			Key: corev1.LabelArchStable, Operator: corev1.NodeSelectorOpIn, Values: []string{"arm64"},
+		}
+		pod := gated("web", "n1", func(p *corev1.Pod) {
+			p.Spec.SchedulingGates = append(p.Spec.SchedulingGates, corev1.PodSchedulingGate{Name: "other"})
+			p.Spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+			p.Spec.Affinity = &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{MatchExpressions: []corev1.NodeSelectorRequirement{zone}},
+						{MatchExpressions: []corev1.NodeSelectorRequirement{arch}},
+					}}}}
+		})
+		setup(pod, nodeOf(true))
+
+		out := reconcile(pod)
+		Expect(patches).To(Equal(1))
+		Expect(out.Spec.SchedulingGates).To(Equal([]corev1.PodSchedulingGate{{Name: "other"}}))
+		Expect(out.Spec.Tolerations).To(ConsistOf(
+			HaveField("Key", "dedicated"), Equal(graph.NodeToleration("n1"))))
+		Expect(out.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms).
+			To(Equal([]corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{zone, pin}},
+				{MatchExpressions: []corev1.NodeSelectorRequirement{arch, pin}},
+			}))
+	})
+
+	It("adds a node affinity term to a pod without one", func() {
+		pod := gated("web", "n1")
+		setup(pod, nodeOf(true))
+
+		out := reconcile(pod)
+		Expect(graph.HasGate(out)).To(BeFalse())
+		Expect(out.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms).
+			To(Equal([]corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{pin}}}))
+	})
+
+	DescribeTable("keeps the gate",
+		func(pod *corev1.Pod, objs ...client.Object) {
+			setup(append(objs, pod)...)
+			out := reconcile(pod)
+			Expect(patches).To(BeZero())
+			Expect(graph.HasGate(out)).To(BeTrue())
+		},
+		Entry("while the Node is NotReady", gated("web", "n1"), nodeOf(false)),
+		Entry("while the Node is cordoned", gated("web", "n1"),
+			nodeOf(true, func(n *corev1.Node) { n.Spec.Unschedulable = true })),
+		Entry("while the RcNode has no Node", gated("web", "n1")),
+		Entry("for a pod the planner has not placed", gated("web", ""), nodeOf(true)),
+	)
+
+	It("refuses to guess between RcNodes of the same name", func() {
+		pod := gated("web", "n1")
+		twin := rc.DeepCopy()
+		twin.Namespace = "other"
+		setup(pod, twin, nodeOf(true))
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+		Expect(err).To(MatchError(ContainSubstring(`RcNode "n1" exists in namespaces [lab other]`)))
+		Expect(patches).To(BeZero())
+	})
+
+	Describe("Node events", func() {
+		BeforeEach(func() {
+			setup(
+				gated("web", "n1"),
+				gated("db", "n1"),
+				gated("elsewhere", "n2"),
+				gated("started", "n1", func(p *corev1.Pod) { p.Spec.SchedulingGates = nil }),
+			)
+		})
+		names := func(reqs []ctrl.Request) []string {
+			var out []string
+			for _, req := range reqs {
+				out = append(out, req.Name)
+			}
+			return out
+		}
+
+		It("bring back the gated pods assigned to the Node's RcNode once it is Ready", func() {
+			Expect(names(r.podsForNode(ctx, nodeOf(true)))).To(ConsistOf("web", "db"))
+		})
+
+		It("are ignored while the Node cannot take pods", func() {
+			Expect(r.podsForNode(ctx, nodeOf(false))).To(BeEmpty())
+			Expect(r.podsForNode(ctx, nodeOf(true, func(n *corev1.Node) { n.Spec.Unschedulable = true }))).
+				To(BeEmpty())
+		})
+
+		It("ignore Nodes of other machines", func() {
+			Expect(r.podsForNode(ctx, nodeOf(true, func(n *corev1.Node) {
+				n.Spec.ProviderID = "aws:///i-0abc"
+			}))).To(BeEmpty())
+		})
+	})
+})
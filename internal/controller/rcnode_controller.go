@@ -2,23 +2,59 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	reclusterv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
 	"github.com/lcereser6/recluster-sync/internal/backend"
+	"github.com/lcereser6/recluster-sync/internal/drain"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// DefaultResync is how often an RcNode is observed when nothing happens.
+const DefaultResync = 30 * time.Second
+
 type RcNodeReconciler struct {
 	client.Client
-	be backend.Backend
+	backends *backend.Registry
+	drainer  *drain.Drainer
+	// Resync is the period of the driver observation (DefaultResync if 0).
+	Resync time.Duration
+	// DeletionTimeout bounds the cleanup of a deleted RcNode
+	// (DefaultDeletionTimeout if 0).
+	DeletionTimeout time.Duration
+	// DrainTimeout bounds the drain of a Node whose RcNode should stop
+	// (DefaultDrainTimeout if 0), unless pods without a controller are
+	// left on it.
+	DrainTimeout time.Duration
 }
 
-func NewRcNodeReconciler(mgr ctrl.Manager, be backend.Backend) *RcNodeReconciler {
-	return &RcNodeReconciler{Client: mgr.GetClient(), be: be}
+func NewRcNodeReconciler(mgr ctrl.Manager, backends *backend.Registry) *RcNodeReconciler {
+	return &RcNodeReconciler{
+		Client:   mgr.GetClient(),
+		backends: backends,
+		drainer:  &drain.Drainer{Client: mgr.GetClient()},
+	}
 }
 
+// Reconcile lets the RcNode's driver act on Spec.DesiredState, then
+// advances the status state machine (see package lifecycle) from the
+// observed Node and what the driver observes of the machine, and comes
+// back every Resync to observe again. An unknown or broken driver is
+// reported on the DriverResolved condition and not retried until the
+// RcNode changes. A Node that should stop is drained before the driver
+// powers its machine off (see drainBeforeStop); deleted RcNodes are torn
+// down first (see finalize).
 func (r *RcNodeReconciler) Reconcile(ctx context.Context,
 	req ctrl.Request) (ctrl.Result, error) {
 
@@ -29,15 +65,132 @@ func (r *RcNodeReconciler) Reconcile(ctx context.Context,
 		}
 		return ctrl.Result{}, err
 	}
-	if err := r.be.Reconcile(ctx, &rc); err != nil {
-		return ctrl.Result{}, err // retry on backend error
+	if rc.DeletionTimestamp != nil {
+		return r.finalize(ctx, &rc)
+	}
+	if err := r.ensureFinalizer(ctx, &rc); err != nil {
+		return ctrl.Result{}, err
+	}
+	if rc.Spec.DesiredState != reclusterv1.DesiredStateRunning {
+		wait, err := r.drainBeforeStop(ctx, &rc)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if wait {
+			return ctrl.Result{RequeueAfter: cleanupPoll}, nil
+		}
+	}
+	be, driver, drvErr := r.backends.For(&rc)
+	var beErr error
+	var obs *lifecycle.Observation
+	if drvErr == nil && be != nil {
+		beErr = be.Reconcile(ctx, &rc)
+		o, err := be.Observe(ctx, &rc)
+		switch {
+		case err == nil:
+			obs = &o
+		case beErr == nil:
+			beErr = fmt.Errorf("observe: %w", err)
+		}
+	}
+
+	node, err := r.observedNode(ctx, &rc)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if node != nil && rc.Spec.DesiredState == reclusterv1.DesiredStateRunning {
+		if err := r.drainer.Uncordon(ctx, node); err != nil {
+			return ctrl.Result{}, fmt.Errorf("uncordon node %s: %w", node.Name, err)
+		}
+	}
+	now := time.Now()
+	st, requeue := lifecycle.Step(&rc, node, obs, now)
+	lifecycle.SetBackendError(&st, rc.Generation, beErr, now)
+	lifecycle.SetDriver(&st, rc.Generation, driver, drvErr, now)
+	lifecycle.SetDrainBlocked(&st, rc.Generation, nil, now)
+	if !equality.Semantic.DeepEqual(st, rc.Status) {
+		if drvErr != nil && !meta.IsStatusConditionFalse(rc.Status.Conditions,
+			reclusterv1.RcNodeConditionDriverResolved) {
+			log.FromContext(ctx).Error(drvErr, "RcNode driver unavailable", "rcnode", rc.Name)
+		}
+		if st.State != rc.Status.State {
+			log.FromContext(ctx).Info("RcNode state transition", "rcnode", rc.Name,
+				"from", rc.Status.State, "to", st.State, "reason", st.Reason)
+		}
+		patch := client.MergeFrom(rc.DeepCopy())
+		rc.Status = st
+		if err := r.Status().Patch(ctx, &rc, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if beErr != nil {
+		return ctrl.Result{}, beErr // retry on backend error
+	}
+	resync := r.Resync
+	if resync <= 0 {
+		resync = DefaultResync
+	}
+	if obs != nil && obs.Recheck > 0 && (requeue <= 0 || obs.Recheck < requeue) {
+		requeue = obs.Recheck
+	}
+	if requeue <= 0 || requeue > resync {
+		requeue = resync
+	}
+	return ctrl.Result{RequeueAfter: requeue}, nil
+}
+
+// observedNode returns the Kubernetes Node backing rc (nil if none), see
+// nodeOf.
+func (r *RcNodeReconciler) observedNode(ctx context.Context, rc *reclusterv1.RcNode) (*corev1.Node, error) {
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return nil, err
+	}
+	return nodeOf(rc, nodes.Items, r.backends.ByHostname(ctx, rc)), nil
+}
+
+// nodeOf picks the Node backing rc among nodes: the one carrying our
+// providerID or, failing that and if byName, the one matched by name (see
+// backend.MatchesNode).
+func nodeOf(rc *reclusterv1.RcNode, nodes []corev1.Node, byName bool) *corev1.Node {
+	var named *corev1.Node
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Spec.ProviderID == backend.ProviderID(rc) {
+			return n
+		}
+		if backend.MatchesNode(rc, n, byName) {
+			named = n
+		}
+	}
+	return named
+}
+
+// rcNodesForNode maps a Node event back onto the RcNode(s) it belongs to.
+func (r *RcNodeReconciler) rcNodesForNode(ctx context.Context, obj client.Object) []reconcile.Request {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return nil
+	}
+	var list reclusterv1.RcNodeList
+	if err := r.List(ctx, &list); err != nil {
+		return nil
+	}
+	var reqs []reconcile.Request
+	for i := range list.Items {
+		rc := &list.Items[i]
+		if backend.MatchesNode(rc, node, r.backends.ByHostname(ctx, rc)) {
+			reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: rc.Namespace, Name: rc.Name}})
+		}
 	}
-	// backend did its job → update observed status if you like
-	return ctrl.Result{}, nil
+	return reqs
 }
 
+// SetupWithManager needs the pod indexes of drain.IndexPods.
 func (r *RcNodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&reclusterv1.RcNode{}).
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.rcNodesForNode)).
 		Complete(r)
 }
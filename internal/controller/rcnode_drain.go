@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	reclusterv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// DefaultDrainTimeout is how long a Node may take to drain before its
+// machine is powered off anyway.
+const DefaultDrainTimeout = 5 * time.Minute
+
+// drainBeforeStop drains the Node of an RcNode that should stop (see
+// package drain) and reports whether the driver must keep the machine on
+// for now. The drain shows as ACTIVE_DELETING/Draining; it ends once the
+// Node is empty or DrainTimeout has passed, whatever PodDisruptionBudgets
+// still hold back. Pods without a controller would be lost: they hold the
+// machine on past DrainTimeout, reported on the DrainBlocked condition.
+// Without an observed Node there is nothing to drain.
+func (r *RcNodeReconciler) drainBeforeStop(ctx context.Context, rc *reclusterv1.RcNode) (bool, error) {
+	now := time.Now()
+	pending, elapsed := lifecycle.DrainPending(rc, now)
+	if !pending {
+		return false, nil
+	}
+	node, err := r.observedNode(ctx, rc)
+	if err != nil || node == nil {
+		return err != nil, err
+	}
+	logger := log.FromContext(ctx).WithValues("rcnode", rc.Name)
+	timeout := r.DrainTimeout
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+
+	res, err := r.drainer.Drain(ctx, rc.Name, node)
+	if len(res.Requeued) > 0 {
+		logger.Info("Handed pods back to the planner", "pods", res.Requeued)
+	}
+	if err != nil {
+		return true, fmt.Errorf("drain: %w", err)
+	}
+	if res.Done() {
+		return false, nil
+	}
+	if elapsed > timeout && len(res.Unowned) == 0 {
+		logger.Info("Drain timed out, powering off anyway", "timeout", timeout, "message", rc.Status.Message)
+		return false, nil
+	}
+
+	st := lifecycle.Draining(rc, fmt.Sprintf("draining node %s: %s", node.Name, res), now)
+	lifecycle.SetDrainBlocked(&st, rc.Generation, res.Unowned, now)
+	if !equality.Semantic.DeepEqual(st, rc.Status) {
+		if rc.Status.State != st.State {
+			logger.Info("RcNode state transition", "from", rc.Status.State,
+				"to", st.State, "reason", st.Reason)
+		}
+		patch := client.MergeFrom(rc.DeepCopy())
+		rc.Status = st
+		if err := r.Status().Patch(ctx, rc, patch); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	reclusterv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/backend"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+	"k8s.io/apimachinery/pkg/api/equality"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// DefaultDeletionTimeout is how long the cleanup of a deleted RcNode
+	// may take before it is released anyway.
+	DefaultDeletionTimeout = 10 * time.Minute
+	// cleanupPoll is how often a stalled cleanup is looked at again.
+	cleanupPoll = 5 * time.Second
+)
+
+// ensureFinalizer adds RcNodeFinalizer to a live RcNode.
+func (r *RcNodeReconciler) ensureFinalizer(ctx context.Context, rc *reclusterv1.RcNode) error {
+	if controllerutil.ContainsFinalizer(rc, reclusterv1.RcNodeFinalizer) {
+		return nil
+	}
+	patch := client.MergeFromWithOptions(rc.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	controllerutil.AddFinalizer(rc, reclusterv1.RcNodeFinalizer)
+	return r.Patch(ctx, rc, patch)
+}
+
+// finalize tears a deleted RcNode down before releasing it: drain its
+// Node, power the machine off through its driver, delete the Node (or wait
+// for a driver managing its Nodes to remove it), drop the finalizer. Only
+// a Node carrying our providerID is deleted; one found by name alone is
+// left in place. Each step is retried until it is done; the
+// AnnotationForceDelete annotation, or DeletionTimeout passing, releases
+// the RcNode as it is.
+func (r *RcNodeReconciler) finalize(ctx context.Context, rc *reclusterv1.RcNode) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(rc, reclusterv1.RcNodeFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	logger := log.FromContext(ctx).WithValues("rcnode", rc.Name)
+	timeout := r.DeletionTimeout
+	if timeout <= 0 {
+		timeout = DefaultDeletionTimeout
+	}
+	switch {
+	case rc.Annotations[reclusterv1.AnnotationForceDelete] == "true":
+		logger.Info("RcNode force-deleted, leaving its Node and machine as they are")
+		return ctrl.Result{}, r.release(ctx, rc)
+	case time.Since(rc.DeletionTimestamp.Time) > timeout:
+		logger.Info("RcNode cleanup timed out, releasing it", "timeout", timeout)
+		return ctrl.Result{}, r.release(ctx, rc)
+	}
+
+	wait, cleanupErr := r.cleanup(ctx, rc)
+	if wait == "" && cleanupErr == nil {
+		logger.Info("RcNode cleaned up, releasing it")
+		return ctrl.Result{}, r.release(ctx, rc)
+	}
+	if cleanupErr != nil {
+		wait = cleanupErr.Error()
+	}
+	st := lifecycle.Deleting(rc, wait, time.Now())
+	if !equality.Semantic.DeepEqual(st, rc.Status) {
+		patch := client.MergeFrom(rc.DeepCopy())
+		rc.Status = st
+		if err := r.Status().Patch(ctx, rc, patch); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if cleanupErr != nil {
+		return ctrl.Result{}, cleanupErr
+	}
+	return ctrl.Result{RequeueAfter: cleanupPoll}, nil
+}
+
+// cleanup runs the next step of the teardown and says what it waits for
+// ("" once everything is done).
+func (r *RcNodeReconciler) cleanup(ctx context.Context, rc *reclusterv1.RcNode) (string, error) {
+	node, err := r.observedNode(ctx, rc)
+	if err != nil {
+		return "", err
+	}
+	if node != nil {
+		res, err := r.drainer.Drain(ctx, rc.Name, node)
+		if err != nil {
+			return "", fmt.Errorf("drain node %s: %w", node.Name, err)
+		}
+		if !res.Done() {
+			return fmt.Sprintf("draining node %s: %s", node.Name, res), nil
+		}
+	}
+
+	be, driver, err := r.backends.For(rc)
+	if err != nil {
+		return fmt.Sprintf("cannot power off: %v", err), nil
+	}
+	off := rc.DeepCopy()
+	off.Spec.DesiredState = reclusterv1.DesiredStateStopped
+	if err := be.Reconcile(ctx, off); err != nil {
+		return "", fmt.Errorf("power off via %s: %w", driver, err)
+	}
+	if obs, err := be.Observe(ctx, off); err == nil {
+		switch obs.Power {
+		case lifecycle.PowerOn, lifecycle.PowerPoweringOn, lifecycle.PowerPoweringOff:
+			return fmt.Sprintf("waiting for the machine to power off (%s)", obs.Power), nil
+		}
+	}
+
+	if node, err = r.observedNode(ctx, rc); err != nil {
+		return "", err
+	}
+	if node == nil {
+		return "", nil
+	}
+	managed, err := backend.ManagesNode(ctx, be)
+	if err != nil {
+		return "", fmt.Errorf("ask %s about its Nodes: %w", driver, err)
+	}
+	if managed {
+		return fmt.Sprintf("waiting for %s to remove node %s", driver, node.Name), nil
+	}
+	if node.Spec.ProviderID != backend.ProviderID(rc) {
+		log.FromContext(ctx).Info("Leaving node in place: its providerID is not ours",
+			"rcnode", rc.Name, "node", node.Name, "providerID", node.Spec.ProviderID)
+		return "", nil
+	}
+	if err := r.Delete(ctx, node); client.IgnoreNotFound(err) != nil {
+		return "", fmt.Errorf("delete node %s: %w", node.Name, err)
+	}
+	return "", nil
+}
+
+func (r *RcNodeReconciler) release(ctx context.Context, rc *reclusterv1.RcNode) error {
+	patch := client.MergeFromWithOptions(rc.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	controllerutil.RemoveFinalizer(rc, reclusterv1.RcNodeFinalizer)
+	return client.IgnoreNotFound(r.Patch(ctx, rc, patch))
+}
@@ -0,0 +1,282 @@
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	reclusterv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/backend"
+	"github.com/lcereser6/recluster-sync/internal/drain"
+	"github.com/lcereser6/recluster-sync/internal/lifecycle"
+)
+
+// managingBackend is the test driver claiming to register its Nodes, as
+// KWOK and plugins announcing CAPABILITY_MANAGES_NODE do.
+type managingBackend struct{ *backend.FakeBackend }
+
+func (managingBackend) ManagesNode(context.Context) (bool, error) { return true, nil }
+
+var _ = Describe("RcNode teardown", func() {
+	var (
+		c  client.Client
+		r  *RcNodeReconciler
+		fb *backend.FakeBackend
+	)
+
+	rcNode := func(name, driver string, mutate ...func(*reclusterv1.RcNode)) *reclusterv1.RcNode {
+		rc := &reclusterv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "lab", Name: name,
+				Finalizers: []string{reclusterv1.RcNodeFinalizer}},
+			Spec: reclusterv1.RcNodeSpec{Driver: driver, DesiredState: reclusterv1.DesiredStateRunning},
+		}
+		for _, m := range mutate {
+			m(rc)
+		}
+		return rc
+	}
+	deletedAt := func(t time.Time) func(*reclusterv1.RcNode) {
+		return func(rc *reclusterv1.RcNode) { rc.DeletionTimestamp = ptr.To(metav1.NewTime(t)) }
+	}
+	deleted := deletedAt(time.Now())
+	nodeOf := func(name, providerID string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.NodeSpec{ProviderID: providerID},
+		}
+	}
+	podOn := func(node string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web",
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet",
+					Name: "web", UID: "rs", Controller: ptr.To(true)}}},
+			Spec:   corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	// pdb refuses every eviction, as a PodDisruptionBudget at its limit does
+	pdb := interceptor.Funcs{
+		SubResourceCreate: func(context.Context, client.Client, string, client.Object, client.Object,
+			...client.SubResourceCreateOption) error {
+			return apierrors.NewTooManyRequests("disruption budget", 10)
+		},
+	}
+	setup := func(funcs interceptor.Funcs, objs ...client.Object) {
+		c = newFakeClient(objs...).WithInterceptorFuncs(funcs).Build()
+		fb = backend.NewFakeBackend(nil)
+		reg := backend.NewRegistry(nil, backend.DriverTest, nil)
+		reg.Register(backend.DriverTest, func(kubernetes.Interface) (backend.Backend, error) { return fb, nil })
+		reg.Register("managing", func(kubernetes.Interface) (backend.Backend, error) {
+			return managingBackend{backend.NewFakeBackend(nil)}, nil
+		})
+		r = &RcNodeReconciler{Client: c, backends: reg, drainer: &drain.Drainer{Client: c}}
+	}
+	reconcile := func(name string) ctrl.Result {
+		res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "lab", Name: name}})
+		Expect(err).NotTo(HaveOccurred())
+		return res
+	}
+	gone := func(obj client.Object) bool {
+		err := c.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		return client.IgnoreNotFound(err) == nil && err != nil
+	}
+	cordoned := func(node *corev1.Node) bool {
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(node), node)).To(Succeed())
+		return node.Spec.Unschedulable
+	}
+	calls := func(rc *reclusterv1.RcNode) []string {
+		var out []string
+		for _, call := range fb.CallsFor(client.ObjectKeyFromObject(rc)) {
+			out = append(out, call.DesiredState)
+		}
+		return out
+	}
+
+	Describe("finalizer", func() {
+		It("drains the Node, powers the machine off and deletes the Node", func() {
+			rc := rcNode("n1", backend.DriverTest, deleted)
+			node := nodeOf("n1-host", backend.ProviderID(rc))
+			setup(interceptor.Funcs{}, rc, node, podOn("n1-host"))
+
+			Expect(reconcile("n1").RequeueAfter).To(Equal(cleanupPoll)) // evicts web
+			Expect(gone(podOn("n1-host"))).To(BeTrue())
+			Expect(calls(rc)).To(BeEmpty())
+
+			reconcile("n1")
+			Expect(calls(rc)).To(Equal([]string{reclusterv1.DesiredStateStopped}))
+			Expect(gone(node)).To(BeTrue())
+			Expect(gone(rc)).To(BeTrue())
+		})
+
+		It("waits for the drain before powering off", func() {
+			rc := rcNode("n1", backend.DriverTest, deleted)
+			node := nodeOf("n1", backend.ProviderID(rc))
+			setup(pdb, rc, node, podOn("n1"))
+
+			Expect(reconcile("n1").RequeueAfter).To(Equal(cleanupPoll))
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(rc), rc)).To(Succeed())
+			Expect(rc.Status.State).To(Equal(reclusterv1.NodeStatusActiveDeleting))
+			Expect(rc.Status.Message).To(Equal(
+				"draining node n1: 1 pods left, 1 blocked by PodDisruptionBudgets"))
+			Expect(cordoned(node)).To(BeTrue())
+			Expect(calls(rc)).To(BeEmpty())
+		})
+
+		It("never touches a Node named like the RcNode carrying another providerID", func() {
+			rc := rcNode("n1", backend.DriverTest, deleted)
+			node := nodeOf("n1", "aws:///eu-west-1a/i-0abc")
+			setup(interceptor.Funcs{}, rc, node)
+
+			reconcile("n1")
+			Expect(gone(rc)).To(BeTrue())
+			Expect(cordoned(node)).To(BeFalse())
+		})
+
+		It("drains a Node found by its hostname but leaves it in place", func() {
+			rc := rcNode("n1", backend.DriverTest, deleted)
+			node := nodeOf("n1", "")
+			setup(interceptor.Funcs{}, rc, node)
+
+			reconcile("n1")
+			Expect(gone(rc)).To(BeTrue())
+			Expect(cordoned(node)).To(BeTrue())
+		})
+
+		It("finds the Nodes of a driver managing them by providerID only", func() {
+			rc := rcNode("n1", "managing", deleted)
+			node := nodeOf("n1", "")
+			setup(interceptor.Funcs{}, rc, node)
+
+			reconcile("n1")
+			Expect(gone(rc)).To(BeTrue())
+			Expect(cordoned(node)).To(BeFalse())
+		})
+
+		It("leaves the Node of a driver managing its Nodes to the driver", func() {
+			rc := rcNode("n1", "managing", deleted)
+			node := nodeOf("kwok-fake-n1", backend.ProviderID(rc))
+			setup(interceptor.Funcs{}, rc, node)
+
+			Expect(reconcile("n1").RequeueAfter).To(Equal(cleanupPoll))
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(rc), rc)).To(Succeed())
+			Expect(rc.Status.Message).To(Equal("waiting for managing to remove node kwok-fake-n1"))
+			Expect(gone(node)).To(BeFalse())
+
+			Expect(c.Delete(ctx, node)).To(Succeed()) // the driver's doing
+			reconcile("n1")
+			Expect(gone(rc)).To(BeTrue())
+		})
+
+		It("releases a force-deleted RcNode as it is", func() {
+			rc := rcNode("n1", backend.DriverTest, deleted, func(rc *reclusterv1.RcNode) {
+				rc.Annotations = map[string]string{reclusterv1.AnnotationForceDelete: "true"}
+			})
+			node := nodeOf("n1", backend.ProviderID(rc))
+			setup(pdb, rc, node, podOn("n1"))
+
+			reconcile("n1")
+			Expect(gone(rc)).To(BeTrue())
+			Expect(cordoned(node)).To(BeFalse())
+			Expect(calls(rc)).To(BeEmpty())
+		})
+
+		It("releases the RcNode once DeletionTimeout has passed", func() {
+			rc := rcNode("n1", backend.DriverTest, deletedAt(time.Now().Add(-time.Hour)))
+			node := nodeOf("n1", backend.ProviderID(rc))
+			setup(pdb, rc, node, podOn("n1"))
+
+			reconcile("n1")
+			Expect(gone(rc)).To(BeTrue())
+			Expect(gone(node)).To(BeFalse())
+			Expect(calls(rc)).To(BeEmpty())
+		})
+	})
+
+	Describe("drain before stop", func() {
+		draining := func(since time.Duration) func(*reclusterv1.RcNode) {
+			return func(rc *reclusterv1.RcNode) {
+				rc.Spec.DesiredState = reclusterv1.DesiredStateStopped
+				rc.Status.State = reclusterv1.NodeStatusActiveDeleting
+				rc.Status.Reason = lifecycle.ReasonDraining
+				rc.Status.LastTransition = ptr.To(metav1.NewTime(time.Now().Add(-since)))
+			}
+		}
+
+		It("keeps the machine on while the drain is blocked", func() {
+			rc := rcNode("n1", backend.DriverTest, draining(time.Minute))
+			node := nodeOf("n1", backend.ProviderID(rc))
+			setup(pdb, rc, node, podOn("n1"))
+
+			Expect(reconcile("n1").RequeueAfter).To(Equal(cleanupPoll))
+			Expect(cordoned(node)).To(BeTrue())
+			Expect(calls(rc)).To(BeEmpty())
+		})
+
+		It("powers off anyway once DrainTimeout has passed", func() {
+			rc := rcNode("n1", backend.DriverTest, draining(DefaultDrainTimeout+time.Minute))
+			node := nodeOf("n1", backend.ProviderID(rc))
+			setup(pdb, rc, node, podOn("n1"))
+
+			reconcile("n1")
+			Expect(calls(rc)).To(Equal([]string{reclusterv1.DesiredStateStopped}))
+			Expect(gone(podOn("n1"))).To(BeFalse())
+		})
+
+		It("keeps the machine on for pods without a controller, past DrainTimeout", func() {
+			rc := rcNode("n1", backend.DriverTest, draining(DefaultDrainTimeout+time.Minute))
+			node := nodeOf("n1", backend.ProviderID(rc))
+			bare := podOn("n1")
+			bare.OwnerReferences = nil
+			setup(interceptor.Funcs{}, rc, node, bare)
+
+			Expect(reconcile("n1").RequeueAfter).To(Equal(cleanupPoll))
+			Expect(gone(bare)).To(BeFalse())
+			Expect(calls(rc)).To(BeEmpty())
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(rc), rc)).To(Succeed())
+			Expect(rc.Status.Message).To(Equal("draining node n1: 1 pods left, 1 without a controller"))
+			cond := meta.FindStatusCondition(rc.Status.Conditions, reclusterv1.RcNodeConditionDrainBlocked)
+			Expect(cond).NotTo(BeNil())
+			Expect(cond.Status).To(Equal(metav1.ConditionTrue))
+			Expect(cond.Message).To(ContainSubstring("apps/web"))
+
+			Expect(c.Delete(ctx, bare)).To(Succeed())
+			reconcile("n1")
+			Expect(calls(rc)).To(Equal([]string{reclusterv1.DesiredStateStopped}))
+			Expect(c.Get(ctx, client.ObjectKeyFromObject(rc), rc)).To(Succeed())
+			Expect(meta.FindStatusCondition(rc.Status.Conditions, reclusterv1.RcNodeConditionDrainBlocked)).To(BeNil())
+		})
+
+		It("lists no pods for an RcNode without a Node", func() {
+			podLists := 0
+			countPodLists := interceptor.Funcs{
+				List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+					if _, ok := list.(*corev1.PodList); ok {
+						podLists++
+					}
+					return c.List(ctx, list, opts...)
+				},
+			}
+			rc := rcNode("n1", backend.DriverTest, func(rc *reclusterv1.RcNode) {
+				rc.Spec.DesiredState = reclusterv1.DesiredStateStopped
+				rc.Status.State = reclusterv1.NodeStatusInactive
+			})
+			setup(countPodLists, rc, nodeOf("other", ""))
+
+			reconcile("n1")
+			reconcile("n1")
+			Expect(podLists).To(BeZero())
+			Expect(calls(rc)).To(HaveLen(2))
+		})
+	})
+})
@@ -0,0 +1,179 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	reclusterv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/graph"
+	"github.com/lcereser6/recluster-sync/internal/policy"
+	"github.com/lcereser6/recluster-sync/internal/solver"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// RcPolicyReconciler compiles every RcPolicy generation once (warming the
+// solver's program cache), reports CEL compile errors on its status and
+// keeps the Valid / InUse conditions up to date.
+type RcPolicyReconciler struct {
+	client.Client
+}
+
+func NewRcPolicyReconciler(mgr ctrl.Manager) *RcPolicyReconciler {
+	return &RcPolicyReconciler{Client: mgr.GetClient()}
+}
+
+func (r *RcPolicyReconciler) Reconcile(ctx context.Context,
+	req ctrl.Request) (ctrl.Result, error) {
+
+	var pol reclusterv1.RcPolicy
+	if err := r.Get(ctx, req.NamespacedName, &pol); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	cp := solver.Compile(&pol)
+	if len(cp.Errors) > 0 {
+		log.FromContext(ctx).Info("RcPolicy has invalid expressions",
+			"policy", pol.Name, "errors", cp.Errors)
+	}
+	matched, err := r.matchedPods(ctx, &pol)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	orig := pol.DeepCopy()
+	st := &pol.Status
+	st.ObservedGeneration = pol.Generation
+	st.CompileErrors = cp.Errors
+	st.MatchedPods = matched
+
+	valid := metav1.Condition{Type: reclusterv1.RcPolicyConditionValid,
+		Status: metav1.ConditionTrue, Reason: "Compiled", ObservedGeneration: pol.Generation}
+	if len(cp.Errors) > 0 {
+		valid.Status, valid.Reason = metav1.ConditionFalse, "CompileError"
+		valid.Message = strings.Join(cp.Errors, "; ")
+	}
+	meta.SetStatusCondition(&st.Conditions, valid)
+
+	inUse := metav1.Condition{Type: reclusterv1.RcPolicyConditionInUse,
+		Status: metav1.ConditionFalse, Reason: "NoPods", ObservedGeneration: pol.Generation}
+	if matched > 0 {
+		inUse.Status, inUse.Reason = metav1.ConditionTrue, "PodsMatched"
+		inUse.Message = fmt.Sprintf("%d managed pod(s) resolve to this policy", matched)
+	}
+	meta.SetStatusCondition(&st.Conditions, inUse)
+
+	if equality.Semantic.DeepEqual(orig.Status, pol.Status) {
+		return ctrl.Result{}, nil
+	}
+	// the feed controller writes the same status: never clobber its update
+	patch := client.MergeFromWithOptions(orig, client.MergeFromWithOptimisticLock{})
+	return ctrl.Result{}, r.Status().Patch(ctx, &pol, patch)
+}
+
+// matchedPods counts the managed Pods that resolve to pol, using the same
+// precedence rules as the planner.
+func (r *RcPolicyReconciler) matchedPods(ctx context.Context, pol *reclusterv1.RcPolicy) (int32, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods); err != nil {
+		return 0, err
+	}
+	var pols reclusterv1.RcPolicyList
+	if err := r.List(ctx, &pols); err != nil {
+		return 0, err
+	}
+
+	var n int32
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !graph.Managed(pod) {
+			continue
+		}
+		got, _, err := policy.ResolveForPod(pod, pols.Items)
+		if err == nil && got != nil && got.Namespace == pol.Namespace && got.Name == pol.Name {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// allPolicies re-evaluates every policy: a policy appearing, going away or
+// changing its selector may take pods from the others.
+func (r *RcPolicyReconciler) allPolicies(ctx context.Context, _ client.Object) []reconcile.Request {
+	var pols reclusterv1.RcPolicyList
+	if err := r.List(ctx, &pols); err != nil {
+		return nil
+	}
+	reqs := make([]reconcile.Request, 0, len(pols.Items))
+	for _, p := range pols.Items {
+		reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: p.Namespace, Name: p.Name}})
+	}
+	return reqs
+}
+
+// podPolicies enqueues the policy a Pod resolves to – before and after an
+// update, as a Pod changing labels may move from one policy to another.
+// No other policy's MatchedPods can change.
+func (r *RcPolicyReconciler) podPolicies() handler.EventHandler {
+	enqueue := func(ctx context.Context, q workqueue.TypedRateLimitingInterface[reconcile.Request], objs ...client.Object) {
+		var pols reclusterv1.RcPolicyList
+		if err := r.List(ctx, &pols); err != nil {
+			log.FromContext(ctx).Error(err, "listing RcPolicies")
+			return
+		}
+		for _, o := range objs {
+			pod, ok := o.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+			got, _, err := policy.ResolveForPod(pod, pols.Items)
+			if err != nil || got == nil {
+				continue
+			}
+			q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: got.Namespace, Name: got.Name}})
+		}
+	}
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.ObjectOld, e.ObjectNew)
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+		GenericFunc: func(ctx context.Context, e event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueue(ctx, q, e.Object)
+		},
+	}
+}
+
+func (r *RcPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// only Pods the planner has touched can change MatchedPods
+	tracked := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		pod, ok := o.(*corev1.Pod)
+		return ok && (pod.Annotations[graph.AnnAssignment] != "" || graph.Managed(pod))
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&reclusterv1.RcPolicy{}).
+		Watches(&reclusterv1.RcPolicy{}, handler.EnqueueRequestsFromMapFunc(r.allPolicies),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.Pod{}, r.podPolicies(), builder.WithPredicates(tracked)).
+		Complete(r)
+}
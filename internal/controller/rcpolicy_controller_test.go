@@ -0,0 +1,92 @@
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	reclusterv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/graph"
+)
+
+var _ = Describe("RcPolicyReconciler", func() {
+	policy := func(name string, sel map[string]string) *reclusterv1.RcPolicy {
+		return &reclusterv1.RcPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Generation: 1},
+			Spec: reclusterv1.RcPolicySpec{
+				Selector: &metav1.LabelSelector{MatchLabels: sel},
+				Metrics:  []reclusterv1.PolicyMetric{{Key: "cpu", Weight: 1}},
+			},
+		}
+	}
+	pod := func(name, tier string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: name, Labels: map[string]string{"tier": tier}},
+			Spec:       corev1.PodSpec{SchedulingGates: []corev1.PodSchedulingGate{{Name: graph.GateKey}}},
+		}
+	}
+	queued := func(q workqueue.TypedRateLimitingInterface[reconcile.Request]) []string {
+		var out []string
+		for q.Len() > 0 {
+			req, _ := q.Get()
+			out = append(out, req.Name)
+			q.Done(req)
+		}
+		return out
+	}
+
+	var r *RcPolicyReconciler
+	BeforeEach(func() {
+		c := newFakeClient(
+			policy("web", map[string]string{"tier": "web"}),
+			policy("batch", map[string]string{"tier": "batch"}),
+			policy("bad", map[string]string{"tier": "none"}),
+			pod("job", "batch"),
+			pod("web", "web"),
+		).Build()
+		r = &RcPolicyReconciler{Client: c}
+		// an invalid policy: only seen through its status
+		var bad reclusterv1.RcPolicy
+		Expect(c.Get(ctx, client.ObjectKey{Name: "bad"}, &bad)).To(Succeed())
+		bad.Spec.HardConstraints = []reclusterv1.PolicyConstraint{{Expression: "spec.nope"}}
+		Expect(c.Update(ctx, &bad)).To(Succeed())
+	})
+
+	It("enqueues only the policies a Pod resolves to, before and after", func() {
+		q := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		defer q.ShutDown()
+		h := r.podPolicies()
+
+		h.Create(ctx, event.CreateEvent{Object: pod("job", "batch")}, q)
+		Expect(queued(q)).To(Equal([]string{"batch"}))
+
+		h.Update(ctx, event.UpdateEvent{ObjectOld: pod("web", "web"), ObjectNew: pod("web", "batch")}, q)
+		Expect(queued(q)).To(ConsistOf("web", "batch"))
+
+		h.Delete(ctx, event.DeleteEvent{Object: pod("job", "batch")}, q)
+		Expect(queued(q)).To(Equal([]string{"batch"}))
+	})
+
+	It("reports the pods a policy serves and its compile errors", func() {
+		for _, name := range []string{"batch", "bad"} {
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKey{Name: name}})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		var batch, bad reclusterv1.RcPolicy
+		Expect(r.Get(ctx, client.ObjectKey{Name: "batch"}, &batch)).To(Succeed())
+		Expect(batch.Status.MatchedPods).To(Equal(int32(1)))
+		Expect(meta.IsStatusConditionTrue(batch.Status.Conditions, reclusterv1.RcPolicyConditionValid)).To(BeTrue())
+		Expect(meta.IsStatusConditionTrue(batch.Status.Conditions, reclusterv1.RcPolicyConditionInUse)).To(BeTrue())
+
+		Expect(r.Get(ctx, client.ObjectKey{Name: "bad"}, &bad)).To(Succeed())
+		Expect(bad.Status.CompileErrors).To(HaveLen(1))
+		Expect(meta.IsStatusConditionFalse(bad.Status.Conditions, reclusterv1.RcPolicyConditionValid)).To(BeTrue())
+	})
+})
@@ -0,0 +1,228 @@
+// Package drain empties a machine before it is powered off. Pods the
+// planner assigned to the RcNode but that have not started yet go back to
+// the planner; the Node is cordoned and the pods running on it are evicted
+// through the Eviction API, so PodDisruptionBudgets are honoured. Evicted
+// pods that belong to a controller are recreated, gated by the webhook and
+// placed anew by the planner. Pods no controller owns would be lost: they
+// are not evicted and keep the Node from draining until someone moves or
+// deletes them.
+//
+// Drain is level-triggered like the controllers calling it: each call
+// cordons (again), evicts what is left and reports what still runs; the
+// caller requeues until nothing does.
+package drain
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/lcereser6/recluster-sync/internal/graph"
+)
+
+// Pod indexes; register them with IndexPods before using a cached client.
+const (
+	NodeNameField   = "spec.nodeName"
+	AssignmentField = "metadata.annotations." + graph.AnnAssignment
+)
+
+// AnnCordoned marks a Node cordoned by Drain, so that Uncordon leaves
+// Nodes cordoned by an administrator alone.
+const AnnCordoned = "recluster.io/cordoned"
+
+// mirrorPodAnnotation marks static pods, which the kubelet owns.
+const mirrorPodAnnotation = "kubernetes.io/config.mirror"
+
+// Result is what a Drain call left behind.
+type Result struct {
+	// Remaining are the pods still on the Node (terminating ones included).
+	Remaining []string
+	// Blocked are the pods whose eviction a PodDisruptionBudget refused.
+	Blocked []string
+	// Unowned are the pods left alone because no controller would
+	// recreate them.
+	Unowned []string
+	// Requeued are the pods handed back to the planner by this call.
+	Requeued []string
+}
+
+// Done reports whether the Node is empty.
+func (r Result) Done() bool { return len(r.Remaining) == 0 }
+
+func (r Result) String() string {
+	if r.Done() {
+		return "drained"
+	}
+	s := fmt.Sprintf("%d pods left", len(r.Remaining))
+	if len(r.Blocked) > 0 {
+		s += fmt.Sprintf(", %d blocked by PodDisruptionBudgets", len(r.Blocked))
+	}
+	if len(r.Unowned) > 0 {
+		s += fmt.Sprintf(", %d without a controller", len(r.Unowned))
+	}
+	return s
+}
+
+// Drainer drains Nodes.
+type Drainer struct {
+	client.Client
+}
+
+// IndexPods registers NodeNameField and AssignmentField with the manager's
+// cache.
+func IndexPods(ctx context.Context, idx client.FieldIndexer) error {
+	if err := idx.IndexField(ctx, &corev1.Pod{}, NodeNameField, podNodeName); err != nil {
+		return err
+	}
+	return idx.IndexField(ctx, &corev1.Pod{}, AssignmentField, podAssignment)
+}
+
+func podNodeName(o client.Object) []string {
+	if n := o.(*corev1.Pod).Spec.NodeName; n != "" {
+		return []string{n}
+	}
+	return nil
+}
+
+func podAssignment(o client.Object) []string {
+	if a := o.GetAnnotations()[graph.AnnAssignment]; a != "" {
+		return []string{a}
+	}
+	return nil
+}
+
+// Drain empties RcNode rcNode, whose Node is node (nil if none is
+// registered): still-gated pods assigned to it are handed back to the
+// planner, then node is cordoned and every pod on it that a controller
+// recreates asked to leave (see Result.Unowned for the others). DaemonSet
+// pods, static pods and finished pods are left alone: they do not hold the
+// node.
+func (d *Drainer) Drain(ctx context.Context, rcNode string, node *corev1.Node) (Result, error) {
+	var res Result
+	requeued, err := d.requeueAssigned(ctx, rcNode)
+	res.Requeued = requeued
+	if err != nil || node == nil {
+		return res, err
+	}
+	if err := d.cordon(ctx, node); err != nil {
+		return res, err
+	}
+	pods, err := d.podsOn(ctx, node.Name)
+	if err != nil {
+		return res, err
+	}
+	for i := range pods {
+		pod := &pods[i]
+		name := pod.Namespace + "/" + pod.Name
+		res.Remaining = append(res.Remaining, name)
+		if pod.DeletionTimestamp != nil {
+			continue // already leaving
+		}
+		if metav1.GetControllerOf(pod) == nil {
+			res.Unowned = append(res.Unowned, name)
+			continue
+		}
+		err := d.SubResource("eviction").Create(ctx, pod, &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Namespace: pod.Namespace, Name: pod.Name},
+		})
+		switch {
+		case err == nil, apierrors.IsNotFound(err):
+		case apierrors.IsTooManyRequests(err): // PDB says not now
+			res.Blocked = append(res.Blocked, name)
+		default:
+			return res, fmt.Errorf("evict %s: %w", name, err)
+		}
+	}
+	return res, nil
+}
+
+// Uncordon undoes the cordon of Drain, for a node that is wanted again.
+func (d *Drainer) Uncordon(ctx context.Context, node *corev1.Node) error {
+	if node.Annotations[AnnCordoned] != "true" {
+		return nil
+	}
+	patch := client.MergeFrom(node.DeepCopy())
+	node.Spec.Unschedulable = false
+	delete(node.Annotations, AnnCordoned)
+	return client.IgnoreNotFound(d.Patch(ctx, node, patch))
+}
+
+func (d *Drainer) cordon(ctx context.Context, node *corev1.Node) error {
+	if node.Spec.Unschedulable {
+		return nil
+	}
+	patch := client.MergeFrom(node.DeepCopy())
+	node.Spec.Unschedulable = true
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	node.Annotations[AnnCordoned] = "true"
+	return client.IgnoreNotFound(d.Patch(ctx, node, patch))
+}
+
+// requeueAssigned takes the assignment off pods the planner placed on
+// rcNode that are still waiting at the scheduling gate, so that the next
+// planner tick places them elsewhere. Their toleration for rcNode stays:
+// pod updates may only add tolerations, and without the assignment it pins
+// nothing.
+func (d *Drainer) requeueAssigned(ctx context.Context, rcNode string) ([]string, error) {
+	var list corev1.PodList
+	if err := d.List(ctx, &list, client.MatchingFields{AssignmentField: rcNode}); err != nil {
+		return nil, err
+	}
+	var out []string
+	for i := range list.Items {
+		pod := &list.Items[i]
+		if pod.Spec.NodeName != "" || pod.DeletionTimestamp != nil || !graph.HasGate(pod) {
+			continue
+		}
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			var cur corev1.Pod
+			if err := d.Get(ctx, client.ObjectKeyFromObject(pod), &cur); err != nil {
+				return err
+			}
+			if cur.Annotations[graph.AnnAssignment] != rcNode || !graph.HasGate(&cur) {
+				return nil
+			}
+			delete(cur.Annotations, graph.AnnAssignment)
+			return d.Update(ctx, &cur)
+		})
+		if client.IgnoreNotFound(err) != nil {
+			return out, fmt.Errorf("requeue %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+		out = append(out, pod.Namespace+"/"+pod.Name)
+	}
+	return out, nil
+}
+
+// podsOn lists the pods that must leave node.
+func (d *Drainer) podsOn(ctx context.Context, node string) ([]corev1.Pod, error) {
+	var list corev1.PodList
+	if err := d.List(ctx, &list, client.MatchingFields{NodeNameField: node}); err != nil {
+		return nil, err
+	}
+	var out []corev1.Pod
+	for _, p := range list.Items {
+		if p.Status.Phase == corev1.PodSucceeded || p.Status.Phase == corev1.PodFailed ||
+			p.Annotations[mirrorPodAnnotation] != "" || ownedByDaemonSet(&p) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func ownedByDaemonSet(p *corev1.Pod) bool {
+	for _, o := range p.OwnerReferences {
+		if o.Kind == "DaemonSet" && o.Controller != nil && *o.Controller {
+			return true
+		}
+	}
+	return false
+}
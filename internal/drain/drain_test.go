@@ -0,0 +1,182 @@
+package drain
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/lcereser6/recluster-sync/internal/graph"
+)
+
+var _ = Describe("Drainer", func() {
+	var ctx = context.Background()
+
+	// pod is owned by a ReplicaSet unless mutated otherwise
+	pod := func(name, node string, mutate ...func(*corev1.Pod)) *corev1.Pod {
+		p := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: name,
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet",
+					Name: name, UID: "rs", Controller: ptr.To(true)}}},
+			Spec:   corev1.PodSpec{NodeName: node},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		for _, m := range mutate {
+			m(p)
+		}
+		return p
+	}
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "n1"}}
+
+	newDrainer := func(funcs interceptor.Funcs, objs ...client.Object) *Drainer {
+		c := fake.NewClientBuilder().
+			WithObjects(append(objs, node.DeepCopy())...).
+			WithIndex(&corev1.Pod{}, NodeNameField, podNodeName).
+			WithIndex(&corev1.Pod{}, AssignmentField, podAssignment).
+			WithInterceptorFuncs(funcs).
+			Build()
+		return &Drainer{Client: c}
+	}
+
+	It("cordons the node and evicts the pods that hold it", func() {
+		d := newDrainer(interceptor.Funcs{},
+			pod("web", "n1"),
+			pod("other-node", "n2"),
+			pod("done", "n1", func(p *corev1.Pod) { p.Status.Phase = corev1.PodSucceeded }),
+			pod("static", "n1", func(p *corev1.Pod) {
+				p.Annotations = map[string]string{mirrorPodAnnotation: "x"}
+			}),
+			pod("agent", "n1", func(p *corev1.Pod) {
+				p.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "DaemonSet",
+					Name: "agent", UID: "u", Controller: ptr.To(true)}}
+			}),
+		)
+		n := node.DeepCopy()
+		Expect(d.Get(ctx, client.ObjectKeyFromObject(n), n)).To(Succeed())
+
+		res, err := d.Drain(ctx, "rc1", n)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Remaining).To(Equal([]string{"apps/web"}))
+		Expect(res.Blocked).To(BeEmpty())
+
+		Expect(d.Get(ctx, client.ObjectKeyFromObject(n), n)).To(Succeed())
+		Expect(n.Spec.Unschedulable).To(BeTrue())
+		Expect(n.Annotations).To(HaveKeyWithValue(AnnCordoned, "true"))
+		var pods corev1.PodList
+		Expect(d.List(ctx, &pods)).To(Succeed())
+		Expect(pods.Items).To(HaveLen(4)) // web evicted
+
+		res, err = d.Drain(ctx, "rc1", n)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Done()).To(BeTrue())
+		Expect(res.String()).To(Equal("drained"))
+	})
+
+	It("reports pods a PodDisruptionBudget keeps", func() {
+		d := newDrainer(interceptor.Funcs{
+			SubResourceCreate: func(ctx context.Context, c client.Client, sub string, obj client.Object,
+				subObj client.Object, opts ...client.SubResourceCreateOption) error {
+				if obj.GetName() == "db" {
+					return apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 10)
+				}
+				return c.SubResource(sub).Create(ctx, obj, subObj, opts...)
+			},
+		}, pod("db", "n1"), pod("web", "n1"))
+
+		res, err := d.Drain(ctx, "rc1", node.DeepCopy())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Blocked).To(Equal([]string{"apps/db"}))
+		Expect(res.String()).To(Equal("2 pods left, 1 blocked by PodDisruptionBudgets"))
+
+		res, _ = d.Drain(ctx, "rc1", node.DeepCopy())
+		Expect(res.Remaining).To(Equal([]string{"apps/db"}))
+	})
+
+	It("leaves pods no controller would recreate on the node", func() {
+		d := newDrainer(interceptor.Funcs{},
+			pod("web", "n1"),
+			pod("bare", "n1", func(p *corev1.Pod) { p.OwnerReferences = nil }),
+			pod("adopted", "n1", func(p *corev1.Pod) { p.OwnerReferences[0].Controller = nil }),
+		)
+
+		res, err := d.Drain(ctx, "rc1", node.DeepCopy())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Unowned).To(Equal([]string{"apps/adopted", "apps/bare"}))
+		Expect(res.String()).To(Equal("3 pods left, 2 without a controller"))
+
+		res, _ = d.Drain(ctx, "rc1", node.DeepCopy())
+		Expect(res.Remaining).To(Equal([]string{"apps/adopted", "apps/bare"}))
+	})
+
+	It("hands gated pods assigned to the node back to the planner", func() {
+		assigned := func(rc string) func(*corev1.Pod) {
+			return func(p *corev1.Pod) {
+				p.Annotations = map[string]string{graph.AnnAssignment: rc}
+				p.Spec.Tolerations = []corev1.Toleration{
+					{Key: graph.TolerationKey, Operator: corev1.TolerationOpEqual, Value: rc},
+					{Key: "dedicated", Operator: corev1.TolerationOpExists},
+				}
+				p.Spec.SchedulingGates = []corev1.PodSchedulingGate{{Name: graph.GateKey}}
+			}
+		}
+		// as the API server does: tolerations may be added, never removed
+		d := newDrainer(interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				var old corev1.Pod
+				if err := c.Get(ctx, client.ObjectKeyFromObject(obj), &old); err != nil {
+					return err
+				}
+				if len(obj.(*corev1.Pod).Spec.Tolerations) < len(old.Spec.Tolerations) {
+					return apierrors.NewForbidden(corev1.Resource("pods"), obj.GetName(),
+						errors.New("pod updates may not change fields other than spec.tolerations (only additions to existing tolerations)"))
+				}
+				return c.Update(ctx, obj, opts...)
+			},
+		},
+			pod("waiting", "", assigned("rc1")),
+			pod("elsewhere", "", assigned("rc2")),
+		)
+
+		res, err := d.Drain(ctx, "rc1", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Requeued).To(Equal([]string{"apps/waiting"}))
+		Expect(res.Done()).To(BeTrue())
+
+		var p corev1.Pod
+		Expect(d.Get(ctx, client.ObjectKey{Namespace: "apps", Name: "waiting"}, &p)).To(Succeed())
+		Expect(p.Annotations).NotTo(HaveKey(graph.AnnAssignment))
+		Expect(p.Spec.Tolerations).To(HaveLen(2))
+		Expect(p.Spec.SchedulingGates).To(HaveLen(1))
+		Expect(d.Get(ctx, client.ObjectKey{Namespace: "apps", Name: "elsewhere"}, &p)).To(Succeed())
+		Expect(p.Annotations).To(HaveKeyWithValue(graph.AnnAssignment, "rc2"))
+	})
+
+	It("only uncordons nodes it cordoned", func() {
+		mine := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "mine", Annotations: map[string]string{AnnCordoned: "true"}},
+			Spec:       corev1.NodeSpec{Unschedulable: true},
+		}
+		admins := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "admins"},
+			Spec:       corev1.NodeSpec{Unschedulable: true},
+		}
+		d := newDrainer(interceptor.Funcs{}, mine, admins)
+
+		for _, n := range []*corev1.Node{mine, admins} {
+			Expect(d.Get(ctx, client.ObjectKeyFromObject(n), n)).To(Succeed())
+			Expect(d.Uncordon(ctx, n)).To(Succeed())
+			Expect(d.Get(ctx, client.ObjectKeyFromObject(n), n)).To(Succeed())
+		}
+		Expect(mine.Spec.Unschedulable).To(BeFalse())
+		Expect(mine.Annotations).NotTo(HaveKey(AnnCordoned))
+		Expect(admins.Spec.Unschedulable).To(BeTrue())
+	})
+})
@@ -0,0 +1,258 @@
+// internal/executor/executor.go
+//
+// Executor turns the planner's in-memory graph.Action values into API calls.
+//
+//   - graph.NodeAction  → RcNode.Spec.DesiredState = Running | Stopped
+//     (the RcNode controller drains the Node before powering it off)
+//   - graph.PodPatch    → annotations + tolerations on the Pod, optionally
+//     removing our scheduling gate
+//
+// Every action is idempotent (already-converged objects are left alone),
+// retried on optimistic-lock conflicts and reported back as a Result.
+//
+// In dry-run mode nothing is written: the executor computes what it *would*
+// change, logs it and records a Kubernetes Event on the target object, so a
+// policy can be trialled on a production cluster. A change that stays pending
+// over many planner ticks is reported on its first tick only.
+
+package executor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	reclusterv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/graph"
+)
+
+// Outcome summarises what happened to a single action.
+type Outcome string
+
+const (
+	OutcomeApplied   Outcome = "Applied"   // object was changed
+	OutcomeUnchanged Outcome = "Unchanged" // object already converged
+	OutcomeDryRun    Outcome = "DryRun"    // would have changed, dry-run on
+	OutcomeSkipped   Outcome = "Skipped"   // action needs no API work (NOP)
+	OutcomeFailed    Outcome = "Failed"
+)
+
+// Result is reported for every action passed to Execute, in input order.
+type Result struct {
+	Action  graph.Action
+	Target  string // "<kind> [<namespace>/]<name>"
+	Outcome Outcome
+	Message string
+	Err     error
+
+	repeated bool // dry run: the previous Execute reported the same change
+}
+
+type Executor struct {
+	client.Client
+	recorder record.EventRecorder
+	log      logr.Logger
+
+	// DryRun – compute and record changes, but never write them.
+	DryRun bool
+
+	// pending[target] is the change the previous Execute reported in dry-run
+	// mode, so it is not recorded again on every tick.
+	pending map[string]string
+}
+
+func New(mgr ctrl.Manager, dryRun bool) *Executor {
+	return &Executor{
+		Client:   mgr.GetClient(),
+		recorder: mgr.GetEventRecorderFor("recluster-executor"),
+		log:      mgr.GetLogger().WithName("executor"),
+		DryRun:   dryRun,
+	}
+}
+
+// Apply has the graph.Sink signature: it executes the actions and logs a
+// one-line summary per action.
+func (e *Executor) Apply(ctx context.Context, acts []graph.Action) {
+	for _, r := range e.Execute(ctx, acts) {
+		if r.Err != nil {
+			e.log.Error(r.Err, "action failed", "target", r.Target)
+			continue
+		}
+		log := e.log
+		if r.repeated {
+			log = log.V(1)
+		}
+		log.Info("action", "target", r.Target, "outcome", r.Outcome, "msg", r.Message)
+	}
+}
+
+// Execute runs every action and returns one Result per action. A failing
+// action does not stop the remaining ones. It is meant for a single caller,
+// the planner's Sink.
+func (e *Executor) Execute(ctx context.Context, acts []graph.Action) []Result {
+	out := make([]Result, 0, len(acts))
+	for _, a := range acts {
+		var r Result
+		switch act := a.(type) {
+		case graph.NodeAction:
+			r = e.execNode(ctx, act)
+		case graph.PodPatch:
+			r = e.execPod(ctx, act)
+		default:
+			r = Result{Outcome: OutcomeFailed, Err: fmt.Errorf("unsupported action %T", a)}
+		}
+		r.Action = a
+		out = append(out, r)
+	}
+
+	pending := map[string]string{}
+	for _, r := range out {
+		if r.Outcome == OutcomeDryRun {
+			pending[r.Target] = r.Message
+		}
+	}
+	e.pending = pending
+	return out
+}
+
+// dryRun reports res as what would happen to obj, recording an Event only if
+// the previous Execute did not report the same change.
+func (e *Executor) dryRun(obj client.Object, res *Result, verb string) {
+	res.Outcome = OutcomeDryRun
+	if msg, ok := e.pending[res.Target]; ok && msg == res.Message {
+		res.repeated = true
+		return
+	}
+	e.recorder.Eventf(obj, corev1.EventTypeNormal, "DryRun", "would %s %s", verb, res.Message)
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                node actions                                */
+/* -------------------------------------------------------------------------- */
+
+func (e *Executor) execNode(ctx context.Context, act graph.NodeAction) Result {
+	key := client.ObjectKeyFromObject(&act.Node)
+	res := Result{Target: "RcNode " + key.Name}
+
+	var want string
+	switch act.Kind {
+	case graph.NodeStart:
+		want = reclusterv1.DesiredStateRunning
+	case graph.NodeStop:
+		want = reclusterv1.DesiredStateStopped
+	case graph.NodeNOP:
+		res.Outcome = OutcomeSkipped
+		return res
+	default:
+		res.Outcome, res.Err = OutcomeFailed, fmt.Errorf("unknown node action %q", act.Kind)
+		return res
+	}
+	res.Message = fmt.Sprintf("desiredState=%s (%s)", want, act.Reason)
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var rc reclusterv1.RcNode
+		if err := e.Get(ctx, key, &rc); err != nil {
+			return err
+		}
+		if rc.Spec.DesiredState == want {
+			res.Outcome = OutcomeUnchanged
+			return nil
+		}
+		if e.DryRun {
+			e.dryRun(&rc, &res, "set")
+			return nil
+		}
+		rc.Spec.DesiredState = want
+		if err := e.Update(ctx, &rc); err != nil {
+			return err
+		}
+		res.Outcome = OutcomeApplied
+		e.recorder.Eventf(&rc, corev1.EventTypeNormal, string(act.Kind), "set %s", res.Message)
+		return nil
+	})
+	if err != nil {
+		res.Outcome, res.Err = OutcomeFailed, err
+	}
+	return res
+}
+
+/* -------------------------------------------------------------------------- */
+/*                                 pod actions                                */
+/* -------------------------------------------------------------------------- */
+
+func (e *Executor) execPod(ctx context.Context, act graph.PodPatch) Result {
+	key := types.NamespacedName{Namespace: act.Pod.Namespace, Name: act.Pod.Name}
+	res := Result{Target: "Pod " + key.String()}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var pod corev1.Pod
+		if err := e.Get(ctx, key, &pod); err != nil {
+			return err
+		}
+		after := pod.DeepCopy()
+		applyPodPatch(after, act)
+		if equality.Semantic.DeepEqual(pod.ObjectMeta.Annotations, after.ObjectMeta.Annotations) &&
+			equality.Semantic.DeepEqual(pod.Spec, after.Spec) {
+			res.Outcome = OutcomeUnchanged
+			return nil
+		}
+		res.Message = fmt.Sprintf("annotations=%v tolerations=%d removeGate=%t",
+			act.Annotations, len(act.Tolerations), act.RemoveGate)
+		if e.DryRun {
+			e.dryRun(&pod, &res, "patch")
+			return nil
+		}
+		if err := e.Update(ctx, after); err != nil {
+			return err
+		}
+		res.Outcome = OutcomeApplied
+		return nil
+	})
+	if err != nil {
+		res.Outcome, res.Err = OutcomeFailed, err
+	}
+	return res
+}
+
+// applyPodPatch mutates pod in place; calling it twice is a no-op.
+func applyPodPatch(pod *corev1.Pod, act graph.PodPatch) {
+	if len(act.Annotations) > 0 && pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	for k, v := range act.Annotations {
+		pod.Annotations[k] = v
+	}
+
+	for _, t := range act.Tolerations {
+		if !hasToleration(pod.Spec.Tolerations, t) {
+			pod.Spec.Tolerations = append(pod.Spec.Tolerations, t)
+		}
+	}
+
+	if act.RemoveGate {
+		gates := pod.Spec.SchedulingGates[:0:0]
+		for _, g := range pod.Spec.SchedulingGates {
+			if g.Name != graph.GateKey {
+				gates = append(gates, g)
+			}
+		}
+		pod.Spec.SchedulingGates = gates
+	}
+}
+
+func hasToleration(list []corev1.Toleration, t corev1.Toleration) bool {
+	for i := range list {
+		if list[i].MatchToleration(&t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package executor
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	reclusterv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/graph"
+)
+
+var _ = Describe("Executor", func() {
+	var (
+		ctx      = context.Background()
+		recorder *record.FakeRecorder
+		updates  int
+	)
+
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(reclusterv1.AddToScheme(scheme)).To(Succeed())
+
+	rcnode := func(name, desired string) *reclusterv1.RcNode {
+		return &reclusterv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       reclusterv1.RcNodeSpec{DesiredState: desired},
+		}
+	}
+	gatedPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: name},
+			Spec: corev1.PodSpec{
+				Containers:      []corev1.Container{{Name: "app", Image: "app"}},
+				SchedulingGates: []corev1.PodSchedulingGate{{Name: graph.GateKey}, {Name: "other"}},
+			},
+		}
+	}
+	place := func(pod *corev1.Pod, node string) graph.PodPatch {
+		return graph.PodPatch{
+			Pod:         *pod,
+			Annotations: map[string]string{graph.AnnAssignment: node},
+			Tolerations: []corev1.Toleration{graph.NodeToleration(node)},
+			RemoveGate:  true,
+		}
+	}
+	start := func(n *reclusterv1.RcNode) graph.NodeAction {
+		return graph.NodeAction{Node: *n, Kind: graph.NodeStart, Reason: "pod waiting"}
+	}
+	newExecutor := func(dryRun bool, funcs interceptor.Funcs, objs ...client.Object) *Executor {
+		update := funcs.Update
+		funcs.Update = func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+			updates++
+			if update != nil {
+				return update(ctx, c, obj, opts...)
+			}
+			return c.Update(ctx, obj, opts...)
+		}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).
+			WithInterceptorFuncs(funcs).Build()
+		return &Executor{Client: c, recorder: recorder, log: logr.Discard(), DryRun: dryRun}
+	}
+
+	BeforeEach(func() {
+		recorder = record.NewFakeRecorder(10)
+		updates = 0
+	})
+
+	It("applies node and pod actions and reports one result per action", func() {
+		n, p := rcnode("a", reclusterv1.DesiredStateStopped), gatedPod("web")
+		e := newExecutor(false, interceptor.Funcs{}, n, p)
+
+		res := e.Execute(ctx, []graph.Action{start(n), place(p, "a")})
+		Expect(res).To(HaveLen(2))
+		Expect(res[0].Target).To(Equal("RcNode a"))
+		Expect(res[0].Outcome).To(Equal(OutcomeApplied))
+		Expect(res[0].Message).To(Equal("desiredState=Running (pod waiting)"))
+		Expect(res[0].Action).To(Equal(start(n)))
+		Expect(res[1].Target).To(Equal("Pod apps/web"))
+		Expect(res[1].Outcome).To(Equal(OutcomeApplied))
+		Expect(recorder.Events).To(Receive(Equal("Normal Start set desiredState=Running (pod waiting)")))
+
+		var got reclusterv1.RcNode
+		Expect(e.Get(ctx, client.ObjectKeyFromObject(n), &got)).To(Succeed())
+		Expect(got.Spec.DesiredState).To(Equal(reclusterv1.DesiredStateRunning))
+		var pod corev1.Pod
+		Expect(e.Get(ctx, client.ObjectKeyFromObject(p), &pod)).To(Succeed())
+		Expect(pod.Annotations).To(HaveKeyWithValue(graph.AnnAssignment, "a"))
+		Expect(pod.Spec.Tolerations).To(ConsistOf(graph.NodeToleration("a")))
+		Expect(pod.Spec.SchedulingGates).To(ConsistOf(corev1.PodSchedulingGate{Name: "other"}))
+	})
+
+	It("leaves converged objects alone", func() {
+		n, p := rcnode("a", reclusterv1.DesiredStateStopped), gatedPod("web")
+		e := newExecutor(false, interceptor.Funcs{}, n, p)
+		acts := []graph.Action{start(n), place(p, "a")}
+		e.Execute(ctx, acts)
+		updates = 0
+
+		for _, r := range e.Execute(ctx, acts) {
+			Expect(r.Outcome).To(Equal(OutcomeUnchanged), r.Target)
+			Expect(r.Err).NotTo(HaveOccurred())
+		}
+		Expect(updates).To(BeZero())
+	})
+
+	It("retries on conflicts", func() {
+		n := rcnode("a", reclusterv1.DesiredStateStopped)
+		conflicts := 2
+		e := newExecutor(false, interceptor.Funcs{
+			Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				if conflicts > 0 {
+					conflicts--
+					return apierrors.NewConflict(schema.GroupResource{Resource: "rcnodes"}, obj.GetName(), errors.New("stale"))
+				}
+				return c.Update(ctx, obj, opts...)
+			},
+		}, n)
+
+		res := e.Execute(ctx, []graph.Action{start(n)})
+		Expect(res[0].Outcome).To(Equal(OutcomeApplied))
+		Expect(updates).To(Equal(3))
+	})
+
+	It("only records events in dry-run mode", func() {
+		n, p := rcnode("a", reclusterv1.DesiredStateStopped), gatedPod("web")
+		e := newExecutor(true, interceptor.Funcs{}, n, p)
+
+		res := e.Execute(ctx, []graph.Action{start(n), place(p, "a")})
+		Expect(res[0].Outcome).To(Equal(OutcomeDryRun))
+		Expect(res[1].Outcome).To(Equal(OutcomeDryRun))
+		Expect(updates).To(BeZero())
+		Expect(recorder.Events).To(Receive(Equal("Normal DryRun would set desiredState=Running (pod waiting)")))
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal DryRun would patch annotations=")))
+
+		var got reclusterv1.RcNode
+		Expect(e.Get(ctx, client.ObjectKeyFromObject(n), &got)).To(Succeed())
+		Expect(got.Spec.DesiredState).To(Equal(reclusterv1.DesiredStateStopped))
+	})
+
+	It("records a change pending over several dry-run ticks once", func() {
+		n, p := rcnode("a", reclusterv1.DesiredStateStopped), gatedPod("web")
+		e := newExecutor(true, interceptor.Funcs{}, n, p)
+		acts := []graph.Action{start(n), place(p, "a")}
+
+		e.Execute(ctx, acts)
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal DryRun would set")))
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal DryRun would patch")))
+
+		res := e.Execute(ctx, acts)
+		Expect(res[0].Outcome).To(Equal(OutcomeDryRun))
+		Expect(res[1].Outcome).To(Equal(OutcomeDryRun))
+		Expect(recorder.Events).To(BeEmpty())
+
+		stop := graph.NodeAction{Node: *n, Kind: graph.NodeStop, Reason: "idle"}
+		n.Spec.DesiredState = reclusterv1.DesiredStateRunning
+		Expect(e.Update(ctx, n)).To(Succeed())
+		e.Execute(ctx, []graph.Action{stop, place(p, "a")})
+		Expect(recorder.Events).To(Receive(Equal("Normal DryRun would set desiredState=Stopped (idle)")))
+		Expect(recorder.Events).To(BeEmpty())
+
+		e.Execute(ctx, nil)
+		e.Execute(ctx, []graph.Action{place(p, "a")})
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal DryRun would patch annotations=")))
+	})
+
+	It("skips NOPs and reports failures without stopping", func() {
+		n, p := rcnode("a", reclusterv1.DesiredStateStopped), gatedPod("web")
+		e := newExecutor(false, interceptor.Funcs{}, n)
+
+		res := e.Execute(ctx, []graph.Action{
+			graph.NodeAction{Node: *n, Kind: graph.NodeNOP},
+			start(rcnode("gone", "")),
+			place(p, "a"),
+			graph.NodeAction{Node: *n, Kind: "Reboot"},
+			start(n),
+		})
+		Expect(res).To(HaveLen(5))
+		Expect(res[0].Outcome).To(Equal(OutcomeSkipped))
+		Expect(res[1].Outcome).To(Equal(OutcomeFailed))
+		Expect(apierrors.IsNotFound(res[1].Err)).To(BeTrue())
+		Expect(res[2].Outcome).To(Equal(OutcomeFailed))
+		Expect(apierrors.IsNotFound(res[2].Err)).To(BeTrue())
+		Expect(res[3].Outcome).To(Equal(OutcomeFailed))
+		Expect(res[3].Err).To(MatchError(`unknown node action "Reboot"`))
+		Expect(res[4].Outcome).To(Equal(OutcomeApplied))
+	})
+})
@@ -0,0 +1,105 @@
+// Package feed reads the ExternalFeedRefs of an RcPolicy (spot prices,
+// carbon intensity …) and keeps the latest value of each one in the
+// policy status, where the offline scorer picks it up.
+package feed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+// maxBody caps how much of a response is read; feeds are single numbers
+// or, at most, a Prometheus exposition page.
+const maxBody = 1 << 20
+
+// Fetcher reads one numeric value per ExternalFeedRef.
+type Fetcher struct {
+	// HTTP is the client used for http(s) feeds (http.DefaultClient if nil).
+	HTTP *http.Client
+	// Reader resolves k8s:// feeds and bearer-token Secrets. Leave it nil
+	// to only allow plain http(s) feeds.
+	Reader client.Reader
+	// Namespaces are the only ones Reader may read ConfigMaps and Secrets
+	// from: whoever can write an RcPolicy must not be able to read every
+	// Secret of the cluster through it. Empty allows none.
+	Namespaces []string
+}
+
+// Fetch reads ref once, bounded by its timeout. ns is the namespace of the
+// owning policy; bearer-token Secrets are looked up there. Values read from
+// a Secret never show up in the returned error.
+func (f *Fetcher) Fetch(ctx context.Context, ns string, ref *rcv1.ExternalFeedRef) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, ref.TimeoutOrDefault())
+	defer cancel()
+
+	u, err := url.Parse(ref.URL)
+	if err != nil {
+		return 0, fmt.Errorf("bad url %q: %w", ref.URL, err)
+	}
+
+	var body []byte
+	switch u.Scheme {
+	case "http", "https":
+		body, err = f.fetchHTTP(ctx, ns, ref)
+	case "k8s":
+		body, err = f.readObject(ctx, u)
+	default:
+		return 0, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return 0, err
+	}
+	v, err := parseValue(body, ref)
+	if err != nil && u.Scheme == "k8s" && u.Host == "secret" {
+		// parse errors may quote what they failed on: the error ends up
+		// in the policy status and the logs
+		return 0, fmt.Errorf("%s: value is not in the expected format", u.String())
+	}
+	return v, err
+}
+
+func (f *Fetcher) fetchHTTP(ctx context.Context, ns string, ref *rcv1.ExternalFeedRef) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ref.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Prometheus != nil {
+		req.Header.Set("Accept", "text/plain;version=0.0.4")
+	} else {
+		req.Header.Set("Accept", "application/json")
+	}
+	if sel := ref.BearerTokenSecretRef; sel != nil {
+		tok, err := f.secretKey(ctx, ns, sel.Name, sel.Key)
+		if err != nil {
+			return nil, fmt.Errorf("bearer token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(tok)))
+	}
+
+	hc := f.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("GET %s: %s", ref.URL, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", ref.URL, err)
+	}
+	return body, nil
+}
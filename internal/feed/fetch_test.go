@@ -0,0 +1,129 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+var _ = Describe("external feeds", func() {
+	var (
+		srv   *httptest.Server
+		body  atomic.Value // string served by the stand-in
+		code  atomic.Int32
+		calls atomic.Int32
+		f     *Fetcher
+	)
+
+	BeforeEach(func() {
+		body.Store(`{"data":{"price":42.5}}`)
+		code.Store(http.StatusOK)
+		calls.Store(0)
+		srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			if r.URL.Path == "/slow" {
+				time.Sleep(200 * time.Millisecond)
+			}
+			w.WriteHeader(int(code.Load()))
+			fmt.Fprint(w, body.Load().(string))
+		}))
+		DeferCleanup(srv.Close)
+		f = &Fetcher{HTTP: srv.Client()}
+	})
+
+	ref := func(path, jsonPath string) *rcv1.ExternalFeedRef {
+		return &rcv1.ExternalFeedRef{
+			Name:     "price",
+			URL:      srv.URL + path,
+			JSONPath: jsonPath,
+			Interval: &metav1.Duration{Duration: time.Minute},
+			Timeout:  &metav1.Duration{Duration: 50 * time.Millisecond},
+		}
+	}
+
+	Describe("Fetch", func() {
+		It("extracts the value with a JSONPath", func() {
+			Expect(f.Fetch(context.Background(), "default", ref("/", "$.data.price"))).To(Equal(42.5))
+		})
+
+		It("accepts a bare number when no JSONPath is set", func() {
+			body.Store(`17`)
+			Expect(f.Fetch(context.Background(), "default", ref("/", ""))).To(Equal(17.0))
+		})
+
+		It("reports HTTP errors, bad bodies, timeouts and unknown schemes", func() {
+			code.Store(http.StatusServiceUnavailable)
+			_, err := f.Fetch(context.Background(), "default", ref("/", "$.data.price"))
+			Expect(err).To(MatchError(ContainSubstring("503")))
+
+			code.Store(http.StatusOK)
+			body.Store(`<html>`)
+			_, err = f.Fetch(context.Background(), "default", ref("/", "$.data.price"))
+			Expect(err).To(MatchError(ContainSubstring("not JSON")))
+
+			_, err = f.Fetch(context.Background(), "default", ref("/slow", ""))
+			Expect(err).To(MatchError(context.DeadlineExceeded))
+
+			_, err = f.Fetch(context.Background(), "default", &rcv1.ExternalFeedRef{URL: "ftp://x"})
+			Expect(err).To(MatchError(ContainSubstring("unsupported")))
+		})
+	})
+
+	Describe("Refresh", func() {
+		var pol *rcv1.RcPolicy
+		t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+		BeforeEach(func() {
+			pol = &rcv1.RcPolicy{Spec: rcv1.RcPolicySpec{
+				ExternalFeeds: []rcv1.ExternalFeedRef{*ref("/", "$.data.price")},
+			}}
+			pol.Status.Feeds = []rcv1.FeedStatus{{Name: "removed"}}
+		})
+
+		It("stores the value and only refetches once the interval elapsed", func() {
+			next, changed := f.Refresh(context.Background(), pol, t0)
+			Expect(changed).To(BeTrue())
+			Expect(next).To(Equal(time.Minute))
+			Expect(pol.Status.Feeds).To(HaveLen(1))
+			st := pol.Status.Feed("price")
+			Expect(*st.Value).To(Equal(42.5))
+			Expect(st.LastUpdated.Time).To(Equal(t0))
+			Expect(pol.Status.LastFeedSync.Time).To(Equal(t0))
+
+			next, changed = f.Refresh(context.Background(), pol, t0.Add(20*time.Second))
+			Expect(changed).To(BeFalse())
+			Expect(next).To(Equal(40 * time.Second))
+			Expect(calls.Load()).To(Equal(int32(1)))
+
+			body.Store(`{"data":{"price":50}}`)
+			_, changed = f.Refresh(context.Background(), pol, t0.Add(time.Minute))
+			Expect(changed).To(BeTrue())
+			Expect(*pol.Status.Feed("price").Value).To(Equal(50.0))
+		})
+
+		It("keeps the last value and records the error when a fetch fails", func() {
+			f.Refresh(context.Background(), pol, t0)
+
+			code.Store(http.StatusInternalServerError)
+			f.Refresh(context.Background(), pol, t0.Add(time.Minute))
+			st := pol.Status.Feed("price")
+			Expect(st.LastError).To(ContainSubstring("500"))
+			Expect(*st.Value).To(Equal(42.5))
+			Expect(st.LastUpdated.Time).To(Equal(t0))
+			Expect(st.LastAttempt.Time).To(Equal(t0.Add(time.Minute)))
+
+			// default TTL is 3 intervals
+			Expect(pol.Spec.ExternalFeeds[0].Fresh(st, t0.Add(3*time.Minute))).To(BeTrue())
+			Expect(pol.Spec.ExternalFeeds[0].Fresh(st, t0.Add(4*time.Minute))).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,76 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Cluster-local feeds, for sites where another job pushes the value into
+// the cluster instead of exposing it over HTTP:
+//
+//	k8s://configmap/<namespace>/<name>/<key>
+//	k8s://secret/<namespace>/<name>/<key>
+//
+// Only objects in Fetcher.Namespaces are read.
+
+func (f *Fetcher) readObject(ctx context.Context, u *url.URL) ([]byte, error) {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("bad url %q: want k8s://%s/<namespace>/<name>/<key>", u.String(), u.Host)
+	}
+	ns, name, key := parts[0], parts[1], parts[2]
+
+	switch u.Host {
+	case "configmap":
+		if err := f.readable(ns); err != nil {
+			return nil, err
+		}
+		var cm corev1.ConfigMap
+		if err := f.Reader.Get(ctx, types.NamespacedName{Namespace: ns, Name: name}, &cm); err != nil {
+			return nil, err
+		}
+		if v, ok := cm.Data[key]; ok {
+			return []byte(v), nil
+		}
+		if v, ok := cm.BinaryData[key]; ok {
+			return v, nil
+		}
+		return nil, fmt.Errorf("configmap %s/%s has no key %q", ns, name, key)
+	case "secret":
+		return f.secretKey(ctx, ns, name, key)
+	default:
+		return nil, fmt.Errorf("bad url %q: unknown object kind %q (want configmap or secret)", u.String(), u.Host)
+	}
+}
+
+func (f *Fetcher) secretKey(ctx context.Context, ns, name, key string) ([]byte, error) {
+	if err := f.readable(ns); err != nil {
+		return nil, err
+	}
+	var sec corev1.Secret
+	if err := f.Reader.Get(ctx, types.NamespacedName{Namespace: ns, Name: name}, &sec); err != nil {
+		return nil, err
+	}
+	v, ok := sec.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s/%s has no key %q", ns, name, key)
+	}
+	return v, nil
+}
+
+// readable rejects namespaces the operator did not open to feeds.
+func (f *Fetcher) readable(ns string) error {
+	if f.Reader == nil {
+		return fmt.Errorf("k8s feeds are not enabled")
+	}
+	if !slices.Contains(f.Namespaces, ns) {
+		return fmt.Errorf("namespace %q is not open to feeds", ns)
+	}
+	return nil
+}
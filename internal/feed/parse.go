@@ -0,0 +1,109 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/solver"
+)
+
+// parseValue turns a fetched body into the feed value, following the
+// format the ref asks for (prometheus → jsonPath → plain number).
+func parseValue(body []byte, ref *rcv1.ExternalFeedRef) (float64, error) {
+	switch {
+	case ref.Prometheus != nil:
+		return parsePrometheus(body, ref.Prometheus)
+	case ref.JSONPath != "":
+		return parseJSON(body, ref.JSONPath)
+	default:
+		return parsePlain(body)
+	}
+}
+
+// parseJSON extracts the number at path.
+func parseJSON(body []byte, path string) (float64, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return 0, fmt.Errorf("response is not JSON: %w", err)
+	}
+	return solver.LookupNumber(path, doc)
+}
+
+// parsePlain accepts a bare JSON scalar ("42", "\"42\"") or plain text
+// ("42.5", "4Gi"), which is what ConfigMap keys usually hold.
+func parsePlain(body []byte) (float64, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		doc = strings.TrimSpace(string(body))
+	}
+	return solver.LookupNumber("$", doc)
+}
+
+// parsePrometheus picks the single sample of sel.Metric whose labels
+// include every sel.Labels pair.
+func parsePrometheus(body []byte, sel *rcv1.PrometheusSelector) (float64, error) {
+	var p expfmt.TextParser
+	families, err := p.TextToMetricFamilies(bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("response is not Prometheus text format: %w", err)
+	}
+	mf, ok := families[sel.Metric]
+	if !ok {
+		return 0, fmt.Errorf("metric %q not found", sel.Metric)
+	}
+
+	var vals []float64
+	for _, m := range mf.GetMetric() {
+		if !labelsMatch(m.GetLabel(), sel.Labels) {
+			continue
+		}
+		v, err := sampleValue(mf.GetType(), m)
+		if err != nil {
+			return 0, fmt.Errorf("metric %q: %w", sel.Metric, err)
+		}
+		vals = append(vals, v)
+	}
+	switch len(vals) {
+	case 0:
+		return 0, fmt.Errorf("metric %q: no sample matches labels %v", sel.Metric, sel.Labels)
+	case 1:
+		return vals[0], nil
+	default:
+		return 0, fmt.Errorf("metric %q: %d samples match labels %v, want 1", sel.Metric, len(vals), sel.Labels)
+	}
+}
+
+func labelsMatch(have []*dto.LabelPair, want map[string]string) bool {
+	for k, v := range want {
+		found := false
+		for _, lp := range have {
+			if lp.GetName() == k && lp.GetValue() == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func sampleValue(t dto.MetricType, m *dto.Metric) (float64, error) {
+	switch t {
+	case dto.MetricType_GAUGE:
+		return m.GetGauge().GetValue(), nil
+	case dto.MetricType_COUNTER:
+		return m.GetCounter().GetValue(), nil
+	case dto.MetricType_UNTYPED:
+		return m.GetUntyped().GetValue(), nil
+	default:
+		return 0, fmt.Errorf("unsupported metric type %s", t)
+	}
+}
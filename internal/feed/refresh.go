@@ -0,0 +1,59 @@
+package feed
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+// Refresh fetches every feed of pol that is due at now and rewrites
+// pol.Status.Feeds in place; entries of feeds removed from the spec are
+// dropped. A failed fetch keeps the previous value (it ages out through the
+// TTL) and records the error.
+//
+// It reports whether the status changed and how long until the next feed
+// is due (0 when the policy has no feeds).
+func (f *Fetcher) Refresh(ctx context.Context, pol *rcv1.RcPolicy, now time.Time) (next time.Duration, changed bool) {
+	ts := metav1.NewTime(now)
+	feeds := make([]rcv1.FeedStatus, 0, len(pol.Spec.ExternalFeeds))
+	changed = len(pol.Status.Feeds) != len(pol.Spec.ExternalFeeds)
+
+	for i := range pol.Spec.ExternalFeeds {
+		ref := &pol.Spec.ExternalFeeds[i]
+		st := rcv1.FeedStatus{Name: ref.Name}
+		if prev := pol.Status.Feed(ref.Name); prev != nil {
+			prev.DeepCopyInto(&st)
+		} else {
+			changed = true
+		}
+
+		interval := ref.IntervalOrDefault()
+		if st.LastAttempt == nil || !now.Before(st.LastAttempt.Add(interval)) {
+			v, err := f.Fetch(ctx, pol.Namespace, ref)
+			st.LastAttempt = &ts
+			if err != nil {
+				st.LastError = err.Error()
+			} else {
+				st.Value = &v
+				st.LastUpdated = &ts
+				st.LastError = ""
+				pol.Status.LastFeedSync = &ts
+			}
+			changed = true
+		}
+
+		if due := st.LastAttempt.Add(interval).Sub(now); next == 0 || due < next {
+			next = due
+		}
+		feeds = append(feeds, st)
+	}
+
+	pol.Status.Feeds = feeds
+	if len(feeds) == 0 {
+		pol.Status.Feeds = nil
+	}
+	return next, changed
+}
@@ -0,0 +1,139 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+const exposition = `# HELP grid_carbon_intensity gCO2eq/kWh
+# TYPE grid_carbon_intensity gauge
+grid_carbon_intensity{zone="IT",source="forecast"} 310
+grid_carbon_intensity{zone="IT",source="measured"} 295.5
+grid_carbon_intensity{zone="FR",source="measured"} 55
+# TYPE tariff_eur_kwh untyped
+tariff_eur_kwh 0.21
+`
+
+var _ = Describe("feed sources", func() {
+	Describe("Prometheus text format", func() {
+		parse := func(metric string, labels map[string]string) (float64, error) {
+			return parseValue([]byte(exposition), &rcv1.ExternalFeedRef{
+				Prometheus: &rcv1.PrometheusSelector{Metric: metric, Labels: labels},
+			})
+		}
+
+		It("selects the single sample matching all labels", func() {
+			Expect(parse("grid_carbon_intensity", map[string]string{"zone": "IT", "source": "measured"})).To(Equal(295.5))
+			Expect(parse("grid_carbon_intensity", map[string]string{"zone": "FR"})).To(Equal(55.0))
+			Expect(parse("tariff_eur_kwh", nil)).To(Equal(0.21))
+		})
+
+		It("rejects ambiguous, missing and unknown selections", func() {
+			_, err := parse("grid_carbon_intensity", map[string]string{"zone": "IT"})
+			Expect(err).To(MatchError(ContainSubstring("2 samples")))
+			_, err = parse("grid_carbon_intensity", map[string]string{"zone": "DE"})
+			Expect(err).To(MatchError(ContainSubstring("no sample")))
+			_, err = parse("nope", nil)
+			Expect(err).To(MatchError(ContainSubstring("not found")))
+		})
+	})
+
+	Describe("cluster-local objects", func() {
+		var f *Fetcher
+
+		BeforeEach(func() {
+			f = &Fetcher{Reader: fake.NewClientBuilder().WithObjects(
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "energy", Name: "grid"},
+					Data: map[string]string{
+						"carbon":  "312.5\n",
+						"metrics": exposition,
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "energy", Name: "tariff"},
+					Data:       map[string][]byte{"price": []byte(`{"eur":0.19}`)},
+				},
+			).Build(), Namespaces: []string{"energy", "default"}}
+		})
+
+		fetch := func(ref *rcv1.ExternalFeedRef) (float64, error) {
+			return f.Fetch(context.Background(), "default", ref)
+		}
+
+		It("reads ConfigMap and Secret keys in any format", func() {
+			Expect(fetch(&rcv1.ExternalFeedRef{URL: "k8s://configmap/energy/grid/carbon"})).To(Equal(312.5))
+			Expect(fetch(&rcv1.ExternalFeedRef{URL: "k8s://configmap/energy/grid/metrics",
+				Prometheus: &rcv1.PrometheusSelector{Metric: "tariff_eur_kwh"}})).To(Equal(0.21))
+			Expect(fetch(&rcv1.ExternalFeedRef{URL: "k8s://secret/energy/tariff/price",
+				JSONPath: "$.eur"})).To(Equal(0.19))
+		})
+
+		It("reports malformed URLs and missing keys", func() {
+			_, err := fetch(&rcv1.ExternalFeedRef{URL: "k8s://configmap/energy/grid"})
+			Expect(err).To(MatchError(ContainSubstring("want k8s://configmap/<namespace>/<name>/<key>")))
+			_, err = fetch(&rcv1.ExternalFeedRef{URL: "k8s://pod/energy/grid/carbon"})
+			Expect(err).To(MatchError(ContainSubstring("unknown object kind")))
+			_, err = fetch(&rcv1.ExternalFeedRef{URL: "k8s://configmap/energy/grid/missing"})
+			Expect(err).To(MatchError(ContainSubstring(`no key "missing"`)))
+			_, err = fetch(&rcv1.ExternalFeedRef{URL: "k8s://secret/energy/absent/price"})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("only reads from the namespaces open to feeds", func() {
+			f.Namespaces = []string{"default"}
+			_, err := fetch(&rcv1.ExternalFeedRef{URL: "k8s://configmap/energy/grid/carbon"})
+			Expect(err).To(MatchError(`namespace "energy" is not open to feeds`))
+			_, err = fetch(&rcv1.ExternalFeedRef{URL: "k8s://secret/energy/tariff/price"})
+			Expect(err).To(MatchError(`namespace "energy" is not open to feeds`))
+		})
+
+		It("never quotes a Secret in its errors", func() {
+			for _, ref := range []*rcv1.ExternalFeedRef{
+				{URL: "k8s://secret/energy/tariff/price", JSONPath: "$.usd"},
+				{URL: "k8s://secret/energy/tariff/price", Prometheus: &rcv1.PrometheusSelector{Metric: "eur"}},
+			} {
+				_, err := fetch(ref)
+				Expect(err).To(MatchError("k8s://secret/energy/tariff/price: value is not in the expected format"))
+			}
+			// nor does any other plain value
+			_, err := parsePlain([]byte("hunter2"))
+			Expect(err).To(MatchError("cannot parse a 7-character string as a number"))
+		})
+
+		It("authenticates http feeds with a bearer token from a Secret", func() {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				fmt.Fprint(w, exposition)
+			}))
+			DeferCleanup(srv.Close)
+			f.HTTP = srv.Client()
+			f.Reader = fake.NewClientBuilder().WithObjects(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "feed-auth"},
+				Data:       map[string][]byte{"token": []byte("s3cr3t\n")},
+			}).Build()
+
+			ref := &rcv1.ExternalFeedRef{URL: srv.URL,
+				Prometheus: &rcv1.PrometheusSelector{Metric: "tariff_eur_kwh"}}
+			_, err := fetch(ref)
+			Expect(err).To(MatchError(ContainSubstring("401")))
+
+			ref.BearerTokenSecretRef = &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "feed-auth"}, Key: "token"}
+			Expect(fetch(ref)).To(Equal(0.21))
+		})
+	})
+})
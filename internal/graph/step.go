@@ -1,98 +1,218 @@
 // graph/step.go
+//
+// RunStep is the pure, in-memory half of the planner: given a snapshot of
+// Pods, RcNodes and RcPolicies it returns the flat list of actions needed to
+// move the cluster one step closer to what the policies ask for. It never
+// talks to the API server – the Planner runnable feeds it and ships the
+// result to its sink.
 package graph
 
-// const gateKey = "recluster-sync/waiting-for-node"
-
-// func RunStep(now time.Time,
-// 	pods []*corev1.Pod,
-// 	rcnodes []*reclusterv1.RcNode,
-// 	policies []*reclusterv1.RcPolicy,
-// 	minIdleSeconds int) []Action {
-
-// 	// ---------------------------------------------------------------------
-// 	// 1. Which Pods still need placement?
-// 	// ---------------------------------------------------------------------
-// 	var pending []*corev1.Pod
-// 	for _, p := range pods {
-// 		if hasGate(p) && p.Annotations["recluster.io/rcnode"] == "" {
-// 			pending = append(pending, p)
-// 		}
-// 	}
-// 	klog.Infof("RunStep: pods=%d pending=%d nodes=%d policies=%d",
-// 		len(pods), len(pending), len(rcnodes), len(policies))
-
-// 	// ---------------------------------------------------------------------
-// 	// 2. Node bookkeeping: who is already running / booting / idle-long?
-// 	// ---------------------------------------------------------------------
-// 	nodeNeeded := map[string]bool{} // any Pod still needs this node
-// 	nodeBootETA := map[string]time.Time{}
-// 	for _, n := range rcnodes {
-// 		if n.Status.State == reclusterv1.NodeStatusBooting {
-// 			eta := n.Status.LastTransition.Time.
-// 				Add(time.Duration(n.Spec.BootSeconds) * time.Second)
-// 			nodeBootETA[n.Name] = eta
-// 		}
-// 	}
-
-// 	// ---------------------------------------------------------------------
-// 	// 3. Solve per-pod placement
-// 	// ---------------------------------------------------------------------
-// 	var acts []Action
-// 	for _, pod := range pending {
-// 		pol := resolvePolicy(pod, policies)
-// 		if pol == nil {
-// 			klog.Warningf("no policy matched pod %s/%s", pod.Namespace, pod.Name)
-// 			continue
-// 		}
-
-// 		best := solver.PickBest(rcnodes, pol)
-// 		if best == nil {
-// 			klog.Infof("no node fits pod %s/%s", pod.Namespace, pod.Name)
-// 			continue
-// 		}
-// 		nodeNeeded[best.Name] = true
-
-// 		acts = append(acts, PodPatch{
-// 			Pod: *pod,
-// 			Annotations: map[string]string{
-// 				"recluster.io/rcnode": best.Name,
-// 			},
-// 			RemoveGate: true,
-// 			Tolerations: []corev1.Toleration{ // runtime-generated toleration
-// 				{
-// 					Key:      "recluster.io/node",
-// 					Operator: corev1.TolerationOpEqual,
-// 					Value:    best.Name,
-// 					Effect:   corev1.TaintEffectNoSchedule,
-// 				},
-// 			},
-// 		})
-// 	}
-
-// 	// ---------------------------------------------------------------------
-// 	// 4. Decide node start / stop
-// 	// ---------------------------------------------------------------------
-// 	for _, n := range rcnodes {
-// 		switch {
-// 		case nodeNeeded[n.Name] && n.Status.State != reclusterv1.NodeStatusRunning:
-// 			acts = append(acts, NodeAction{
-// 				Node:    *n,
-// 				Kind:    NodeStart,
-// 				ReadyAt: now.Add(time.Duration(n.Spec.BootSeconds) * time.Second),
-// 				Reason:  "pod waiting",
-// 			})
-// 		case !nodeNeeded[n.Name] &&
-// 			n.Status.State == reclusterv1.NodeStatusRunning &&
-// 			now.Sub(n.Status.LastTransition.Time) >= time.Duration(minIdleSeconds)*time.Second:
-// 			acts = append(acts, NodeAction{
-// 				Node:    *n,
-// 				Kind:    NodeStop,
-// 				ReadyAt: now,
-// 				Reason:  "idle timeout",
-// 			})
-// 		default:
-// 			// keep alive or still booting
-// 		}
-// 	}
-// 	return dedupNodeActions(acts)
-// }
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	reclusterv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/policy"
+	"github.com/lcereser6/recluster-sync/internal/solver"
+)
+
+// The gate contract shared by the webhook, the planner and the Pod
+// controller: internal/webhook adds GateKey to every new pod it manages;
+// the planner picks an RcNode and records it in AnnAssignment (plus the
+// NodeToleration for it); once that RcNode's Node is Ready the Pod
+// controller pins the pod to the Node and removes GateKey in one update.
+const (
+	// GateKey is misspelled ("wating") on purpose: pods gated by earlier
+	// releases carry it, and the Pod controller must still release them.
+	GateKey = "recluster-sync/wating-for-recluster-scheduling"
+
+	AnnAssignment = "recluster.io/rcnode" // pod annotation: chosen RcNode
+
+	// TolerationKey is what assigned pods tolerate, valued with their
+	// RcNode's name. Nothing here taints Nodes with it: an admin who wants
+	// a Node kept for the planner's pods taints it by hand or at join time.
+	TolerationKey = "recluster.io/node"
+)
+
+// Managed reports whether the planner is placing p or has placed it on a
+// node that p still occupies.
+func Managed(p *corev1.Pod) bool {
+	if p.Annotations[AnnAssignment] != "" {
+		return p.DeletionTimestamp == nil && !podFinished(p)
+	}
+	return HasGate(p)
+}
+
+func RunStep(now time.Time,
+	pods []*corev1.Pod,
+	rcnodes []*reclusterv1.RcNode,
+	policies []*reclusterv1.RcPolicy,
+	minIdle time.Duration) []Action {
+
+	// ---------------------------------------------------------------------
+	// 1. Which Pods still need placement? Who is already using a node?
+	// ---------------------------------------------------------------------
+	var pending []*corev1.Pod
+	nodeNeeded := map[string]bool{} // any Pod is (or will be) on this node
+	used := solver.Usage{}          // what those pods request, per node
+	for _, p := range pods {
+		if target := p.Annotations[AnnAssignment]; target != "" {
+			if p.DeletionTimestamp == nil && !podFinished(p) {
+				nodeNeeded[target] = true
+				used.Add(target, p)
+			}
+			continue
+		}
+		if HasGate(p) {
+			pending = append(pending, p)
+		}
+	}
+	klog.V(2).Infof("RunStep: pods=%d pending=%d nodes=%d policies=%d",
+		len(pods), len(pending), len(rcnodes), len(policies))
+
+	// solver / resolver work on value slices
+	nodes := make([]reclusterv1.RcNode, 0, len(rcnodes))
+	for _, n := range rcnodes {
+		nodes = append(nodes, *n)
+	}
+	pols := make([]reclusterv1.RcPolicy, 0, len(policies))
+	for _, p := range policies {
+		pols = append(pols, *p)
+	}
+
+	// ---------------------------------------------------------------------
+	// 2. Solve per-pod placement
+	// ---------------------------------------------------------------------
+	var acts []Action
+	for _, pod := range pending {
+		pol, reason, err := policy.ResolveForPod(pod, pols)
+		if err != nil {
+			klog.Warningf("RunStep: pod %s/%s: %v (%s)", pod.Namespace, pod.Name, err, reason)
+			continue
+		}
+		if pol == nil { // skip annotation
+			continue
+		}
+
+		d, err := solver.Decide(pol, pod, nodes, used, now)
+		if err != nil {
+			klog.Warningf("RunStep: policy %s failed for pod %s/%s: %v",
+				pol.Name, pod.Namespace, pod.Name, err)
+			continue
+		}
+		best := d.Node
+		for i, c := range d.Candidates {
+			klog.V(2).Infof("RunStep: pod %s/%s candidate #%d: %s", pod.Namespace, pod.Name, i+1, c)
+		}
+		if best == nil {
+			klog.Infof("RunStep: no node fits pod %s/%s under %s (%d nodes rejected)",
+				pod.Namespace, pod.Name, pol.Name, len(d.Candidates))
+			continue
+		}
+		klog.Infof("RunStep: pod %s/%s -> %s (policy=%s score=%.3f schedule=%q weights=%v feeds=%v feedErrors=%v violated=%q)",
+			pod.Namespace, pod.Name, best.Name, pol.Name, d.Score,
+			d.Weights.Schedule, d.Weights.Effective, d.Weights.Multipliers, d.Weights.FeedErrors, d.Violated)
+		nodeNeeded[best.Name] = true
+		used.Add(best.Name, pod) // later pods of this step see it
+
+		acts = append(acts, PodPatch{
+			Pod:         *pod,
+			Annotations: map[string]string{AnnAssignment: best.Name},
+			Tolerations: []corev1.Toleration{NodeToleration(best.Name)},
+			RemoveGate:  false, // PodReconciler lifts the gate once the node is Ready
+		})
+	}
+
+	// ---------------------------------------------------------------------
+	// 3. Decide node start / stop
+	// ---------------------------------------------------------------------
+	for _, n := range rcnodes {
+		running := n.Spec.DesiredState == reclusterv1.DesiredStateRunning
+		switch {
+		case nodeNeeded[n.Name] && !running:
+			acts = append(acts, NodeAction{
+				Node:    *n,
+				Kind:    NodeStart,
+				ReadyAt: now.Add(time.Duration(n.Spec.BootSeconds) * time.Second),
+				Reason:  "pod waiting",
+			})
+		case !nodeNeeded[n.Name] && running && idleFor(n, now) >= minIdle:
+			acts = append(acts, NodeAction{
+				Node:    *n,
+				Kind:    NodeStop,
+				ReadyAt: now,
+				Reason:  "idle timeout",
+			})
+		default:
+			// keep alive or still booting
+		}
+	}
+	return dedupNodeActions(acts)
+}
+
+/* -------------------------------------------------------------------------- */
+/*                            helper functions                                */
+/* -------------------------------------------------------------------------- */
+
+// HasGate reports whether p still waits at our scheduling gate.
+func HasGate(p *corev1.Pod) bool {
+	for _, g := range p.Spec.SchedulingGates {
+		if g.Name == GateKey {
+			return true
+		}
+	}
+	return false
+}
+
+func podFinished(p *corev1.Pod) bool {
+	return p.Status.Phase == corev1.PodSucceeded || p.Status.Phase == corev1.PodFailed
+}
+
+// NodeToleration lets a pod land on a Node tainted with TolerationKey for
+// the RcNode nodeName.
+func NodeToleration(nodeName string) corev1.Toleration {
+	return corev1.Toleration{
+		Key:      TolerationKey,
+		Operator: corev1.TolerationOpEqual,
+		Value:    nodeName,
+		Effect:   corev1.TaintEffectNoSchedule,
+	}
+}
+
+// idleFor reports how long the node has been in its current state. Without a
+// recorded transition we cannot tell, so the node is never considered idle.
+func idleFor(n *reclusterv1.RcNode, now time.Time) time.Duration {
+	if n.Status.LastTransition == nil {
+		return 0
+	}
+	return now.Sub(n.Status.LastTransition.Time)
+}
+
+// dedupNodeActions removes duplicate NodeActions (keep first) while leaving
+// PodPatches untouched. Node actions are sorted by name so every tick logs
+// the same order for the same input.
+func dedupNodeActions(in []Action) []Action {
+	seen := make(map[string]struct{})
+	var pods, nodes []Action
+	for _, a := range in {
+		na, ok := a.(NodeAction)
+		if !ok {
+			pods = append(pods, a)
+			continue
+		}
+		key := fmt.Sprintf("%s:%s", na.Node.Name, na.Kind)
+		if _, done := seen[key]; done {
+			continue
+		}
+		seen[key] = struct{}{}
+		nodes = append(nodes, a)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].(NodeAction).Node.Name < nodes[j].(NodeAction).Node.Name
+	})
+	return append(nodes, pods...)
+}
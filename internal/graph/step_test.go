@@ -0,0 +1,199 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+	"github.com/lcereser6/recluster-sync/internal/state"
+)
+
+var _ = Describe("RunStep", func() {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	const minIdle = 10 * time.Minute
+
+	gated := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: name},
+			Spec:       corev1.PodSpec{SchedulingGates: []corev1.PodSchedulingGate{{Name: GateKey}}},
+		}
+	}
+	placed := func(name, node string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "apps", Name: name, Annotations: map[string]string{AnnAssignment: node},
+		}}
+	}
+	// node is an RcNode that reached its desired state idle ago (never,
+	// when idle is negative).
+	node := func(name, desired string, idle time.Duration) *rcv1.RcNode {
+		n := &rcv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       rcv1.RcNodeSpec{DesiredState: desired, BootSeconds: 30},
+		}
+		if idle >= 0 {
+			n.Status.LastTransition = &metav1.Time{Time: now.Add(-idle)}
+		}
+		return n
+	}
+	// the cluster default: fewest cores, so "a" wins over "b"
+	policies := []*rcv1.RcPolicy{{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec:       rcv1.RcPolicySpec{Metrics: []rcv1.PolicyMetric{{Key: "cores", Weight: 1, Selector: "$.spec.cpu.cores"}}},
+	}}
+
+	// describe flattens actions so tables can compare them.
+	describe := func(acts []Action) []string {
+		var out []string
+		for _, a := range acts {
+			switch act := a.(type) {
+			case NodeAction:
+				out = append(out, string(act.Kind)+" "+act.Node.Name)
+			case PodPatch:
+				out = append(out, "place "+act.Pod.Name+" on "+act.Annotations[AnnAssignment])
+			}
+		}
+		return out
+	}
+
+	DescribeTable("actions",
+		func(pods []*corev1.Pod, nodes []*rcv1.RcNode, want []string) {
+			Expect(describe(RunStep(now, pods, nodes, policies, minIdle))).To(Equal(want))
+		},
+		Entry("nothing to do",
+			nil, []*rcv1.RcNode{node("a", rcv1.DesiredStateStopped, time.Hour)},
+			nil),
+		Entry("a gated pod is placed and its stopped node started",
+			[]*corev1.Pod{gated("web")},
+			[]*rcv1.RcNode{node("a", rcv1.DesiredStateStopped, -1)},
+			[]string{"Start a", "place web on a"}),
+		Entry("a pod without the gate is left alone",
+			[]*corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "plain"}}},
+			[]*rcv1.RcNode{node("a", rcv1.DesiredStateStopped, -1)},
+			nil),
+		Entry("a node already assigned a pod is started",
+			[]*corev1.Pod{placed("web", "a")},
+			[]*rcv1.RcNode{node("a", rcv1.DesiredStateStopped, -1)},
+			[]string{"Start a"}),
+		Entry("a running node in use is kept",
+			[]*corev1.Pod{placed("web", "a")},
+			[]*rcv1.RcNode{node("a", rcv1.DesiredStateRunning, time.Hour)},
+			nil),
+		Entry("an unused node is stopped once idle for minIdle",
+			nil, []*rcv1.RcNode{node("a", rcv1.DesiredStateRunning, minIdle)},
+			[]string{"Stop a"}),
+		Entry("an unused node is kept before minIdle",
+			nil, []*rcv1.RcNode{node("a", rcv1.DesiredStateRunning, minIdle-time.Second)},
+			nil),
+		Entry("an unused node without a recorded transition is kept",
+			nil, []*rcv1.RcNode{node("a", rcv1.DesiredStateRunning, -1)},
+			nil),
+		Entry("a finished pod no longer needs its node",
+			[]*corev1.Pod{func() *corev1.Pod {
+				p := placed("job", "a")
+				p.Status.Phase = corev1.PodSucceeded
+				return p
+			}()},
+			[]*rcv1.RcNode{node("a", rcv1.DesiredStateRunning, time.Hour)},
+			[]string{"Stop a"}),
+	)
+
+	It("starts a node once for several pods and lists node actions by name first", func() {
+		nodes := []*rcv1.RcNode{
+			node("z", rcv1.DesiredStateRunning, time.Hour),
+			node("a", rcv1.DesiredStateStopped, -1),
+			node("m", rcv1.DesiredStateRunning, time.Hour),
+		}
+		pods := []*corev1.Pod{gated("one"), gated("two"), placed("web", "m")}
+
+		acts := RunStep(now, pods, nodes, policies, minIdle)
+		Expect(describe(acts)).To(Equal([]string{
+			"Start a", "Stop z", "place one on a", "place two on a",
+		}))
+		Expect(describe(RunStep(now, pods, nodes, policies, minIdle))).To(Equal(describe(acts)))
+
+		start := acts[0].(NodeAction)
+		Expect(start.ReadyAt).To(Equal(now.Add(30 * time.Second)))
+		patch := acts[2].(PodPatch)
+		Expect(patch.Tolerations).To(ConsistOf(NodeToleration("a")))
+		Expect(patch.RemoveGate).To(BeFalse())
+	})
+
+	It("skips pods no policy resolves for", func() {
+		pods := []*corev1.Pod{gated("web")}
+		nodes := []*rcv1.RcNode{node("a", rcv1.DesiredStateStopped, -1)}
+		Expect(RunStep(now, pods, nodes, nil, minIdle)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Planner", func() {
+	It("hands every tick's actions to its sink", func() {
+		st := &fixedState{
+			pods:  []*corev1.Pod{{ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "web", Annotations: map[string]string{AnnAssignment: "a"}}}},
+			nodes: []*rcv1.RcNode{{ObjectMeta: metav1.ObjectMeta{Name: "a"}, Spec: rcv1.RcNodeSpec{DesiredState: rcv1.DesiredStateStopped}}},
+		}
+		var got [][]Action
+		p := &Planner{st: st, cooldown: time.Minute, log: logr.Discard()}
+		p.Sink = func(_ context.Context, acts []Action) { got = append(got, acts) }
+
+		acts := p.Tick(context.Background(), time.Now())
+		Expect(acts).To(HaveLen(1))
+		Expect(acts[0].(NodeAction).Kind).To(Equal(NodeStart))
+
+		st.pods = nil
+		Expect(p.Tick(context.Background(), time.Now())).To(BeEmpty())
+		Expect(got).To(HaveLen(2)) // empty rounds reach the sink too
+	})
+
+	It("stops idle nodes after its idle timeout, not its cooldown", func() {
+		now := time.Now()
+		idleSince := func(d time.Duration) *rcv1.RcNode {
+			return &rcv1.RcNode{
+				ObjectMeta: metav1.ObjectMeta{Name: "a"},
+				Spec:       rcv1.RcNodeSpec{DesiredState: rcv1.DesiredStateRunning},
+				Status:     rcv1.RcNodeStatus{LastTransition: &metav1.Time{Time: now.Add(-d)}},
+			}
+		}
+		st := &fixedState{nodes: []*rcv1.RcNode{idleSince(time.Minute)}}
+		p := &Planner{st: st, cooldown: 5 * time.Second, log: logr.Discard(), Sink: func(context.Context, []Action) {}}
+		Expect(p.Tick(context.Background(), now)).To(BeEmpty())
+
+		st.nodes = []*rcv1.RcNode{idleSince(DefaultIdleTimeout)}
+		Expect(p.Tick(context.Background(), now)).To(ConsistOf(HaveField("Kind", NodeStop)))
+
+		p.IdleTimeout = time.Hour
+		Expect(p.Tick(context.Background(), now)).To(BeEmpty())
+	})
+
+	It("stops ticking with its context", func() {
+		p := &Planner{st: &fixedState{}, cooldown: time.Millisecond, log: logr.Discard()}
+		ticks := make(chan struct{}, 100)
+		p.Sink = func(context.Context, []Action) { ticks <- struct{}{} }
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- p.Start(ctx) }()
+		Eventually(ticks).Should(Receive())
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+})
+
+// fixedState serves the same objects on every call.
+type fixedState struct {
+	pods     []*corev1.Pod
+	nodes    []*rcv1.RcNode
+	policies []*rcv1.RcPolicy
+}
+
+var _ state.State = (*fixedState)(nil)
+
+func (s *fixedState) Start(context.Context) error  { return nil }
+func (s *fixedState) Pods() []*corev1.Pod          { return s.pods }
+func (s *fixedState) RcNodes() []*rcv1.RcNode      { return s.nodes }
+func (s *fixedState) RcPolicies() []*rcv1.RcPolicy { return s.policies }
@@ -0,0 +1,65 @@
+package lifecycle
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PowerState is the power state of a machine as seen by its driver.
+type PowerState string
+
+const (
+	PowerOn          PowerState = "On"
+	PowerOff         PowerState = "Off"
+	PowerPoweringOn  PowerState = "PoweringOn"
+	PowerPoweringOff PowerState = "PoweringOff"
+	PowerUnknown     PowerState = "Unknown"
+)
+
+// BootProgress is how far a powered machine got towards a Ready Node.
+type BootProgress string
+
+const (
+	BootNone           BootProgress = ""               // not booting (off)
+	BootPowerOn        BootProgress = "PowerOn"        // powered, no Node yet
+	BootNodeRegistered BootProgress = "NodeRegistered" // Node exists, not Ready
+	BootReady          BootProgress = "Ready"          // Node Ready
+)
+
+// Observation is what a driver reports about one RcNode's machine (see
+// backend.Backend.Observe). Step uses it, when there is one, on top of the
+// Node it looks at itself.
+type Observation struct {
+	Power PowerState
+	Boot  BootProgress
+	// PowerWatts is the measured draw; nil if the driver cannot tell.
+	PowerWatts *int
+	// Heartbeat is the last sign of life; nil if the driver has none.
+	Heartbeat *metav1.Time
+	// LastError is the last failure of a power action, "" if it succeeded.
+	LastError string
+	// Recheck is when the driver expects the next change worth observing
+	// (a simulated boot completing, …); 0 if it cannot tell.
+	Recheck time.Duration
+}
+
+// NodeProgress derives boot progress and heartbeat from the Node backing a
+// powered machine (nil when none registered yet). Drivers without better
+// sources use it to fill an Observation.
+func NodeProgress(node *corev1.Node) (BootProgress, *metav1.Time) {
+	if node == nil {
+		return BootPowerOn, nil
+	}
+	c := readyCondition(node)
+	var hb *metav1.Time
+	if c != nil && !c.LastHeartbeatTime.IsZero() {
+		t := c.LastHeartbeatTime
+		hb = &t
+	}
+	if c != nil && c.Status == corev1.ConditionTrue {
+		return BootReady, hb
+	}
+	return BootNodeRegistered, hb
+}
@@ -0,0 +1,345 @@
+// Package lifecycle derives RcNode.Status from what was asked
+// (Spec.DesiredState) and what is observed (the Kubernetes Node that the
+// backend brings up for the RcNode).
+//
+//	INACTIVE ─Running─► BOOTING ─Node registers─► ACTIVE_NOT_READY ◄─Ready─► ACTIVE_READY
+//	                       │ boot deadline                 │ Stopped / Node deleted
+//	                       ▼                               ▼
+//	                    UNKNOWN ◄─Node lost─          ACTIVE_DELETING ─Node gone─► INACTIVE
+//
+// When the driver can observe the machine itself (see Observation), its
+// power state refines the picture: a node that should be off but still
+// draws power stays ACTIVE_DELETING, one that never powered on or lost
+// power goes UNKNOWN.
+//
+// Before a node that should stop is powered off, the controller drains its
+// Node (package drain); meanwhile the status is ACTIVE_DELETING/Draining
+// (see Draining and DrainPending).
+//
+// Step is pure: the caller persists the returned status and requeues.
+package lifecycle
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+const (
+	// DefaultBootSeconds is assumed for nodes that do not set Spec.BootSeconds.
+	DefaultBootSeconds = 60
+	// bootGrace × boot time is how long BOOTING may last before the node is
+	// declared UNKNOWN.
+	bootGrace = 2
+)
+
+// Reasons written to Status.Reason.
+const (
+	ReasonPowerOn      = "PowerOn"
+	ReasonBootTimeout  = "BootTimeout"
+	ReasonNodeNotReady = "NodeNotReady"
+	ReasonNodeReady    = "NodeReady"
+	ReasonNodeLost     = "NodeLost"
+	ReasonPowerOff     = "PowerOff"
+	ReasonPoweredOff   = "PoweredOff"
+	ReasonPowerOnFail  = "PowerOnFailed"
+	ReasonPowerLost    = "PowerLost"
+	ReasonDeleting     = "Deleting"
+	ReasonDraining     = "Draining"
+)
+
+// BootTime is how long rc is expected to take from power-on to a
+// registered Node.
+func BootTime(rc *rcv1.RcNode) time.Duration {
+	s := rc.Spec.BootSeconds
+	if s <= 0 {
+		s = DefaultBootSeconds
+	}
+	return time.Duration(s) * time.Second
+}
+
+// Step computes the next status of rc given the observed Node (nil when no
+// Node is registered) and the driver's observation (nil when it has none),
+// and how long to wait before looking again (0 = only on the next event).
+func Step(rc *rcv1.RcNode, node *corev1.Node, obs *Observation, now time.Time) (rcv1.RcNodeStatus, time.Duration) {
+	st := *rc.Status.DeepCopy()
+	cur := st.State
+	wantRunning := rc.Spec.DesiredState == rcv1.DesiredStateRunning
+
+	if node != nil {
+		if c := readyCondition(node); c != nil && !c.LastHeartbeatTime.IsZero() {
+			hb := c.LastHeartbeatTime
+			st.LastHeartbeat = &hb
+		}
+	}
+	if obs != nil {
+		if obs.Heartbeat != nil {
+			hb := *obs.Heartbeat
+			st.LastHeartbeat = &hb
+		}
+		if obs.PowerWatts != nil {
+			w := *obs.PowerWatts
+			st.ObservedPowerWatts = &w
+		}
+	}
+	powered, off := obs.powered(), obs.off()
+
+	next, reason, msg := cur, st.Reason, st.Message
+	var requeue time.Duration
+
+	switch {
+	case node != nil && (!wantRunning || node.DeletionTimestamp != nil):
+		next, reason, msg = rcv1.NodeStatusActiveDeleting, ReasonPowerOff,
+			fmt.Sprintf("waiting for node %s to go away", node.Name)
+
+	case node != nil && isReady(node):
+		next, reason, msg = rcv1.NodeStatusActiveReady, ReasonNodeReady, ""
+
+	case node != nil:
+		next, reason = rcv1.NodeStatusActiveNotReady, ReasonNodeNotReady
+		msg = fmt.Sprintf("node %s is not Ready", node.Name)
+		if c := readyCondition(node); c != nil && c.Message != "" {
+			msg = c.Message
+		}
+
+	case !wantRunning && powered:
+		next, reason = rcv1.NodeStatusActiveDeleting, ReasonPowerOff
+		msg = fmt.Sprintf("waiting for the machine to power off (%s)", obs.Power)
+
+	case !wantRunning:
+		next, reason, msg = rcv1.NodeStatusInactive, ReasonPoweredOff, ""
+
+	// from here on: Running is wanted, no Node is registered
+	case cur == rcv1.NodeStatusBooting:
+		elapsed := since(st.LastTransition, now)
+		boot := BootTime(rc)
+		deadline := bootGrace * boot
+		switch {
+		case off && elapsed >= boot:
+			next, reason = rcv1.NodeStatusUnknown, ReasonPowerOnFail
+			msg = fmt.Sprintf("machine still powered off %s after power-on", elapsed.Round(time.Second))
+		case elapsed >= deadline:
+			next, reason = rcv1.NodeStatusUnknown, ReasonBootTimeout
+			msg = fmt.Sprintf("no node registered %s after power-on (expected %s)",
+				elapsed.Round(time.Second), boot)
+		case elapsed < boot:
+			requeue = boot - elapsed
+		default:
+			requeue = deadline - elapsed
+		}
+
+	case cur == rcv1.NodeStatusUnknown:
+		// boot timed out or the node was lost; stay put until a Node shows up
+
+	case isActive(cur) && off:
+		next, reason, msg = rcv1.NodeStatusUnknown, ReasonPowerLost,
+			"machine powered off while the RcNode should be running"
+
+	case isActive(cur):
+		next, reason, msg = rcv1.NodeStatusUnknown, ReasonNodeLost,
+			"node disappeared while the RcNode should be running"
+
+	default: // INACTIVE, ACTIVE_DELETING, "" → power on
+		next, reason = rcv1.NodeStatusBooting, ReasonPowerOn
+		msg = fmt.Sprintf("waiting for node to register (boot ~%s)", BootTime(rc))
+		requeue = BootTime(rc)
+	}
+
+	if next != cur {
+		t := metav1.NewTime(now)
+		st.LastTransition = &t
+	}
+	st.State, st.Reason, st.Message = next, reason, msg
+	setConditions(&st, rc.Generation, node, obs, now)
+	return st, requeue
+}
+
+// powered reports whether the driver saw the machine drawing power.
+func (o *Observation) powered() bool {
+	if o == nil {
+		return false
+	}
+	switch o.Power {
+	case PowerOn, PowerPoweringOn, PowerPoweringOff:
+		return true
+	}
+	return false
+}
+
+// off reports whether the driver saw the machine powered off.
+func (o *Observation) off() bool {
+	return o != nil && o.Power == PowerOff
+}
+
+// setConditions mirrors the state onto the standard conditions, so that
+// `kubectl wait --for=condition=Ready rcnode/x` works. PoweredOn follows
+// the driver's observation when there is one, the state otherwise.
+func setConditions(st *rcv1.RcNodeStatus, gen int64, node *corev1.Node, obs *Observation, now time.Time) {
+	st.ObservedGeneration = gen
+	set := func(t string, s metav1.ConditionStatus, reason, msg string) {
+		if reason == "" {
+			reason = "Unknown" // conditions require a reason
+		}
+		meta.SetStatusCondition(&st.Conditions, metav1.Condition{
+			Type: t, Status: s, Reason: reason, Message: msg,
+			ObservedGeneration: gen, LastTransitionTime: metav1.NewTime(now),
+		})
+	}
+
+	switch {
+	case obs.powered():
+		set(rcv1.RcNodeConditionPoweredOn, metav1.ConditionTrue, string(obs.Power), st.Message)
+	case obs.off():
+		set(rcv1.RcNodeConditionPoweredOn, metav1.ConditionFalse, string(obs.Power), st.Message)
+	case obs != nil && obs.Power == PowerUnknown:
+		set(rcv1.RcNodeConditionPoweredOn, metav1.ConditionUnknown, string(obs.Power), st.Message)
+
+	// no observation: infer from the state
+	case st.State == rcv1.NodeStatusBooting, st.State == rcv1.NodeStatusActive,
+		st.State == rcv1.NodeStatusActiveNotReady, st.State == rcv1.NodeStatusActiveReady:
+		set(rcv1.RcNodeConditionPoweredOn, metav1.ConditionTrue, st.Reason, st.Message)
+	case st.State == rcv1.NodeStatusUnknown:
+		set(rcv1.RcNodeConditionPoweredOn, metav1.ConditionUnknown, st.Reason, st.Message)
+	default:
+		set(rcv1.RcNodeConditionPoweredOn, metav1.ConditionFalse, st.Reason, st.Message)
+	}
+
+	if node != nil {
+		set(rcv1.RcNodeConditionNodeRegistered, metav1.ConditionTrue, "NodeFound",
+			fmt.Sprintf("backed by node %s", node.Name))
+	} else {
+		set(rcv1.RcNodeConditionNodeRegistered, metav1.ConditionFalse, "NodeNotFound", "")
+	}
+
+	if st.State == rcv1.NodeStatusActiveReady {
+		set(rcv1.RcNodeConditionReady, metav1.ConditionTrue, st.Reason, st.Message)
+	} else {
+		set(rcv1.RcNodeConditionReady, metav1.ConditionFalse, st.Reason, st.Message)
+	}
+}
+
+// Deleting is the status of a deleted rc while it is torn down (see
+// rcv1.RcNodeFinalizer): ACTIVE_DELETING and not Ready, msg saying what it
+// waits for.
+func Deleting(rc *rcv1.RcNode, msg string, now time.Time) rcv1.RcNodeStatus {
+	return leaving(rc, ReasonDeleting, msg, now)
+}
+
+// Draining is the status of rc while its Node is drained before the
+// driver powers it off: ACTIVE_DELETING and not Ready, msg telling the
+// drain's progress.
+func Draining(rc *rcv1.RcNode, msg string, now time.Time) rcv1.RcNodeStatus {
+	return leaving(rc, ReasonDraining, msg, now)
+}
+
+// DrainPending reports whether rc, which should stop, still has to be
+// drained before its driver powers it off, and for how long it has been
+// draining. It is false once the drain is over (the state moved on to
+// ACTIVE_DELETING/PowerOff) or when the node is leaving for another
+// reason.
+func DrainPending(rc *rcv1.RcNode, now time.Time) (bool, time.Duration) {
+	switch {
+	case rc.Status.State != rcv1.NodeStatusActiveDeleting:
+		return true, 0 // about to begin
+	case rc.Status.Reason == ReasonDraining:
+		return true, since(rc.Status.LastTransition, now)
+	}
+	return false, 0
+}
+
+func leaving(rc *rcv1.RcNode, reason, msg string, now time.Time) rcv1.RcNodeStatus {
+	st := *rc.Status.DeepCopy()
+	if st.State != rcv1.NodeStatusActiveDeleting {
+		t := metav1.NewTime(now)
+		st.LastTransition = &t
+	}
+	st.State, st.Reason, st.Message = rcv1.NodeStatusActiveDeleting, reason, msg
+	st.ObservedGeneration = rc.Generation
+	meta.SetStatusCondition(&st.Conditions, metav1.Condition{
+		Type: rcv1.RcNodeConditionReady, Status: metav1.ConditionFalse,
+		Reason: reason, Message: msg,
+		ObservedGeneration: rc.Generation, LastTransitionTime: metav1.NewTime(now),
+	})
+	return st
+}
+
+// SetBackendError records the outcome of the last backend call (err == nil
+// clears the condition).
+func SetBackendError(st *rcv1.RcNodeStatus, gen int64, err error, now time.Time) {
+	c := metav1.Condition{
+		Type:   rcv1.RcNodeConditionBackendError,
+		Status: metav1.ConditionFalse, Reason: "BackendOK",
+		ObservedGeneration: gen, LastTransitionTime: metav1.NewTime(now),
+	}
+	if err != nil {
+		c.Status, c.Reason, c.Message = metav1.ConditionTrue, "BackendFailed", err.Error()
+	}
+	meta.SetStatusCondition(&st.Conditions, c)
+}
+
+// SetDrainBlocked records the pods without a controller that keep the
+// RcNode's Node from draining (none clears the condition).
+func SetDrainBlocked(st *rcv1.RcNodeStatus, gen int64, unowned []string, now time.Time) {
+	if len(unowned) == 0 {
+		meta.RemoveStatusCondition(&st.Conditions, rcv1.RcNodeConditionDrainBlocked)
+		return
+	}
+	meta.SetStatusCondition(&st.Conditions, metav1.Condition{
+		Type:   rcv1.RcNodeConditionDrainBlocked,
+		Status: metav1.ConditionTrue, Reason: "UnownedPods",
+		Message: fmt.Sprintf("pods without a controller would be lost, move or delete them: %s",
+			strings.Join(unowned, ", ")),
+		ObservedGeneration: gen, LastTransitionTime: metav1.NewTime(now),
+	})
+}
+
+// SetDriver records which driver handles the RcNode; err (unknown or
+// unusable driver) turns DriverResolved False.
+func SetDriver(st *rcv1.RcNodeStatus, gen int64, driver string, err error, now time.Time) {
+	st.Driver = driver
+	c := metav1.Condition{
+		Type:   rcv1.RcNodeConditionDriverResolved,
+		Status: metav1.ConditionTrue, Reason: "DriverFound",
+		Message:            fmt.Sprintf("powered by driver %q", driver),
+		ObservedGeneration: gen, LastTransitionTime: metav1.NewTime(now),
+	}
+	if err != nil {
+		c.Status, c.Reason, c.Message = metav1.ConditionFalse, "DriverUnavailable", err.Error()
+	}
+	meta.SetStatusCondition(&st.Conditions, c)
+}
+
+func since(t *metav1.Time, now time.Time) time.Duration {
+	if t == nil {
+		return 0
+	}
+	return now.Sub(t.Time)
+}
+
+func isActive(s rcv1.NodeStatus) bool {
+	switch s {
+	case rcv1.NodeStatusActive, rcv1.NodeStatusActiveReady, rcv1.NodeStatusActiveNotReady:
+		return true
+	}
+	return false
+}
+
+func readyCondition(n *corev1.Node) *corev1.NodeCondition {
+	for i := range n.Status.Conditions {
+		if n.Status.Conditions[i].Type == corev1.NodeReady {
+			return &n.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func isReady(n *corev1.Node) bool {
+	c := readyCondition(n)
+	return c != nil && c.Status == corev1.ConditionTrue
+}
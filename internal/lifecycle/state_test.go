@@ -0,0 +1,233 @@
+package lifecycle
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+var _ = Describe("RcNode state machine", func() {
+	t0 := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var rc *rcv1.RcNode
+	BeforeEach(func() {
+		rc = &rcv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Name: "n1"},
+			Spec:       rcv1.RcNodeSpec{BootSeconds: 30, DesiredState: rcv1.DesiredStateRunning},
+		}
+	})
+
+	node := func(ready corev1.ConditionStatus) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "n1"},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{{
+				Type: corev1.NodeReady, Status: ready, Message: "kubelet starting",
+				LastHeartbeatTime: metav1.NewTime(t0),
+			}}},
+		}
+	}
+
+	// step applies Step to rc and returns the requeue delay.
+	step := func(n *corev1.Node, at time.Time) time.Duration {
+		st, requeue := Step(rc, n, nil, at)
+		rc.Status = st
+		return requeue
+	}
+	// observed is step with a driver observation.
+	observed := func(n *corev1.Node, obs Observation, at time.Time) time.Duration {
+		st, requeue := Step(rc, n, &obs, at)
+		rc.Status = st
+		return requeue
+	}
+
+	It("walks INACTIVE → BOOTING → ACTIVE_NOT_READY → ACTIVE_READY → ACTIVE_DELETING → INACTIVE", func() {
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		Expect(step(nil, t0)).To(BeZero())
+		Expect(rc.Status.State).To(Equal(rcv1.NodeStatusInactive))
+
+		rc.Spec.DesiredState = rcv1.DesiredStateRunning
+		Expect(step(nil, t0)).To(Equal(30 * time.Second))
+		Expect(rc.Status.State).To(Equal(rcv1.NodeStatusBooting))
+		Expect(rc.Status.Reason).To(Equal(ReasonPowerOn))
+		Expect(rc.Status.LastTransition.Time).To(Equal(t0))
+
+		step(node(corev1.ConditionFalse), t0.Add(20*time.Second))
+		Expect(rc.Status.State).To(Equal(rcv1.NodeStatusActiveNotReady))
+		Expect(rc.Status.Message).To(Equal("kubelet starting"))
+		Expect(rc.Status.LastHeartbeat.Time).To(Equal(t0))
+
+		step(node(corev1.ConditionTrue), t0.Add(25*time.Second))
+		Expect(rc.Status.State).To(Equal(rcv1.NodeStatusActiveReady))
+		Expect(rc.Status.LastTransition.Time).To(Equal(t0.Add(25 * time.Second)))
+
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		step(node(corev1.ConditionTrue), t0.Add(time.Minute))
+		Expect(rc.Status.State).To(Equal(rcv1.NodeStatusActiveDeleting))
+
+		step(nil, t0.Add(2*time.Minute))
+		Expect(rc.Status.State).To(Equal(rcv1.NodeStatusInactive))
+		Expect(rc.Status.Reason).To(Equal(ReasonPoweredOff))
+	})
+
+	It("drains a node before it is powered off", func() {
+		step(node(corev1.ConditionTrue), t0)
+		rc.Spec.DesiredState = rcv1.DesiredStateStopped
+		pending, elapsed := DrainPending(rc, t0)
+		Expect(pending).To(BeTrue())
+		Expect(elapsed).To(BeZero())
+
+		rc.Status = Draining(rc, "draining node n1: 2 pods left", t0.Add(time.Minute))
+		Expect(rc.Status.State).To(Equal(rcv1.NodeStatusActiveDeleting))
+		Expect(rc.Status.Reason).To(Equal(ReasonDraining))
+		Expect(meta.IsStatusConditionFalse(rc.Status.Conditions, rcv1.RcNodeConditionReady)).To(BeTrue())
+		rc.Status = Draining(rc, "draining node n1: 1 pods left", t0.Add(2*time.Minute))
+		Expect(rc.Status.LastTransition.Time).To(Equal(t0.Add(time.Minute)))
+		pending, elapsed = DrainPending(rc, t0.Add(3*time.Minute))
+		Expect(pending).To(BeTrue())
+		Expect(elapsed).To(Equal(2 * time.Minute))
+
+		// drained: the driver powers it off
+		step(node(corev1.ConditionTrue), t0.Add(4*time.Minute))
+		Expect(rc.Status.Reason).To(Equal(ReasonPowerOff))
+		pending, _ = DrainPending(rc, t0.Add(4*time.Minute))
+		Expect(pending).To(BeFalse())
+	})
+
+	It("requeues while booting and gives up after the boot deadline", func() {
+		step(nil, t0)
+		Expect(step(nil, t0.Add(10*time.Second))).To(Equal(20 * time.Second))
+		Expect(step(nil, t0.Add(45*time.Second))).To(Equal(15 * time.Second))
+		Expect(rc.Status.State).To(Equal(rcv1.NodeStatusBooting))
+		Expect(rc.Status.LastTransition.Time).To(Equal(t0))
+
+		Expect(step(nil, t0.Add(time.Minute))).To(BeZero())
+		Expect(rc.Status.State).To(Equal(rcv1.NodeStatusUnknown))
+		Expect(rc.Status.Reason).To(Equal(ReasonBootTimeout))
+
+		// a late Node still brings it up
+		step(node(corev1.ConditionTrue), t0.Add(2*time.Minute))
+		Expect(rc.Status.State).To(Equal(rcv1.NodeStatusActiveReady))
+	})
+
+	It("marks a running node whose Node vanished as UNKNOWN", func() {
+		rc.Status.State = rcv1.NodeStatusActiveReady
+		step(nil, t0)
+		Expect(rc.Status.State).To(Equal(rcv1.NodeStatusUnknown))
+		Expect(rc.Status.Reason).To(Equal(ReasonNodeLost))
+	})
+
+	It("treats a Node being deleted as ACTIVE_DELETING even if Running is wanted", func() {
+		n := node(corev1.ConditionTrue)
+		n.DeletionTimestamp = &metav1.Time{Time: t0}
+		step(n, t0)
+		Expect(rc.Status.State).To(Equal(rcv1.NodeStatusActiveDeleting))
+	})
+
+	It("mirrors the state onto standard conditions", func() {
+		rc.Generation = 3
+		step(nil, t0)
+		Expect(meta.IsStatusConditionTrue(rc.Status.Conditions, rcv1.RcNodeConditionPoweredOn)).To(BeTrue())
+		Expect(meta.IsStatusConditionFalse(rc.Status.Conditions, rcv1.RcNodeConditionNodeRegistered)).To(BeTrue())
+		Expect(meta.IsStatusConditionFalse(rc.Status.Conditions, rcv1.RcNodeConditionReady)).To(BeTrue())
+
+		step(node(corev1.ConditionTrue), t0.Add(time.Second))
+		ready := meta.FindStatusCondition(rc.Status.Conditions, rcv1.RcNodeConditionReady)
+		Expect(ready.Status).To(Equal(metav1.ConditionTrue))
+		Expect(ready.ObservedGeneration).To(Equal(int64(3)))
+		Expect(rc.Status.ObservedGeneration).To(Equal(int64(3)))
+
+		SetBackendError(&rc.Status, 3, errors.New("bmc unreachable"), t0)
+		be := meta.FindStatusCondition(rc.Status.Conditions, rcv1.RcNodeConditionBackendError)
+		Expect(be.Status).To(Equal(metav1.ConditionTrue))
+		Expect(be.Message).To(Equal("bmc unreachable"))
+		SetBackendError(&rc.Status, 3, nil, t0)
+		Expect(meta.IsStatusConditionFalse(rc.Status.Conditions, rcv1.RcNodeConditionBackendError)).To(BeTrue())
+
+		SetDriver(&rc.Status, 3, "ipmi", errors.New(`unknown driver "ipmi"`), t0)
+		Expect(rc.Status.Driver).To(Equal("ipmi"))
+		dr := meta.FindStatusCondition(rc.Status.Conditions, rcv1.RcNodeConditionDriverResolved)
+		Expect(dr.Status).To(Equal(metav1.ConditionFalse))
+		Expect(dr.Reason).To(Equal("DriverUnavailable"))
+		SetDriver(&rc.Status, 3, "kwok", nil, t0)
+		Expect(meta.IsStatusConditionTrue(rc.Status.Conditions, rcv1.RcNodeConditionDriverResolved)).To(BeTrue())
+	})
+
+	It("leaves LastTransition alone when nothing changes", func() {
+		step(node(corev1.ConditionTrue), t0)
+		step(node(corev1.ConditionTrue), t0.Add(time.Hour))
+		Expect(rc.Status.LastTransition.Time).To(Equal(t0))
+	})
+
+	It("reports a deleted RcNode as ACTIVE_DELETING while it is torn down", func() {
+		step(node(corev1.ConditionTrue), t0)
+		rc.Status = Deleting(rc, "draining node n1: 2 pods left", t0.Add(time.Minute))
+		Expect(rc.Status.State).To(Equal(rcv1.NodeStatusActiveDeleting))
+		Expect(rc.Status.Reason).To(Equal(ReasonDeleting))
+		Expect(rc.Status.LastTransition.Time).To(Equal(t0.Add(time.Minute)))
+		Expect(meta.IsStatusConditionFalse(rc.Status.Conditions, rcv1.RcNodeConditionReady)).To(BeTrue())
+
+		rc.Status = Deleting(rc, "waiting for the machine to power off (PoweringOff)", t0.Add(2*time.Minute))
+		Expect(rc.Status.Message).To(ContainSubstring("PoweringOff"))
+		Expect(rc.Status.LastTransition.Time).To(Equal(t0.Add(time.Minute)))
+	})
+
+	Context("with a driver observation", func() {
+		It("records power draw and heartbeat", func() {
+			w := 180
+			hb := metav1.NewTime(t0.Add(-time.Second))
+			observed(nil, Observation{Power: PowerOn, Boot: BootPowerOn, PowerWatts: &w, Heartbeat: &hb}, t0)
+			Expect(rc.Status.State).To(Equal(rcv1.NodeStatusBooting))
+			Expect(*rc.Status.ObservedPowerWatts).To(Equal(180))
+			Expect(rc.Status.LastHeartbeat.Time).To(Equal(hb.Time))
+			Expect(meta.FindStatusCondition(rc.Status.Conditions, rcv1.RcNodeConditionPoweredOn).Reason).
+				To(Equal("On"))
+		})
+
+		It("fails a boot whose machine never powered on", func() {
+			observed(nil, Observation{Power: PowerOff}, t0)
+			Expect(rc.Status.State).To(Equal(rcv1.NodeStatusBooting))
+			observed(nil, Observation{Power: PowerOff}, t0.Add(20*time.Second))
+			Expect(rc.Status.State).To(Equal(rcv1.NodeStatusBooting))
+			Expect(meta.IsStatusConditionFalse(rc.Status.Conditions, rcv1.RcNodeConditionPoweredOn)).To(BeTrue())
+
+			observed(nil, Observation{Power: PowerOff}, t0.Add(30*time.Second))
+			Expect(rc.Status.State).To(Equal(rcv1.NodeStatusUnknown))
+			Expect(rc.Status.Reason).To(Equal(ReasonPowerOnFail))
+		})
+
+		It("tells a power loss from a lost Node", func() {
+			rc.Status.State = rcv1.NodeStatusActiveReady
+			observed(nil, Observation{Power: PowerOff}, t0)
+			Expect(rc.Status.Reason).To(Equal(ReasonPowerLost))
+
+			rc.Status.State = rcv1.NodeStatusActiveReady
+			observed(nil, Observation{Power: PowerOn, Boot: BootPowerOn}, t0)
+			Expect(rc.Status.Reason).To(Equal(ReasonNodeLost))
+		})
+
+		It("waits for the power to go off before INACTIVE", func() {
+			rc.Spec.DesiredState = rcv1.DesiredStateStopped
+			rc.Status.State = rcv1.NodeStatusActiveDeleting
+			observed(nil, Observation{Power: PowerPoweringOff}, t0)
+			Expect(rc.Status.State).To(Equal(rcv1.NodeStatusActiveDeleting))
+			observed(nil, Observation{Power: PowerOff}, t0.Add(time.Minute))
+			Expect(rc.Status.State).To(Equal(rcv1.NodeStatusInactive))
+		})
+
+		It("derives boot progress from the Node", func() {
+			Expect(NodeProgress(nil)).To(Equal(BootPowerOn))
+			p, hb := NodeProgress(node(corev1.ConditionFalse))
+			Expect(p).To(Equal(BootNodeRegistered))
+			Expect(hb.Time).To(Equal(t0))
+			p, _ = NodeProgress(node(corev1.ConditionTrue))
+			Expect(p).To(Equal(BootReady))
+		})
+	})
+})
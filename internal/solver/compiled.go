@@ -0,0 +1,181 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"k8s.io/apimachinery/pkg/types"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                            compiled policies                               */
+/* -------------------------------------------------------------------------- */
+//
+// Parsing + type-checking CEL is by far the most expensive part of scoring,
+// and policies change rarely. Every RcPolicy is therefore compiled once per
+// (UID, generation) and the programs are reused for every node and pod.
+
+// CompiledPolicy holds the type-checked programs of one RcPolicy generation.
+type CompiledPolicy struct {
+	UID        types.UID
+	Generation int64
+
+	constraints []program                     // same order as Spec.HardConstraints
+	soft        []program                     // same order as Spec.SoftConstraints
+	transforms  map[string]program            // metric key → transform
+	feeds       map[string]map[string]program // feed name → metric key → multiplier
+
+	// Errors lists every expression that failed to compile, formatted for
+	// humans ("hardConstraints[0]: …"). Empty means the policy is valid.
+	Errors []string
+}
+
+type program struct {
+	expr string
+	prg  cel.Program
+}
+
+// Err folds Errors into a single error (nil when the policy is valid).
+func (c *CompiledPolicy) Err() error {
+	if len(c.Errors) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(c.Errors, "; "))
+}
+
+// Compile type-checks every expression of pol. It never fails as a whole:
+// broken expressions are reported in CompiledPolicy.Errors.
+func Compile(pol *rcv1.RcPolicy) *CompiledPolicy {
+	return defaultCache.get(pol)
+}
+
+// Retain drops cached programs of policies that are no longer in the list.
+func Retain(pols []*rcv1.RcPolicy) {
+	defaultCache.retain(pols)
+}
+
+func compilePolicy(pol *rcv1.RcPolicy) *CompiledPolicy {
+	c := &CompiledPolicy{
+		UID:        pol.UID,
+		Generation: pol.Generation,
+		transforms: map[string]program{},
+		feeds:      map[string]map[string]program{},
+	}
+	fail := func(where string, err error) {
+		c.Errors = append(c.Errors, fmt.Sprintf("%s: %v", where, err))
+	}
+
+	for i, hc := range pol.Spec.HardConstraints {
+		prg, err := compile(hc.Expression, cel.BoolType)
+		if err != nil {
+			fail(fmt.Sprintf("hardConstraints[%d]", i), err)
+		}
+		c.constraints = append(c.constraints, program{expr: hc.Expression, prg: prg})
+	}
+
+	for i, sc := range pol.Spec.SoftConstraints {
+		prg, err := compile(sc.Expression, cel.BoolType)
+		if err != nil {
+			fail(fmt.Sprintf("softConstraints[%d]", i), err)
+		}
+		if sc.Penalty < 0 {
+			fail(fmt.Sprintf("softConstraints[%d].penalty", i), fmt.Errorf("negative: %g", sc.Penalty))
+		}
+		c.soft = append(c.soft, program{expr: sc.Expression, prg: prg})
+	}
+
+	if err := checkRanking(&pol.Spec); err != nil {
+		fail("mode", err)
+	}
+	for i, m := range pol.Spec.Metrics {
+		if err := checkNormalization(m.Normalize); err != nil {
+			fail(fmt.Sprintf("metrics[%d] (%s).normalize", i, m.Key), err)
+		}
+		if m.Tolerance < 0 {
+			fail(fmt.Sprintf("metrics[%d] (%s).tolerance", i, m.Key), fmt.Errorf("negative: %g", m.Tolerance))
+		}
+		if m.Transform == nil {
+			continue
+		}
+		prg, err := compile(*m.Transform, cel.DoubleType)
+		if err != nil {
+			fail(fmt.Sprintf("metrics[%d] (%s).transform", i, m.Key), err)
+			continue
+		}
+		c.transforms[m.Key] = program{expr: *m.Transform, prg: prg}
+	}
+
+	for i, f := range pol.Spec.ExternalFeeds {
+		byKey := map[string]program{}
+		seen := map[string]bool{}
+		for j, mp := range f.Mappings {
+			// one multiplier per metric: a second would silently win
+			if seen[mp.Key] {
+				fail(fmt.Sprintf("externalFeeds[%d] (%s).mappings[%d]", i, f.Name, j),
+					fmt.Errorf("metric %q is already mapped by this feed", mp.Key))
+				continue
+			}
+			seen[mp.Key] = true
+			expr := feedExpr(mp.Transform)
+			prg, err := compile(expr, cel.DoubleType)
+			if err != nil {
+				fail(fmt.Sprintf("externalFeeds[%d] (%s).mappings[%d]", i, f.Name, j), err)
+				continue
+			}
+			byKey[mp.Key] = program{expr: expr, prg: prg}
+		}
+		c.feeds[f.Name] = byKey
+	}
+	return c
+}
+
+// feedExpr maps the documented `$value` placeholder (not a legal CEL
+// identifier) onto the `value` variable.
+func feedExpr(expr string) string {
+	return strings.ReplaceAll(expr, "$value", "value")
+}
+
+/* -------------------------------- cache ----------------------------------- */
+
+var defaultCache = &policyCache{entries: map[types.UID]*CompiledPolicy{}}
+
+type policyCache struct {
+	mu      sync.Mutex
+	entries map[types.UID]*CompiledPolicy
+}
+
+func (pc *policyCache) get(pol *rcv1.RcPolicy) *CompiledPolicy {
+	// Objects that never went through the API server have no UID; there is
+	// nothing stable to key on, so compile them every time.
+	if pol.UID == "" {
+		return compilePolicy(pol)
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	if c, ok := pc.entries[pol.UID]; ok && c.Generation == pol.Generation {
+		return c
+	}
+	c := compilePolicy(pol)
+	pc.entries[pol.UID] = c
+	return c
+}
+
+func (pc *policyCache) retain(pols []*rcv1.RcPolicy) {
+	live := make(map[types.UID]struct{}, len(pols))
+	for _, p := range pols {
+		live[p.UID] = struct{}{}
+	}
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for uid := range pc.entries {
+		if _, ok := live[uid]; !ok {
+			delete(pc.entries, uid)
+		}
+	}
+}
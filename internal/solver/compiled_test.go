@@ -0,0 +1,73 @@
+package solver
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+var _ = Describe("compiled policies", func() {
+	newPolicy := func(uid string, gen int64, constraint string) *rcv1.RcPolicy {
+		transform := "x / 1000"
+		return &rcv1.RcPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "p", UID: types.UID("uid-" + uid), Generation: gen},
+			Spec: rcv1.RcPolicySpec{
+				Metrics:         []rcv1.PolicyMetric{{Key: "watts", Weight: 1, Transform: &transform}},
+				HardConstraints: []rcv1.PolicyConstraint{{Expression: constraint}},
+				ExternalFeeds: []rcv1.ExternalFeedRef{{
+					Name:     "carbon",
+					Mappings: []rcv1.FeedMetricMapping{{Key: "watts", Transform: "1 + ($value / 1000)"}},
+				}},
+			},
+		}
+	}
+
+	It("compiles every expression kind, including $value feed mappings", func() {
+		cp := Compile(newPolicy("a", 1, "spec.cpu.cores > 2"))
+		Expect(cp.Err()).NotTo(HaveOccurred())
+		Expect(cp.constraints).To(HaveLen(1))
+		Expect(cp.transforms).To(HaveKey("watts"))
+		Expect(cp.feeds["carbon"]).To(HaveKey("watts"))
+	})
+
+	It("reports broken expressions with their location", func() {
+		cp := Compile(newPolicy("b", 1, "spec.nope > 2"))
+		Expect(cp.Errors).To(HaveLen(1))
+		Expect(cp.Errors[0]).To(HavePrefix("hardConstraints[0]"))
+	})
+
+	It("rejects a feed mapping the same metric twice", func() {
+		pol := newPolicy("dup", 1, "true")
+		pol.Spec.ExternalFeeds[0].Mappings = append(pol.Spec.ExternalFeeds[0].Mappings,
+			rcv1.FeedMetricMapping{Key: "watts", Transform: "2"})
+		cp := Compile(pol)
+		Expect(cp.Errors).To(Equal([]string{
+			`externalFeeds[0] (carbon).mappings[1]: metric "watts" is already mapped by this feed`,
+		}))
+	})
+
+	It("caches by UID and generation", func() {
+		first := Compile(newPolicy("c", 1, "true"))
+		Expect(Compile(newPolicy("c", 1, "true"))).To(BeIdenticalTo(first))
+		Expect(Compile(newPolicy("c", 2, "true"))).NotTo(BeIdenticalTo(first))
+
+		Retain(nil)
+		Expect(defaultCache.entries).To(BeEmpty())
+	})
+
+	It("aborts expressions that exceed the cost limit", func() {
+		list := "[" + strings.TrimSuffix(strings.Repeat("1,", 20), ",") + "]"
+		expr := list + ".all(a, " + list + ".all(b, " + list + ".all(c, " + list + ".all(d, " +
+			list + ".all(e, a + b + c + d + e > 0)))))"
+		cp := Compile(newPolicy("d", 1, expr))
+		Expect(cp.Err()).NotTo(HaveOccurred())
+
+		_, err := satisfies(evalInput{Node: sampleNode()}, cp.constraints[0])
+		Expect(err).To(MatchError(ContainSubstring("cost limit")))
+	})
+})
@@ -0,0 +1,213 @@
+package solver
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                          shared CEL environment                            */
+/* -------------------------------------------------------------------------- */
+//
+// Every expression (hard constraint, metric transform) is compiled against
+// the same environment. Variables:
+//
+//	spec, status, metadata   the candidate RcNode
+//	pod                      the Pod being placed
+//	policy                   the RcPolicy in use
+//	cpu, ram, boot           legacy shortcuts (double) kept for old policies
+//	x                        the fetched metric value inside transforms
+//	value                    the feed reading inside feed mappings ($value)
+//
+// Object types are generated from the Go structs by reflection, so field
+// names are the JSON ones (spec.memoryBytes, status.predictedPowerWatts …)
+// and a typo is a compile error, not a silent false.
+//
+// Helper functions:
+//
+//	quantity("4Gi")            → double   (also accepts resource.Quantity)
+//	hasFlag("avx512f")         → "avx512f" in spec.cpu.flags
+//	inPool("edge")             → spec.nodePool == "edge"
+
+var env *cel.Env
+
+// CEL type names of the native object types, as derived by ext.NativeTypes.
+const (
+	typeNodeSpec   = "v1alpha1.RcNodeSpec"
+	typeNodeStatus = "v1alpha1.RcNodeStatus"
+	typeObjectMeta = "v1.ObjectMeta"
+	typePod        = "v1.Pod"
+	typePolicy     = "v1alpha1.RcPolicy"
+	typeQuantity   = "resource.Quantity"
+)
+
+func init() {
+	e, err := cel.NewEnv(
+		ext.NativeTypes(
+			reflect.TypeOf(&rcv1.RcNodeSpec{}),
+			reflect.TypeOf(&rcv1.RcNodeStatus{}),
+			reflect.TypeOf(&metav1.ObjectMeta{}),
+			reflect.TypeOf(&corev1.Pod{}),
+			reflect.TypeOf(&rcv1.RcPolicy{}),
+			ext.ParseStructField(jsonFieldName),
+		),
+		cel.Variable("spec", cel.ObjectType(typeNodeSpec)),
+		cel.Variable("status", cel.ObjectType(typeNodeStatus)),
+		cel.Variable("metadata", cel.ObjectType(typeObjectMeta)),
+		cel.Variable("pod", cel.ObjectType(typePod)),
+		cel.Variable("policy", cel.ObjectType(typePolicy)),
+
+		cel.Variable("cpu", cel.DoubleType),
+		cel.Variable("ram", cel.DoubleType),
+		cel.Variable("boot", cel.DoubleType),
+		cel.Variable("x", cel.DoubleType),     // used only inside metric transforms
+		cel.Variable("value", cel.DoubleType), // feed reading inside feed mappings
+
+		cel.Function("quantity",
+			cel.Overload("quantity_string", []*cel.Type{cel.StringType}, cel.DoubleType,
+				cel.UnaryBinding(quantityFromString)),
+			cel.Overload("quantity_quantity", []*cel.Type{cel.ObjectType(typeQuantity)}, cel.DoubleType,
+				cel.UnaryBinding(quantityFromNative)),
+		),
+		cel.CrossTypeNumericComparisons(true), // legacy `ram < 5` (double vs int)
+		cel.Macros(
+			cel.GlobalMacro("hasFlag", 1, expandHasFlag),
+			cel.GlobalMacro("inPool", 1, expandInPool),
+		),
+	)
+	if err != nil {
+		panic(err)
+	}
+	env = e
+}
+
+// jsonFieldName exposes struct fields under their JSON name; inline and
+// untagged fields keep the Go name.
+func jsonFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return f.Name
+	}
+	return name
+}
+
+/* ------------------------------- helpers ---------------------------------- */
+
+func quantityFromString(v ref.Val) ref.Val {
+	s, ok := v.Value().(string)
+	if !ok {
+		return types.NewErr("quantity: want string, got %s", v.Type())
+	}
+	q, err := resource.ParseQuantity(s)
+	if err != nil {
+		return types.NewErr("quantity(%q): %v", s, err)
+	}
+	return types.Double(q.AsApproximateFloat64())
+}
+
+func quantityFromNative(v ref.Val) ref.Val {
+	switch q := v.Value().(type) {
+	case resource.Quantity:
+		return types.Double(q.AsApproximateFloat64())
+	case *resource.Quantity:
+		return types.Double(q.AsApproximateFloat64())
+	}
+	return types.NewErr("quantity: unsupported value %T", v.Value())
+}
+
+// hasFlag(f) → f in spec.cpu.flags
+func expandHasFlag(eh cel.MacroExprFactory, _ ast.Expr, args []ast.Expr) (ast.Expr, *cel.Error) {
+	flags := eh.NewSelect(eh.NewSelect(eh.NewIdent("spec"), "cpu"), "flags")
+	return eh.NewCall(operators.In, args[0], flags), nil
+}
+
+// inPool(p) → spec.nodePool == p
+func expandInPool(eh cel.MacroExprFactory, _ ast.Expr, args []ast.Expr) (ast.Expr, *cel.Error) {
+	return eh.NewCall(operators.Equals, eh.NewSelect(eh.NewIdent("spec"), "nodePool"), args[0]), nil
+}
+
+/* ------------------------------ activation -------------------------------- */
+
+// evalInput is everything an expression may look at for one candidate.
+type evalInput struct {
+	Node   *rcv1.RcNode
+	Pod    *corev1.Pod
+	Policy *rcv1.RcPolicy
+}
+
+func (in evalInput) vars() map[string]interface{} {
+	pod, pol := in.Pod, in.Policy
+	if pod == nil {
+		pod = &corev1.Pod{}
+	}
+	if pol == nil {
+		pol = &rcv1.RcPolicy{}
+	}
+	return map[string]interface{}{
+		"spec":     &in.Node.Spec,
+		"status":   &in.Node.Status,
+		"metadata": &in.Node.ObjectMeta,
+		"pod":      pod,
+		"policy":   pol,
+		"cpu":      float64(in.Node.Spec.CPU.Cores),
+		"ram":      float64(in.Node.Spec.Memory),
+		"boot":     float64(in.Node.Spec.BootSeconds),
+	}
+}
+
+// costLimit bounds the runtime cost of a single evaluation (same order of
+// magnitude as the API server's per-expression budget) so a pathological
+// expression cannot stall the planner.
+const costLimit = 1_000_000
+
+// compile parses + type-checks expr and verifies its result type.
+//
+// Expressions that must yield a double (transforms, feed multipliers) get a
+// second chance: if they fail to type-check as written, integer literals are
+// promoted to doubles, so `1 + value / 1000` works without writing `1.0`.
+func compile(expr string, want *cel.Type) (cel.Program, error) {
+	checked, iss := env.Compile(expr)
+	if iss.Err() != nil && want == cel.DoubleType {
+		if parsed, piss := env.Parse(expr); piss.Err() == nil {
+			promoteIntLiterals(parsed)
+			if retry, riss := env.Check(parsed); riss.Err() == nil {
+				checked, iss = retry, riss
+			}
+		}
+	}
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	if !checked.OutputType().IsAssignableType(want) {
+		return nil, fmt.Errorf("expression %q returns %s, want %s", expr, checked.OutputType(), want)
+	}
+	return env.Program(checked,
+		cel.CostLimit(costLimit),
+		cel.InterruptCheckFrequency(100),
+	)
+}
+
+func promoteIntLiterals(a *cel.Ast) {
+	fac := ast.NewExprFactory()
+	ast.PostOrderVisit(a.NativeRep().Expr(), ast.NewExprVisitor(func(e ast.Expr) {
+		if e.Kind() != ast.LiteralKind {
+			return
+		}
+		if i, ok := e.AsLiteral().(types.Int); ok {
+			e.SetKindCase(fac.NewLiteral(e.ID(), types.Double(i)))
+		}
+	}))
+}
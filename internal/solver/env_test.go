@@ -0,0 +1,66 @@
+package solver
+
+import (
+	"github.com/google/cel-go/cel"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+var _ = Describe("CEL environment", func() {
+	var in evalInput
+
+	BeforeEach(func() {
+		n := sampleNode()
+		n.Spec.NodePool = "edge"
+		n.Spec.MaxPowerConsumption = 150
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"tier": "batch"}},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Name: "c",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("500m"),
+				}},
+			}}},
+		}
+		in = evalInput{Node: n, Pod: pod, Policy: &rcv1.RcPolicy{ObjectMeta: metav1.ObjectMeta{Name: "p"}}}
+	})
+
+	check := func(expr string) (bool, error) {
+		prg, err := compile(expr, cel.BoolType)
+		if err != nil {
+			return false, err
+		}
+		return satisfies(in, program{expr: expr, prg: prg})
+	}
+
+	DescribeTable("hard constraints",
+		func(expr string, want bool) {
+			Expect(check(expr)).To(Equal(want))
+		},
+		Entry("status vs spec", "status.predictedPowerWatts <= spec.maxPowerConsumption", true),
+		Entry("memory arithmetic", "spec.memoryBytes >= 4 * 1024 * 1024 * 1024", true),
+		Entry("legacy shortcut", "ram < 5", false),
+		Entry("node metadata", "metadata.labels['recluster.io/ssd'] == 'true'", true),
+		Entry("pod labels", "pod.metadata.labels['tier'] == 'batch'", true),
+		Entry("pod requests", "quantity(pod.spec.containers[0].resources.requests['cpu']) < double(spec.cpu.cores)", true),
+		Entry("quantity from string", "quantity('4Gi') < double(spec.memoryBytes)", true),
+		Entry("policy", "policy.metadata.name == 'p'", true),
+		Entry("hasFlag", "hasFlag('avx2') && !hasFlag('avx512f')", true),
+		Entry("inPool", "inPool('edge')", true),
+	)
+
+	It("rejects unknown fields at compile time", func() {
+		_, err := check("spec.nope > 1")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects non-boolean constraints", func() {
+		_, err := check("spec.cpu.cores")
+		Expect(err).To(HaveOccurred())
+	})
+})
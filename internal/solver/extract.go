@@ -0,0 +1,225 @@
+package solver
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/jsonpath"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                          metric value extraction                           */
+/* -------------------------------------------------------------------------- */
+
+// legacySelectors keeps the original hard-coded keys working for policies
+// that set neither Source nor Selector.
+var legacySelectors = map[string]string{
+	"cpu":  "$.spec.cpu.cores",
+	"ram":  "$.spec.memoryBytes",
+	"boot": "$.spec.bootSeconds",
+}
+
+// toDoc converts an RcNode into the generic map form both JSONPath and
+// fieldPath lookups work on. Done once per node, not once per metric.
+func toDoc(n *rcv1.RcNode) (map[string]interface{}, error) {
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(n)
+}
+
+// extract resolves m.Source / m.Selector against doc and coerces the result
+// to a float64.
+func extract(m rcv1.PolicyMetric, doc map[string]interface{}) (float64, error) {
+	sel := m.Selector
+	if sel == "" {
+		if legacy, ok := legacySelectors[m.Key]; ok && m.Source != rcv1.ValueFromFieldPath {
+			sel = legacy
+		} else {
+			sel = m.Key
+		}
+	}
+
+	var (
+		v   interface{}
+		err error
+	)
+	switch m.Source {
+	case "", rcv1.ValueFromJSONPath:
+		v, err = lookupJSONPath(sel, doc)
+	case rcv1.ValueFromFieldPath:
+		v, err = lookupFieldPath(sel, doc)
+	default:
+		return 0, fmt.Errorf("metric %q: unknown source %q", m.Key, m.Source)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("metric %q: %w", m.Key, err)
+	}
+
+	f, err := toFloat(v)
+	if err != nil {
+		return 0, fmt.Errorf("metric %q (%s): %w", m.Key, sel, err)
+	}
+	return f, nil
+}
+
+/* ------------------------------- JSONPath --------------------------------- */
+
+// lookupJSONPath accepts both the bare "$.spec.x" form used in policies and
+// the kubectl "{.spec.x}" template form.
+func lookupJSONPath(expr string, doc interface{}) (interface{}, error) {
+	tpl := strings.TrimSpace(expr)
+	if !strings.HasPrefix(tpl, "{") {
+		if !strings.HasPrefix(tpl, "$") && !strings.HasPrefix(tpl, ".") {
+			tpl = "." + tpl
+		}
+		tpl = "{" + tpl + "}"
+	}
+
+	jp := jsonpath.New("metric")
+	if err := jp.Parse(tpl); err != nil {
+		return nil, fmt.Errorf("bad jsonPath %q: %w", expr, err)
+	}
+	results, err := jp.FindResults(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var vals []interface{}
+	for _, set := range results {
+		for _, rv := range set {
+			if rv.IsValid() && rv.CanInterface() {
+				vals = append(vals, rv.Interface())
+			}
+		}
+	}
+	switch len(vals) {
+	case 0:
+		return nil, fmt.Errorf("jsonPath %q matched nothing", expr)
+	case 1:
+		return vals[0], nil
+	default:
+		return nil, fmt.Errorf("jsonPath %q matched %d values, want 1", expr, len(vals))
+	}
+}
+
+// LookupNumber evaluates a JSONPath against an arbitrary decoded JSON
+// document and coerces the single match to a float64 (same rules as
+// metric selectors). Used by the external-feed fetcher.
+func LookupNumber(expr string, doc interface{}) (float64, error) {
+	v, err := lookupJSONPath(expr, doc)
+	if err != nil {
+		return 0, err
+	}
+	return toFloat(v)
+}
+
+/* ------------------------------- fieldPath -------------------------------- */
+
+// lookupFieldPath walks a downward-API style path:
+//
+//	metadata.name
+//	metadata.labels['topology.kubernetes.io/zone']
+//	spec.cpu.cores
+func lookupFieldPath(path string, doc map[string]interface{}) (interface{}, error) {
+	segs, err := splitFieldPath(path)
+	if err != nil {
+		return nil, err
+	}
+	var cur interface{} = doc
+	for _, s := range segs {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("fieldPath %q: %q is not an object", path, s)
+		}
+		if cur, ok = m[s]; !ok {
+			return nil, fmt.Errorf("fieldPath %q: %q not found", path, s)
+		}
+	}
+	return cur, nil
+}
+
+func splitFieldPath(path string) ([]string, error) {
+	var segs []string
+	rest := strings.TrimSpace(path)
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return nil, fmt.Errorf("fieldPath %q: unterminated [' ']", path)
+			}
+			segs = append(segs, rest[2:end])
+			rest = rest[end+2:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			segs = append(segs, rest[:end])
+			rest = rest[end:]
+		}
+	}
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("empty fieldPath")
+	}
+	return segs, nil
+}
+
+/* --------------------------- numeric coercion ----------------------------- */
+
+// toFloat coerces whatever the lookup produced:
+//   - numbers as-is
+//   - booleans → 1 / 0
+//   - strings  → float, Kubernetes quantity ("4Gi", "500m") or "true"/"false"
+//   - lists / maps → number of elements
+func toFloat(v interface{}) (float64, error) {
+	switch x := v.(type) {
+	case nil:
+		return 0, fmt.Errorf("value is null")
+	case float64:
+		return x, nil
+	case float32:
+		return float64(x), nil
+	case int:
+		return float64(x), nil
+	case int32:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	case bool:
+		if x {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		return parseNumeric(x)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(rv.Len()), nil
+	}
+	return 0, fmt.Errorf("cannot use %T as a number", v)
+}
+
+func parseNumeric(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	if b, err := strconv.ParseBool(s); err == nil {
+		return toFloat(b)
+	}
+	if q, err := resource.ParseQuantity(s); err == nil {
+		return q.AsApproximateFloat64(), nil
+	}
+	// not quoted: the string may come from a Secret (external feeds)
+	return 0, fmt.Errorf("cannot parse a %d-character string as a number", len(s))
+}
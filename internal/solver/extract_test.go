@@ -0,0 +1,68 @@
+package solver
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+func sampleNode() *rcv1.RcNode {
+	return &rcv1.RcNode{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "edge-1",
+			Labels: map[string]string{
+				"topology.kubernetes.io/zone": "3",
+				"recluster.io/ssd":            "true",
+				"recluster.io/mem":            "4Gi",
+			},
+		},
+		Spec: rcv1.RcNodeSpec{
+			CPU:         rcv1.RcNodeCPUSpec{Cores: 8, Flags: []string{"avx2", "sse4_2"}},
+			Memory:      8 << 30,
+			BootSeconds: 45,
+		},
+		Status: rcv1.RcNodeStatus{PredictedPowerWatts: 120},
+	}
+}
+
+var _ = Describe("metric extraction", func() {
+	var doc map[string]interface{}
+
+	BeforeEach(func() {
+		var err error
+		doc, err = toDoc(sampleNode())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	DescribeTable("resolves selectors to numbers",
+		func(m rcv1.PolicyMetric, want float64) {
+			Expect(extract(m, doc)).To(Equal(want))
+		},
+		Entry("jsonPath on status", rcv1.PolicyMetric{Key: "watts", Selector: "$.status.predictedPowerWatts"}, 120.0),
+		Entry("jsonPath on spec", rcv1.PolicyMetric{Key: "cores", Selector: "$.spec.cpu.cores"}, 8.0),
+		Entry("kubectl template form", rcv1.PolicyMetric{Key: "boot", Selector: "{.spec.bootSeconds}"}, 45.0),
+		Entry("legacy key without selector", rcv1.PolicyMetric{Key: "ram"}, float64(8<<30)),
+		Entry("list counts its elements", rcv1.PolicyMetric{Key: "flags", Selector: "$.spec.cpu.flags"}, 2.0),
+		Entry("fieldPath label as number", rcv1.PolicyMetric{Key: "zone", Source: rcv1.ValueFromFieldPath,
+			Selector: "metadata.labels['topology.kubernetes.io/zone']"}, 3.0),
+		Entry("fieldPath label as boolean", rcv1.PolicyMetric{Key: "ssd", Source: rcv1.ValueFromFieldPath,
+			Selector: "metadata.labels['recluster.io/ssd']"}, 1.0),
+		Entry("fieldPath label as quantity", rcv1.PolicyMetric{Key: "mem", Source: rcv1.ValueFromFieldPath,
+			Selector: "metadata.labels['recluster.io/mem']"}, float64(4<<30)),
+	)
+
+	It("fails on missing fields", func() {
+		_, err := extract(rcv1.PolicyMetric{Key: "x", Selector: "$.status.nope"}, doc)
+		Expect(err).To(HaveOccurred())
+		_, err = extract(rcv1.PolicyMetric{Key: "x", Source: rcv1.ValueFromFieldPath,
+			Selector: "metadata.labels['missing']"}, doc)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails on non-numeric strings", func() {
+		_, err := extract(rcv1.PolicyMetric{Key: "name", Selector: "$.metadata.name"}, doc)
+		Expect(err).To(HaveOccurred())
+	})
+})
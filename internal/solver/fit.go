@@ -0,0 +1,164 @@
+package solver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                               resource fit                                 */
+/* -------------------------------------------------------------------------- */
+//
+// Before any policy is looked at, a node must have room for the pod: what
+// the pod requests (PodRequests) plus what the pods already assigned to the
+// node request (Usage) may not exceed the node's Capacity. Resources an
+// RcNode does not describe (0 cores, no memoryBytes, no storages) are not
+// checked; extended resources it does not list have a capacity of 0.
+
+// PodRequests is what pod asks the scheduler for, computed like the
+// kube-scheduler does: the sum of its containers (and sidecars) or the
+// largest init container if that is more, plus the pod overhead. A nil
+// pod requests nothing.
+func PodRequests(pod *corev1.Pod) corev1.ResourceList {
+	reqs := corev1.ResourceList{}
+	if pod == nil {
+		return reqs
+	}
+	for _, c := range pod.Spec.Containers {
+		addList(reqs, c.Resources.Requests)
+	}
+
+	// init containers run one after the other, next to the sidecars
+	// (restartable init containers) started before them
+	sidecars, initPeak := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, c := range pod.Spec.InitContainers {
+		if c.RestartPolicy != nil && *c.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			addList(sidecars, c.Resources.Requests)
+			maxList(initPeak, sidecars)
+			continue
+		}
+		running := sidecars.DeepCopy()
+		addList(running, c.Resources.Requests)
+		maxList(initPeak, running)
+	}
+	addList(reqs, sidecars)
+	maxList(reqs, initPeak)
+
+	addList(reqs, pod.Spec.Overhead)
+	return reqs
+}
+
+// Capacity is what rc offers: CPU cores, memory, the summed size of its
+// storages as ephemeral storage, and its extended resources.
+func Capacity(rc *rcv1.RcNode) corev1.ResourceList {
+	c := corev1.ResourceList{}
+	if rc.Spec.CPU.Cores > 0 {
+		c[corev1.ResourceCPU] = *resource.NewMilliQuantity(int64(rc.Spec.CPU.Cores)*1000, resource.DecimalSI)
+	}
+	if rc.Spec.Memory > 0 {
+		c[corev1.ResourceMemory] = *resource.NewQuantity(rc.Spec.Memory, resource.BinarySI)
+	}
+	var storage int64
+	for _, s := range rc.Spec.Storage {
+		storage += s.Size
+	}
+	if storage > 0 {
+		c[corev1.ResourceEphemeralStorage] = *resource.NewQuantity(storage, resource.BinarySI)
+	}
+	for name, q := range rc.Spec.ExtendedResources {
+		c[name] = q.DeepCopy()
+	}
+	return c
+}
+
+// Usage is what the pods assigned to each RcNode request, by RcNode name.
+type Usage map[string]corev1.ResourceList
+
+// Add counts pod against node.
+func (u Usage) Add(node string, pod *corev1.Pod) {
+	if u[node] == nil {
+		u[node] = corev1.ResourceList{}
+	}
+	addList(u[node], PodRequests(pod))
+}
+
+// Shortfall is one resource a node lacks for a pod.
+type Shortfall struct {
+	Resource  corev1.ResourceName
+	Requested resource.Quantity // by the pod
+	Free      resource.Quantity // capacity minus what assigned pods request
+	Capacity  resource.Quantity
+}
+
+func (s Shortfall) String() string {
+	return fmt.Sprintf("%s: requests %s, %s free of %s",
+		s.Resource, s.Requested.String(), s.Free.String(), s.Capacity.String())
+}
+
+// Shortfalls explains why a node does not fit.
+type Shortfalls []Shortfall
+
+func (s Shortfalls) String() string {
+	parts := make([]string, len(s))
+	for i, sf := range s {
+		parts[i] = sf.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// fit returns the resources of n that cannot hold req on top of used (nil
+// when the pod fits), sorted by resource name.
+func fit(n *rcv1.RcNode, req, used corev1.ResourceList) Shortfalls {
+	capacity := Capacity(n)
+	var out Shortfalls
+	for name, want := range req {
+		if want.IsZero() {
+			continue
+		}
+		c, declared := capacity[name]
+		if !declared && isNative(name) {
+			continue // not described: not checked
+		}
+		free := c.DeepCopy()
+		if u, ok := used[name]; ok {
+			free.Sub(u)
+		}
+		if want.Cmp(free) > 0 {
+			out = append(out, Shortfall{Resource: name, Requested: want, Free: free, Capacity: c})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Resource < out[j].Resource })
+	return out
+}
+
+// isNative reports whether name is one of the resources RcNodeSpec
+// describes with its own fields.
+func isNative(name corev1.ResourceName) bool {
+	switch name {
+	case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage:
+		return true
+	}
+	return false
+}
+
+func addList(dst, src corev1.ResourceList) {
+	for name, q := range src {
+		v := dst[name]
+		v.Add(q)
+		dst[name] = v
+	}
+}
+
+func maxList(dst, src corev1.ResourceList) {
+	for name, q := range src {
+		if v, ok := dst[name]; !ok || q.Cmp(v) > 0 {
+			dst[name] = q.DeepCopy()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package solver
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+var _ = Describe("resource fit", func() {
+	requests := func(kv ...string) corev1.ResourceRequirements {
+		r := corev1.ResourceList{}
+		for i := 0; i < len(kv); i += 2 {
+			r[corev1.ResourceName(kv[i])] = resource.MustParse(kv[i+1])
+		}
+		return corev1.ResourceRequirements{Requests: r}
+	}
+	pod := func(cpu, mem string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "p"},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{
+				{Name: "app", Resources: requests("cpu", cpu, "memory", mem)},
+			}},
+		}
+	}
+	node := func(name string, cores int, memGi int64) rcv1.RcNode {
+		return rcv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: rcv1.RcNodeSpec{
+				CPU:    rcv1.RcNodeCPUSpec{Cores: cores},
+				Memory: memGi << 30,
+			},
+		}
+	}
+	pol := &rcv1.RcPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "small-first"},
+		Spec:       rcv1.RcPolicySpec{Metrics: []rcv1.PolicyMetric{{Key: "cpu", Weight: 1}}},
+	}
+
+	It("sums containers, sidecars and the largest init container like the scheduler", func() {
+		p := pod("500m", "1Gi")
+		p.Spec.InitContainers = []corev1.Container{
+			{Name: "migrate", Resources: requests("cpu", "2", "memory", "256Mi")},
+			{Name: "proxy", RestartPolicy: ptr.To(corev1.ContainerRestartPolicyAlways),
+				Resources: requests("cpu", "100m", "memory", "64Mi")},
+			{Name: "warmup", Resources: requests("cpu", "1")},
+		}
+		p.Spec.Overhead = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("32Mi")}
+
+		r := PodRequests(p)
+		Expect(r.Cpu().String()).To(Equal("2"))         // migrate beats 500m+100m
+		Expect(r.Memory().String()).To(Equal("1120Mi")) // 1Gi + 64Mi sidecar + 32Mi overhead
+		Expect(PodRequests(&corev1.Pod{})).To(BeEmpty())
+	})
+
+	It("rejects nodes too small for the pod and explains the shortfall", func() {
+		nodes := []rcv1.RcNode{node("tiny", 2, 4), node("big", 16, 64)}
+
+		d, err := Decide(pol, pod("8", "2Gi"), nodes, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Node.Name).To(Equal("big"))
+		Expect(d.Candidates[1].Node.Name).To(Equal("tiny"))
+		Expect(d.Candidates[1].Unfit.String()).To(Equal("cpu: requests 8, 2 free of 2"))
+
+		p, err := PickBest(pol, pod("32", "2Gi"), nodes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).To(BeNil())
+	})
+
+	It("subtracts what the pods already assigned to a node request", func() {
+		nodes := []rcv1.RcNode{node("small", 4, 8), node("big", 16, 64)}
+		used := Usage{}
+		used.Add("small", pod("3", "7Gi"))
+
+		d, err := Decide(pol, pod("2", "2Gi"), nodes, used, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Node.Name).To(Equal("big"))
+		Expect(d.Candidates[1].Unfit).To(HaveLen(2))
+		Expect(d.Candidates[1].Unfit.String()).To(Equal(
+			"cpu: requests 2, 1 free of 4; memory: requests 2Gi, 1Gi free of 8Gi"))
+	})
+
+	It("needs extended resources to be listed, other resources only when described", func() {
+		gpu := node("gpu", 8, 0)
+		gpu.Spec.ExtendedResources = corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("1")}
+		plain := node("plain", 0, 0)
+
+		p := pod("1", "64Gi")
+		p.Spec.Containers[0].Resources.Requests["nvidia.com/gpu"] = resource.MustParse("1")
+
+		d, err := Decide(pol, p, []rcv1.RcNode{plain, gpu}, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Node.Name).To(Equal("gpu")) // undescribed memory is not checked
+		Expect(d.Candidates[1].Unfit.String()).To(Equal("nvidia.com/gpu: requests 1, 0 free of 0"))
+	})
+})
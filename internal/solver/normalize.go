@@ -0,0 +1,98 @@
+package solver
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                           metric normalization                             */
+/* -------------------------------------------------------------------------- */
+//
+// A metric's values over the feasible candidates of one pod are rescaled
+// before weighting (PolicyMetric.Normalize), so that ram in bytes does not
+// drown boot in seconds. Infinite values (failed or NaN metrics) are left
+// out of the statistics and stay infinite.
+
+// checkNormalization rejects modes the solver does not know.
+func checkNormalization(n rcv1.Normalization) error {
+	switch n {
+	case "", rcv1.NormalizeNone, rcv1.NormalizeMinMax, rcv1.NormalizeZScore,
+		rcv1.NormalizeRank, rcv1.NormalizeLog:
+		return nil
+	}
+	return fmt.Errorf("unknown normalization %q", n)
+}
+
+// normalize rescales vals in place according to mode.
+func normalize(mode rcv1.Normalization, vals []float64) {
+	var finite []float64
+	for _, v := range vals {
+		if !math.IsInf(v, 0) {
+			finite = append(finite, v)
+		}
+	}
+	apply := func(f func(float64) float64) {
+		for i, v := range vals {
+			if !math.IsInf(v, 0) {
+				vals[i] = f(v)
+			}
+		}
+	}
+
+	switch mode {
+	case rcv1.NormalizeMinMax:
+		if len(finite) == 0 {
+			return
+		}
+		lo, hi := slices.Min(finite), slices.Max(finite)
+		apply(func(v float64) float64 {
+			if hi == lo {
+				return 0
+			}
+			return (v - lo) / (hi - lo)
+		})
+
+	case rcv1.NormalizeZScore:
+		if len(finite) == 0 {
+			return
+		}
+		var mean, sq float64
+		for _, v := range finite {
+			mean += v
+		}
+		mean /= float64(len(finite))
+		for _, v := range finite {
+			sq += (v - mean) * (v - mean)
+		}
+		sd := math.Sqrt(sq / float64(len(finite)))
+		apply(func(v float64) float64 {
+			if sd == 0 {
+				return 0
+			}
+			return (v - mean) / sd
+		})
+
+	case rcv1.NormalizeRank:
+		// ties share their average position
+		sorted := append([]float64(nil), finite...)
+		sort.Float64s(sorted)
+		apply(func(v float64) float64 {
+			if len(sorted) < 2 {
+				return 0
+			}
+			lo := sort.SearchFloat64s(sorted, v)
+			hi := sort.Search(len(sorted), func(i int) bool { return sorted[i] > v })
+			return (float64(lo+hi-1) / 2) / float64(len(sorted)-1)
+		})
+
+	case rcv1.NormalizeLog:
+		apply(func(v float64) float64 {
+			return math.Copysign(math.Log1p(math.Abs(v)), v)
+		})
+	}
+}
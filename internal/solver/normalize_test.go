@@ -0,0 +1,74 @@
+package solver
+
+import (
+	"math"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+var _ = Describe("metric normalization", func() {
+	norm := func(mode rcv1.Normalization, vals ...float64) []float64 {
+		normalize(mode, vals)
+		return vals
+	}
+
+	It("rescales values over the candidates", func() {
+		Expect(norm(rcv1.NormalizeNone, 10, 20)).To(Equal([]float64{10, 20}))
+		Expect(norm(rcv1.NormalizeMinMax, 10, 30, 20)).To(Equal([]float64{0, 1, 0.5}))
+		Expect(norm(rcv1.NormalizeMinMax, 7, 7)).To(Equal([]float64{0, 0}))
+		Expect(norm(rcv1.NormalizeZScore, 2, 4, 4, 4, 5, 5, 7, 9)).To(Equal(
+			[]float64{-1.5, -0.5, -0.5, -0.5, 0, 0, 1, 2}))
+		Expect(norm(rcv1.NormalizeRank, 30, 10, 20, 20, 40)).To(Equal(
+			[]float64{0.75, 0, 0.375, 0.375, 1}))
+		Expect(norm(rcv1.NormalizeRank, 5)).To(Equal([]float64{0}))
+		Expect(norm(rcv1.NormalizeLog, 0, math.E-1, -(math.E - 1))).To(Equal([]float64{0, 1, -1}))
+	})
+
+	It("keeps infinite values out of the statistics", func() {
+		inf := math.Inf(1)
+		Expect(norm(rcv1.NormalizeMinMax, 0, inf, 10)).To(Equal([]float64{0, inf, 1}))
+		Expect(norm(rcv1.NormalizeRank, inf)).To(Equal([]float64{inf}))
+	})
+
+	It("lets weights trade metrics in different units", func() {
+		node := func(name string, memGi int64, boot int) rcv1.RcNode {
+			return rcv1.RcNode{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Spec:       rcv1.RcNodeSpec{Memory: memGi << 30, BootSeconds: boot},
+			}
+		}
+		nodes := []rcv1.RcNode{node("fat-slow", 256, 300), node("lean-fast", 64, 20)}
+		pol := func(mode rcv1.Normalization) *rcv1.RcPolicy {
+			return &rcv1.RcPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "p"},
+				Spec: rcv1.RcPolicySpec{Metrics: []rcv1.PolicyMetric{
+					{Key: "ram", Weight: -1, Normalize: mode}, // prefer memory …
+					{Key: "boot", Weight: 2, Normalize: mode}, // … but boot time twice as much
+				}},
+			}
+		}
+
+		d, err := Decide(pol(""), nil, nodes, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Node.Name).To(Equal("fat-slow")) // bytes drown seconds
+
+		d, err = Decide(pol(rcv1.NormalizeMinMax), nil, nodes, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Node.Name).To(Equal("lean-fast"))
+		Expect(d.Detail).To(Equal(map[string]float64{"ram": 0, "boot": 0}))
+		Expect(d.Candidates[1].String()).To(Equal(
+			"fat-slow score=1 [ram=2.74877906944e+11→2.74877906944e+11→1×-1=-1 boot=300→300→1×2=2]"))
+	})
+
+	It("reports unknown modes when compiling", func() {
+		cp := Compile(&rcv1.RcPolicy{Spec: rcv1.RcPolicySpec{Metrics: []rcv1.PolicyMetric{
+			{Key: "ram", Weight: 1, Normalize: "percentile"},
+		}}})
+		Expect(cp.Errors).To(ConsistOf(`metrics[0] (ram).normalize: unknown normalization "percentile"`))
+	})
+})
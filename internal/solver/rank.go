@@ -0,0 +1,181 @@
+package solver
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sort"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                              ranking modes                                 */
+/* -------------------------------------------------------------------------- */
+//
+// Once every feasible candidate has its metric values, RcPolicySpec.Mode
+// orders them:
+//
+//	weightedSum    by Score
+//	lexicographic  metric by metric: the nodes within Tolerance of the best
+//	               value stay in, the next metric decides among them
+//	pareto         by successive Pareto fronts (Candidate.Front), each
+//	               ordered by the TieBreaker
+//
+// The last two compare the normalized values, oriented by the sign of the
+// effective weight, and the soft-constraint Penalty: lexicographic looks
+// at it before the first metric, pareto as one more objective. Whatever is
+// still tied goes by node name, so the same cluster always yields the same
+// placement.
+
+// checkRanking rejects modes the solver does not know.
+func checkRanking(spec *rcv1.RcPolicySpec) error {
+	switch spec.Mode {
+	case "", rcv1.RankWeightedSum, rcv1.RankLexicographic, rcv1.RankPareto:
+	default:
+		return fmt.Errorf("unknown mode %q", spec.Mode)
+	}
+	switch spec.TieBreaker {
+	case "", rcv1.RankWeightedSum, rcv1.RankLexicographic:
+	default:
+		return fmt.Errorf("unknown tieBreaker %q", spec.TieBreaker)
+	}
+	return nil
+}
+
+// rank orders the feasible candidates in place according to spec.Mode.
+func rank(spec *rcv1.RcPolicySpec, cands []Candidate) {
+	switch spec.Mode {
+	case rcv1.RankLexicographic:
+		lexicographic(spec, cands)
+	case rcv1.RankPareto:
+		pareto(spec, cands)
+	default:
+		byScore(cands)
+	}
+}
+
+func byScore(cands []Candidate) {
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].Score != cands[j].Score {
+			return cands[i].Score < cands[j].Score
+		}
+		return cands[i].Node.Name < cands[j].Node.Name
+	})
+}
+
+func byName(cands []Candidate) {
+	sort.SliceStable(cands, func(i, j int) bool { return cands[i].Node.Name < cands[j].Node.Name })
+}
+
+// key is metric j of c oriented so that lower is better; false when the
+// metric does not count (zero weight).
+func key(c *Candidate, j int) (float64, bool) {
+	m := c.Metrics[j]
+	switch {
+	case m.Weight > 0:
+		return m.Normalized, true
+	case m.Weight < 0:
+		return -m.Normalized, true
+	}
+	return 0, false
+}
+
+// lexicographic orders cands by picking the lexicographic winner of what
+// is left over and over: tolerance bands are not transitive, so there is
+// no comparison to sort with.
+func lexicographic(spec *rcv1.RcPolicySpec, cands []Candidate) {
+	rest := slices.Clone(cands)
+	for n := range cands {
+		i := lexBest(spec, rest)
+		cands[n] = rest[i]
+		rest = slices.Delete(rest, i, i+1)
+	}
+}
+
+func lexBest(spec *rcv1.RcPolicySpec, cands []Candidate) int {
+	var tied []int
+	least := slices.MinFunc(cands, func(a, b Candidate) int { return cmp.Compare(a.Penalty, b.Penalty) }).Penalty
+	for i := range cands {
+		if cands[i].Penalty == least {
+			tied = append(tied, i)
+		}
+	}
+	for j, m := range spec.Metrics {
+		if len(tied) == 1 {
+			break
+		}
+		if _, ok := key(&cands[tied[0]], j); !ok {
+			continue
+		}
+		best, _ := key(&cands[tied[0]], j)
+		for _, i := range tied[1:] {
+			v, _ := key(&cands[i], j)
+			best = min(best, v)
+		}
+		next := tied[:0]
+		for _, i := range tied {
+			if v, _ := key(&cands[i], j); v <= best+m.Tolerance {
+				next = append(next, i)
+			}
+		}
+		tied = next
+	}
+	return slices.MinFunc(tied, func(a, b int) int {
+		switch {
+		case cands[a].Node.Name < cands[b].Node.Name:
+			return -1
+		case cands[a].Node.Name > cands[b].Node.Name:
+			return 1
+		}
+		return 0
+	})
+}
+
+// pareto orders cands front by front: first the nodes no other node
+// dominates, then those only the first front dominates, and so on.
+func pareto(spec *rcv1.RcPolicySpec, cands []Candidate) {
+	rest := slices.Clone(cands)
+	out := cands[:0]
+	for front := 1; len(rest) > 0; front++ {
+		var cur, dominated []Candidate
+		for i := range rest {
+			if slices.ContainsFunc(rest, func(o Candidate) bool { return dominates(&o, &rest[i]) }) {
+				dominated = append(dominated, rest[i])
+				continue
+			}
+			rest[i].Front = front
+			cur = append(cur, rest[i])
+		}
+		if spec.TieBreaker == rcv1.RankLexicographic {
+			lexicographic(spec, cur)
+		} else {
+			byScore(cur)
+		}
+		out = append(out, cur...)
+		rest = dominated
+	}
+}
+
+// dominates reports whether a is at least as good as b on every metric
+// and the penalty and better on one of them.
+func dominates(a, b *Candidate) bool {
+	if a.Penalty > b.Penalty {
+		return false
+	}
+	better := a.Penalty < b.Penalty
+	for j := range a.Metrics {
+		va, ok := key(a, j)
+		if !ok {
+			continue
+		}
+		vb, _ := key(b, j)
+		if va > vb {
+			return false
+		}
+		if va < vb {
+			better = true
+		}
+	}
+	return better
+}
@@ -0,0 +1,88 @@
+package solver
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+var _ = Describe("ranking modes", func() {
+	// watts (minimised) and cores (maximised)
+	node := func(name string, watts, cores int) rcv1.RcNode {
+		return rcv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       rcv1.RcNodeSpec{CPU: rcv1.RcNodeCPUSpec{Cores: cores}},
+			Status:     rcv1.RcNodeStatus{PredictedPowerWatts: watts},
+		}
+	}
+	policy := func(mode rcv1.RankingMode, wattsTolerance float64) *rcv1.RcPolicy {
+		return &rcv1.RcPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "p"},
+			Spec: rcv1.RcPolicySpec{
+				Mode: mode,
+				Metrics: []rcv1.PolicyMetric{
+					{Key: "watts", Weight: 1, Selector: "$.status.predictedPowerWatts", Tolerance: wattsTolerance},
+					{Key: "cpu", Weight: -0.001},
+				},
+			},
+		}
+	}
+	names := func(pol *rcv1.RcPolicy, nodes []rcv1.RcNode) []string {
+		cands, err := Rank(pol, nil, nodes, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		var out []string
+		for _, c := range cands {
+			out = append(out, c.Node.Name)
+		}
+		return out
+	}
+	nodes := []rcv1.RcNode{
+		node("d", 100, 4), node("c", 105, 16), node("b", 100, 8), node("a", 300, 64), node("e", 100, 8),
+	}
+
+	It("breaks weighted-sum ties by node name", func() {
+		Expect(names(policy("", 0), nodes)).To(Equal([]string{"b", "e", "d", "c", "a"}))
+	})
+
+	It("orders metric by metric in lexicographic mode, honouring tolerance bands", func() {
+		Expect(names(policy(rcv1.RankLexicographic, 0), nodes)).To(Equal([]string{"b", "e", "d", "c", "a"}))
+		// 105 W is as good as 100 W: cores decide
+		Expect(names(policy(rcv1.RankLexicographic, 10), nodes)).To(Equal([]string{"c", "b", "e", "d", "a"}))
+	})
+
+	It("ranks Pareto fronts, ordered by the tie-breaker", func() {
+		pol := policy(rcv1.RankPareto, 0)
+		pol.Spec.Metrics[1].Weight = -10 // cores weigh more in the weighted sum
+		cands, err := Rank(pol, nil, nodes, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		fronts := map[string]int{}
+		for _, c := range cands {
+			fronts[c.Node.Name] = c.Front
+		}
+		// d is beaten by b on cores; nothing beats a on cores or b/e on watts
+		Expect(fronts).To(Equal(map[string]int{"a": 1, "b": 1, "c": 1, "e": 1, "d": 2}))
+		Expect(names(pol, nodes)).To(Equal([]string{"a", "c", "b", "e", "d"}))
+		Expect(cands[0].String()).To(HavePrefix("a front=1 score=-340 "))
+
+		pol.Spec.TieBreaker = rcv1.RankLexicographic
+		Expect(names(pol, nodes)).To(Equal([]string{"b", "e", "c", "a", "d"}))
+	})
+
+	It("reports unknown modes when compiling", func() {
+		pol := policy("fastest", -1)
+		pol.Spec.TieBreaker = rcv1.RankPareto
+		Expect(Compile(pol).Errors).To(ConsistOf(
+			`mode: unknown mode "fastest"`,
+			`metrics[0] (watts).tolerance: negative: -1`,
+		))
+		pol.Spec.Mode = rcv1.RankPareto
+		Expect(Compile(pol).Errors).To(ConsistOf(
+			`mode: unknown tieBreaker "pareto"`,
+			`metrics[0] (watts).tolerance: negative: -1`,
+		))
+	})
+})
@@ -4,55 +4,22 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"strings"
+	"time"
 
-	"github.com/google/cel-go/cel"
-	"github.com/google/cel-go/checker/decls"
+	corev1 "k8s.io/api/core/v1"
 
 	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
 )
 
-/* -------------------------------------------------------------------------- */
-/*                          shared CEL environment                            */
-/* -------------------------------------------------------------------------- */
-
-var env *cel.Env
-
-func init() {
-	// declare the variables our expressions may reference
-	declarations := cel.Declarations(
-		decls.NewVar("cpu", decls.Double),
-		decls.NewVar("ram", decls.Double),
-		decls.NewVar("boot", decls.Double),
-		decls.NewVar("x", decls.Double), // used only inside metric transforms
-	)
-
-	e, err := cel.NewEnv(declarations)
-	if err != nil {
-		panic(err)
-	}
-	env = e
-}
-
-/* -------------------------------------------------------------------------- */
-
-type candidate struct {
-	Node   *rcv1.RcNode
-	Score  float64
-	Detail map[string]float64
-}
-
 /* ------------------------ hard-constraint checker ------------------------- */
 
-func satisfies(n *rcv1.RcNode, expr string) (bool, error) {
-	ast, iss := env.Parse(expr)
-	if iss.Err() != nil {
-		return false, iss.Err()
-	}
-	prg, err := env.Program(ast)
-	if err != nil {
-		return false, err
-	}
-	out, _, err := prg.Eval(toVars(n))
+// satisfies evaluates one compiled hard or soft constraint; anything but a
+// boolean true rejects the node (hard) or costs it the penalty (soft). The
+// error is why the expression could not be evaluated on this node (missing
+// map key, division by zero, cost limit…).
+func satisfies(in evalInput, p program) (bool, error) {
+	out, _, err := p.prg.Eval(in.vars())
 	if err != nil {
 		return false, err
 	}
@@ -62,34 +29,33 @@ func satisfies(n *rcv1.RcNode, expr string) (bool, error) {
 
 /* -------------------------- metric + transform ---------------------------- */
 
-func metricValue(m rcv1.PolicyMetric, n *rcv1.RcNode) (float64, error) {
-	var raw float64
-	switch m.Key {
-	case "cpu":
-		raw = float64(n.Spec.CPU.Cores)
-	case "ram":
-		raw = float64(n.Spec.Memory)
-	case "boot":
-		raw = float64(n.Spec.BootSeconds)
-	default:
-		return 0, fmt.Errorf("unknown metric %q", m.Key)
-	}
-
-	if m.Transform == nil {
-		return raw, nil
+// metricValue fetches the metric described by m (see extract.go) from the
+// node document and runs the optional compiled transform on it, returning
+// both values. Transforms see the same variables as hard constraints plus
+// `x`.
+func metricValue(m rcv1.PolicyMetric, cp *CompiledPolicy, doc map[string]interface{}, in evalInput) (raw, val float64, err error) {
+	raw, err = extract(m, doc)
+	if err != nil {
+		return 0, 0, err
 	}
 
-	ast, iss := env.Parse(*m.Transform)
-	if iss.Err() != nil {
-		return 0, iss.Err()
+	t, ok := cp.transforms[m.Key]
+	if !ok {
+		return raw, raw, nil
 	}
-	prg, err := env.Program(ast)
+	vars := in.vars()
+	vars["x"] = raw
+	val, err = evalDouble(t, vars)
 	if err != nil {
-		return 0, err
+		return 0, 0, fmt.Errorf("metric %q transform: %w", m.Key, err)
 	}
-	out, _, err := prg.Eval(map[string]interface{}{"x": raw})
+	return raw, val, nil
+}
+
+func evalDouble(p program, vars map[string]interface{}) (float64, error) {
+	out, _, err := p.prg.Eval(vars)
 	if err != nil {
-		return 0, err
+		return 0, fmt.Errorf("%q: %w", p.expr, err)
 	}
 	v, err := out.ConvertToNative(reflect.TypeOf(float64(0)))
 	if err != nil {
@@ -100,58 +66,232 @@ func metricValue(m rcv1.PolicyMetric, n *rcv1.RcNode) (float64, error) {
 
 /* ----------------------------- public API --------------------------------- */
 
-// PickBest returns the RcNode with the lowest weighted-score that satisfies
-// *all* hard constraints in the supplied policy.
-func PickBest(pol *rcv1.RcPolicy, nodes []rcv1.RcNode) (*rcv1.RcNode, error) {
-	var best *candidate
+// MetricScore is how one metric scored a node.
+type MetricScore struct {
+	Key          string
+	Raw          float64 // fetched from the node
+	Value        float64 // after the metric's transform (Raw without one)
+	Normalized   float64 // Value rescaled over the feasible nodes (see PolicyMetric.Normalize)
+	Weight       float64 // effective weight (see Weights)
+	Contribution float64 // Normalized × Weight, what the score sums
+}
+
+// Candidate is one node as the solver saw it for a pod.
+type Candidate struct {
+	Node *rcv1.RcNode
+	// Score is the sum of the contributions plus Penalty; feasible nodes
+	// only.
+	Score   float64
+	Metrics []MetricScore // policy order; feasible nodes only
+	// Violated lists the soft constraints the node failed, Penalty what
+	// they cost it; feasible nodes only.
+	Violated []string
+	Penalty  float64
+	// Rejected lists the hard constraints the node failed, with the error
+	// for those that could not be evaluated on it.
+	Rejected []string
+	// Unfit lists the resources the node lacks for the pod.
+	Unfit Shortfalls
+	// Unscored lists why metrics could not be read from the node, e.g. a
+	// status field it has not reported yet.
+	Unscored []string
+	// Front is the Pareto front of the node (1 = not dominated) in pareto
+	// mode, 0 otherwise.
+	Front int
+}
+
+// Feasible reports whether the pod may go to the node.
+func (c Candidate) Feasible() bool {
+	return len(c.Rejected) == 0 && len(c.Unfit) == 0 && len(c.Unscored) == 0
+}
+
+func (c Candidate) String() string {
+	if !c.Feasible() {
+		var why []string
+		if len(c.Unfit) > 0 {
+			why = append(why, "unfit: "+c.Unfit.String())
+		}
+		if len(c.Rejected) > 0 {
+			why = append(why, fmt.Sprintf("rejected by %q", c.Rejected))
+		}
+		if len(c.Unscored) > 0 {
+			why = append(why, "unscored: "+strings.Join(c.Unscored, "; "))
+		}
+		return fmt.Sprintf("%s (%s)", c.Node.Name, strings.Join(why, ", "))
+	}
+	parts := make([]string, len(c.Metrics))
+	for i, m := range c.Metrics {
+		v := fmt.Sprintf("%g", m.Value)
+		if m.Normalized != m.Value {
+			v += fmt.Sprintf("→%g", m.Normalized)
+		}
+		parts[i] = fmt.Sprintf("%s=%g→%s×%g=%g", m.Key, m.Raw, v, m.Weight, m.Contribution)
+	}
+	if len(c.Violated) > 0 {
+		parts = append(parts, fmt.Sprintf("penalty=%g for %q", c.Penalty, c.Violated))
+	}
+	if c.Front > 0 {
+		return fmt.Sprintf("%s front=%d score=%g [%s]", c.Node.Name, c.Front, c.Score, strings.Join(parts, " "))
+	}
+	return fmt.Sprintf("%s score=%g [%s]", c.Node.Name, c.Score, strings.Join(parts, " "))
+}
+
+// Decision explains a placement: which node won, with which score, the
+// effective weights every metric was multiplied by and how every node
+// ranked.
+type Decision struct {
+	Node    *rcv1.RcNode // nil when no node fits, satisfies the hard constraints and exposes every metric
+	Score   float64
+	Detail  map[string]float64 // metric key → weighted contribution
+	Weights Weights
+	// Violated lists the soft constraints the chosen node failed.
+	Violated []string
+	// Candidates are all nodes, best first: the feasible ones as the
+	// policy's Mode ranks them, then the others by name.
+	Candidates []Candidate
+}
+
+// PickBest returns the RcNode the policy ranks first (lowest weighted-score
+// by default) among those that have room for the pod and satisfy *all*
+// hard constraints in the supplied policy.
+func PickBest(pol *rcv1.RcPolicy, pod *corev1.Pod, nodes []rcv1.RcNode) (*rcv1.RcNode, error) {
+	d, err := Decide(pol, pod, nodes, nil, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return d.Node, nil
+}
+
+// Rank returns every node as a Candidate for pod, best first (see
+// Decision.Candidates).
+func Rank(pol *rcv1.RcPolicy, pod *corev1.Pod, nodes []rcv1.RcNode, used Usage, now time.Time) ([]Candidate, error) {
+	d, err := Decide(pol, pod, nodes, used, now)
+	if err != nil {
+		return nil, err
+	}
+	return d.Candidates, nil
+}
+
+// Decide is PickBest with what the pods already assigned to the nodes
+// request (nil: nothing), an explicit clock and the full explanation.
+func Decide(pol *rcv1.RcPolicy, pod *corev1.Pod, nodes []rcv1.RcNode, used Usage, now time.Time) (*Decision, error) {
+	cp := Compile(pol)
+	if err := cp.Err(); err != nil {
+		return nil, fmt.Errorf("policy %s: %w", pol.Name, err)
+	}
+	weights := effectiveWeights(pol, cp, now)
 
-outer:
+	req := PodRequests(pod)
+	cands := make([]Candidate, 0, len(nodes))
 	for i := range nodes {
 		n := &nodes[i]
+		in := evalInput{Node: n, Pod: pod, Policy: pol}
+		cand := Candidate{Node: n}
 
-		// 1) hard constraints
-		for _, hc := range pol.Spec.HardConstraints {
-			ok, err := satisfies(n, hc.Expression)
-			if err != nil {
-				return nil, err
-			}
-			if !ok {
-				continue outer // reject this node
+		// 1) room for the pod
+		cand.Unfit = fit(n, req, used[n.Name])
+
+		// 2) hard constraints, all of them for the explanation; one that
+		//    cannot be evaluated on the node rejects it too
+		for _, hc := range cp.constraints {
+			ok, err := satisfies(in, hc)
+			switch {
+			case err != nil:
+				cand.Rejected = append(cand.Rejected, fmt.Sprintf("%s (error: %v)", hc.expr, err))
+			case !ok:
+				cand.Rejected = append(cand.Rejected, hc.expr)
 			}
 		}
+		if !cand.Feasible() {
+			cands = append(cands, cand)
+			continue
+		}
 
-		// 2) weighted score
-		cand := candidate{Node: n, Detail: map[string]float64{}}
+		// 3) metric values; a node that does not expose one cannot be
+		//    compared with the others
+		doc, err := toDoc(n)
+		if err != nil {
+			cand.Unscored = append(cand.Unscored, err.Error())
+			cands = append(cands, cand)
+			continue
+		}
 		for _, m := range pol.Spec.Metrics {
-			val, err := metricValue(m, n)
+			raw, val, err := metricValue(m, cp, doc, in)
 			if err != nil {
-				return nil, err
+				cand.Unscored = append(cand.Unscored, err.Error())
+				continue
 			}
 			// defensive: NaNs break comparisons
 			if math.IsNaN(val) {
 				val = math.Inf(1)
 			}
-			contrib := val * m.Weight
-			cand.Detail[m.Key] = contrib
-			cand.Score += contrib
+			cand.Metrics = append(cand.Metrics, MetricScore{Key: m.Key, Raw: raw, Value: val})
+		}
+		if !cand.Feasible() {
+			cand.Metrics = nil
+			cands = append(cands, cand)
+			continue
+		}
+
+		// 4) soft constraints; one that cannot be evaluated counts as violated
+		for i, sc := range cp.soft {
+			ok, err := satisfies(in, sc)
+			switch {
+			case err != nil:
+				cand.Violated = append(cand.Violated, fmt.Sprintf("%s (error: %v)", sc.expr, err))
+			case !ok:
+				cand.Violated = append(cand.Violated, sc.expr)
+			default:
+				continue
+			}
+			cand.Penalty += pol.Spec.SoftConstraints[i].Penalty
 		}
+		cand.Score = cand.Penalty
+		cands = append(cands, cand)
+	}
 
-		if best == nil || cand.Score < best.Score {
-			best = &cand
+	// 5) normalization over the feasible nodes, then the weighted sum
+	var feasible []int
+	for i := range cands {
+		if cands[i].Feasible() {
+			feasible = append(feasible, i)
 		}
 	}
-	if best == nil {
-		return nil, nil
+	vals := make([]float64, len(feasible))
+	for j, m := range pol.Spec.Metrics {
+		for k, i := range feasible {
+			vals[k] = cands[i].Metrics[j].Value
+		}
+		normalize(m.Normalize, vals)
+		w := weights.Effective[m.Key]
+		for k, i := range feasible {
+			ms := &cands[i].Metrics[j]
+			ms.Normalized, ms.Weight, ms.Contribution = vals[k], w, vals[k]*w
+			cands[i].Score += ms.Contribution
+		}
 	}
-	return best.Node, nil
-}
 
-/* ------------------------------- helpers ---------------------------------- */
+	// 6) ranking
+	var ok, rejected []Candidate
+	for _, c := range cands {
+		if c.Feasible() {
+			ok = append(ok, c)
+		} else {
+			rejected = append(rejected, c)
+		}
+	}
+	rank(&pol.Spec, ok)
+	byName(rejected)
+	cands = append(ok, rejected...)
 
-func toVars(n *rcv1.RcNode) map[string]interface{} {
-	return map[string]interface{}{
-		"cpu":  float64(n.Spec.CPU.Cores),
-		"ram":  float64(n.Spec.Memory),
-		"boot": float64(n.Spec.BootSeconds),
+	d := &Decision{Weights: weights, Candidates: cands}
+	if len(cands) > 0 && cands[0].Feasible() {
+		best := cands[0]
+		d.Node, d.Score, d.Violated = best.Node, best.Score, best.Violated
+		d.Detail = make(map[string]float64, len(best.Metrics))
+		for _, m := range best.Metrics {
+			d.Detail[m.Key] = m.Contribution
+		}
 	}
+	return d, nil
 }
@@ -0,0 +1,162 @@
+package solver
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+var _ = Describe("ranking", func() {
+	node := func(name string, cores, boot int) rcv1.RcNode {
+		return rcv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       rcv1.RcNodeSpec{CPU: rcv1.RcNodeCPUSpec{Cores: cores}, BootSeconds: boot},
+		}
+	}
+	halve := "x / 2"
+	pol := &rcv1.RcPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "fast-boot"},
+		Spec: rcv1.RcPolicySpec{
+			Metrics: []rcv1.PolicyMetric{
+				{Key: "boot", Weight: 1, Transform: &halve},
+				{Key: "cpu", Weight: -2},
+			},
+			HardConstraints: []rcv1.PolicyConstraint{
+				{Expression: "spec.cpu.cores >= 4"},
+				{Expression: "spec.bootSeconds < 100"},
+			},
+		},
+	}
+	nodes := []rcv1.RcNode{
+		node("slow", 8, 120), node("small", 2, 10), node("a", 4, 40), node("b", 8, 30),
+	}
+
+	It("returns every node, feasible ones by score, with the breakdown", func() {
+		cands, err := Rank(pol, nil, nodes, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cands).To(HaveLen(4))
+
+		names := []string{}
+		for _, c := range cands {
+			names = append(names, c.Node.Name)
+		}
+		Expect(names).To(Equal([]string{"b", "a", "slow", "small"}))
+
+		b := cands[0]
+		Expect(b.Feasible()).To(BeTrue())
+		Expect(b.Metrics).To(Equal([]MetricScore{
+			{Key: "boot", Raw: 30, Value: 15, Normalized: 15, Weight: 1, Contribution: 15},
+			{Key: "cpu", Raw: 8, Value: 8, Normalized: 8, Weight: -2, Contribution: -16},
+		}))
+		Expect(b.Score).To(Equal(-1.0))
+		Expect(b.String()).To(Equal("b score=-1 [boot=30→15×1=15 cpu=8→8×-2=-16]"))
+
+		Expect(cands[2].Rejected).To(Equal([]string{"spec.bootSeconds < 100"}))
+		Expect(cands[2].Metrics).To(BeEmpty())
+		Expect(cands[3].Rejected).To(Equal([]string{"spec.cpu.cores >= 4"}))
+		Expect(cands[3].String()).To(Equal(`small (rejected by ["spec.cpu.cores >= 4"])`))
+	})
+
+	It("keeps the winner and its contributions on the decision", func() {
+		d, err := Decide(pol, nil, nodes, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Node.Name).To(Equal("b"))
+		Expect(d.Score).To(Equal(-1.0))
+		Expect(d.Detail).To(Equal(map[string]float64{"boot": 15, "cpu": -16}))
+
+		d, err = Decide(pol, nil, nodes[:2], nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Node).To(BeNil())
+		Expect(d.Candidates).To(HaveLen(2))
+	})
+})
+
+var _ = Describe("nodes missing a metric", func() {
+	pol := &rcv1.RcPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "low-power"},
+		Spec: rcv1.RcPolicySpec{Metrics: []rcv1.PolicyMetric{
+			{Key: "watts", Weight: 1, Selector: "$.status.predictedPowerWatts"},
+		}},
+	}
+	node := func(name string, watts int) rcv1.RcNode {
+		return rcv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     rcv1.RcNodeStatus{PredictedPowerWatts: watts},
+		}
+	}
+
+	It("are left out with the reason while the others are ranked", func() {
+		// 0 W is omitted from the status: not reported yet
+		nodes := []rcv1.RcNode{node("new", 0), node("busy", 300), node("idle", 80)}
+
+		d, err := Decide(pol, nil, nodes, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Node.Name).To(Equal("idle"))
+		Expect(d.Candidates).To(HaveLen(3))
+
+		unscored := d.Candidates[2]
+		Expect(unscored.Node.Name).To(Equal("new"))
+		Expect(unscored.Feasible()).To(BeFalse())
+		Expect(unscored.Metrics).To(BeEmpty())
+		Expect(unscored.Unscored).To(Equal([]string{
+			`metric "watts": predictedPowerWatts is not found`,
+		}))
+		Expect(unscored.String()).To(Equal(
+			`new (unscored: metric "watts": predictedPowerWatts is not found)`))
+	})
+
+	It("leave no node when none exposes the metric", func() {
+		d, err := Decide(pol, nil, []rcv1.RcNode{node("new", 0)}, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Node).To(BeNil())
+	})
+
+	It("include nodes a transform fails on", func() {
+		p := pol.DeepCopy()
+		p.Spec.Metrics[0].Transform = ptr.To("x / double(spec.cpu.cores / spec.cpu.cores)")
+		cands, err := Rank(p, nil, []rcv1.RcNode{node("a", 80)}, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cands[0].Feasible()).To(BeFalse())
+		Expect(cands[0].Unscored[0]).To(HavePrefix(`metric "watts" transform: `))
+	})
+})
+
+var _ = Describe("hard constraints failing at runtime", func() {
+	It("reject only the node they fail on", func() {
+		pol := &rcv1.RcPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "zoned"},
+			Spec: rcv1.RcPolicySpec{
+				Metrics: []rcv1.PolicyMetric{{Key: "cpu", Weight: 1}},
+				HardConstraints: []rcv1.PolicyConstraint{
+					{Expression: "metadata.labels['zone'] == 'a'"},
+					{Expression: "100 / spec.cpu.cores > 10"},
+				},
+			},
+		}
+		node := func(name string, cores int, labels map[string]string) rcv1.RcNode {
+			return rcv1.RcNode{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+				Spec:       rcv1.RcNodeSpec{CPU: rcv1.RcNodeCPUSpec{Cores: cores}},
+			}
+		}
+		nodes := []rcv1.RcNode{
+			node("unlabelled", 4, nil),
+			node("no-cores", 0, map[string]string{"zone": "a"}),
+			node("ok", 4, map[string]string{"zone": "a"}),
+		}
+
+		d, err := Decide(pol, nil, nodes, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Node.Name).To(Equal("ok"))
+
+		Expect(d.Candidates[1].Node.Name).To(Equal("no-cores"))
+		Expect(d.Candidates[1].Rejected).To(Equal([]string{"100 / spec.cpu.cores > 10 (error: division by zero)"}))
+		Expect(d.Candidates[2].Node.Name).To(Equal("unlabelled"))
+		Expect(d.Candidates[2].Rejected).To(Equal([]string{"metadata.labels['zone'] == 'a' (error: no such key: zone)"}))
+	})
+})
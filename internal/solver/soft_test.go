@@ -0,0 +1,108 @@
+package solver
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+var _ = Describe("soft constraints", func() {
+	const (
+		edge    = "spec.nodePool == 'edge'"
+		spectre = "!spec.cpu.vulnerabilities.exists(v, v.contains('spectre'))"
+	)
+	node := func(name, pool string, watts int, vulns ...string) rcv1.RcNode {
+		return rcv1.RcNode{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       rcv1.RcNodeSpec{NodePool: pool, CPU: rcv1.RcNodeCPUSpec{Vulnerabilities: vulns}},
+			Status:     rcv1.RcNodeStatus{PredictedPowerWatts: watts},
+		}
+	}
+	policy := func(mode rcv1.RankingMode) *rcv1.RcPolicy {
+		return &rcv1.RcPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "prefer-edge"},
+			Spec: rcv1.RcPolicySpec{
+				Mode:    mode,
+				Metrics: []rcv1.PolicyMetric{{Key: "watts", Weight: 1, Selector: "$.status.predictedPowerWatts"}},
+				SoftConstraints: []rcv1.PolicySoftConstraint{
+					{Expression: edge, Penalty: 50},
+					{Expression: spectre, Penalty: 100},
+				},
+			},
+		}
+	}
+	nodes := []rcv1.RcNode{
+		node("edge-spectre", "edge", 100, "spectre_v2", "meltdown"),
+		node("core-clean", "core", 101),
+		node("edge-clean", "edge", 130),
+	}
+
+	It("adds the penalty of every violated constraint to the score", func() {
+		d, err := Decide(policy(""), nil, nodes, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Node.Name).To(Equal("edge-clean"))
+		Expect(d.Score).To(Equal(130.0))
+		Expect(d.Violated).To(BeEmpty())
+
+		var order []string
+		for _, c := range d.Candidates {
+			Expect(c.Feasible()).To(BeTrue())
+			order = append(order, c.Node.Name)
+		}
+		Expect(order).To(Equal([]string{"edge-clean", "core-clean", "edge-spectre"}))
+		Expect(d.Candidates[1].Penalty).To(Equal(50.0))
+		Expect(d.Candidates[1].Score).To(Equal(151.0))
+		Expect(d.Candidates[2].Violated).To(Equal([]string{spectre}))
+		Expect(d.Candidates[2].String()).To(Equal(
+			`edge-spectre score=200 [watts=100→100×1=100 penalty=100 for ["` +
+				`!spec.cpu.vulnerabilities.exists(v, v.contains('spectre'))"]]`))
+	})
+
+	It("still places the pod when no node satisfies them all", func() {
+		d, err := Decide(policy(""), nil, nodes[:2], nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Node.Name).To(Equal("core-clean"))
+		Expect(d.Violated).To(Equal([]string{edge}))
+	})
+
+	It("compares penalties before any metric in lexicographic mode", func() {
+		cands, err := Rank(policy(rcv1.RankLexicographic), nil, nodes, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cands[0].Node.Name).To(Equal("edge-clean"))
+		Expect(cands[1].Node.Name).To(Equal("core-clean"))
+	})
+
+	It("treats the penalty as one more objective in pareto mode", func() {
+		cands, err := Rank(policy(rcv1.RankPareto), nil, nodes, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		for _, c := range cands {
+			Expect(c.Front).To(Equal(1)) // each is best at watts or penalty
+		}
+	})
+
+	It("counts expressions that fail on a node as violated", func() {
+		pol := policy("")
+		pol.Spec.SoftConstraints = []rcv1.PolicySoftConstraint{{Expression: "metadata.labels['zone'] == 'a'", Penalty: 500}}
+		d, err := Decide(pol, nil, nodes, nil, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d.Node.Name).To(Equal("edge-spectre")) // all pay the same
+		Expect(d.Violated).To(Equal([]string{"metadata.labels['zone'] == 'a' (error: no such key: zone)"}))
+		Expect(d.Score).To(Equal(600.0))
+	})
+
+	It("reports broken expressions and negative penalties", func() {
+		pol := policy("")
+		pol.Spec.SoftConstraints = []rcv1.PolicySoftConstraint{
+			{Expression: "spec.nodePool ==", Penalty: 1},
+			{Expression: edge, Penalty: -1},
+		}
+		cp := Compile(pol)
+		Expect(cp.Errors).To(HaveLen(2))
+		Expect(cp.Errors[0]).To(HavePrefix("softConstraints[0]: "))
+		Expect(cp.Errors[1]).To(Equal("softConstraints[1].penalty: negative: -1"))
+	})
+})
@@ -0,0 +1,141 @@
+package solver
+
+import (
+	"fmt"
+	"time"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+/* -------------------------------------------------------------------------- */
+/*                         effective metric weights                           */
+/* -------------------------------------------------------------------------- */
+//
+// The documented pipeline (see RcPolicy):
+//
+//	base spec.metrics[*].weight
+//	  → first active spec.schedule window   (Replace wins over Multiply)
+//	  → every externalFeeds[*].mappings[*]  (CEL multiplier on the latest value,
+//	                                         fallbackMultiplier once it is stale
+//	                                         or a mapping fails on it)
+//	  = effective weight used for scoring
+
+// Weights records how every metric weight was derived, so a decision can be
+// explained after the fact ("why this node at 22:00 but not at 21:59?").
+type Weights struct {
+	// At is the instant the schedule was evaluated for.
+	At time.Time
+	// Schedule is the name of the active schedule window ("" = none).
+	Schedule string
+
+	Base      map[string]float64 // spec.metrics[*].weight
+	Scheduled map[string]float64 // after the schedule window
+	Effective map[string]float64 // after all feed multipliers
+
+	// Multipliers[metricKey][feedName] is the factor a feed applied.
+	Multipliers map[string]map[string]float64
+	// SkippedFeeds lists feeds without a fresh value (and no fallback) that
+	// were ignored.
+	SkippedFeeds []string
+	// StaleFeeds lists feeds whose FallbackMultiplier was used instead.
+	StaleFeeds []string
+	// FeedErrors[feedName] is why a mapping could not be evaluated on the
+	// feed's value; such a feed is skipped or stale like an old value.
+	FeedErrors map[string]string
+}
+
+// EffectiveWeights runs the weight pipeline of pol at instant now.
+func EffectiveWeights(pol *rcv1.RcPolicy, now time.Time) Weights {
+	return effectiveWeights(pol, Compile(pol), now)
+}
+
+func effectiveWeights(pol *rcv1.RcPolicy, cp *CompiledPolicy, now time.Time) Weights {
+	w := Weights{
+		At:          now,
+		Base:        map[string]float64{},
+		Scheduled:   map[string]float64{},
+		Effective:   map[string]float64{},
+		Multipliers: map[string]map[string]float64{},
+	}
+
+	// 1) base
+	for _, m := range pol.Spec.Metrics {
+		w.Base[m.Key] = m.Weight
+		w.Scheduled[m.Key] = m.Weight
+	}
+
+	// 2) schedule window
+	if entry, ok := pol.ActiveSchedule(now); ok {
+		w.Schedule = entry.Name
+		for _, adj := range entry.Adjustments {
+			cur, known := w.Scheduled[adj.Key]
+			if !known {
+				continue // adjustment for a metric the policy does not score
+			}
+			switch {
+			case adj.Replace != nil:
+				w.Scheduled[adj.Key] = *adj.Replace
+			case adj.Multiply != nil:
+				w.Scheduled[adj.Key] = cur * *adj.Multiply
+			}
+		}
+	}
+	for k, v := range w.Scheduled {
+		w.Effective[k] = v
+	}
+
+	// 3) external feeds
+	apply := func(key, feed string, mul float64) {
+		if _, known := w.Effective[key]; !known {
+			return
+		}
+		if w.Multipliers[key] == nil {
+			w.Multipliers[key] = map[string]float64{}
+		}
+		w.Multipliers[key][feed] = mul
+		w.Effective[key] *= mul
+	}
+	for i := range pol.Spec.ExternalFeeds {
+		f := &pol.Spec.ExternalFeeds[i]
+		fs := pol.Status.Feed(f.Name)
+		var muls map[string]float64
+		if f.Fresh(fs, now) {
+			var err error
+			if muls, err = feedMultipliers(cp.feeds[f.Name], *fs.Value); err != nil {
+				if w.FeedErrors == nil {
+					w.FeedErrors = map[string]string{}
+				}
+				w.FeedErrors[f.Name] = err.Error()
+			}
+		}
+		if muls == nil {
+			if f.FallbackMultiplier == nil {
+				w.SkippedFeeds = append(w.SkippedFeeds, f.Name)
+				continue
+			}
+			w.StaleFeeds = append(w.StaleFeeds, f.Name)
+			for _, mp := range f.Mappings {
+				apply(mp.Key, f.Name, *f.FallbackMultiplier)
+			}
+			continue
+		}
+		for key, mul := range muls {
+			apply(key, f.Name, mul)
+		}
+	}
+	return w
+}
+
+// feedMultipliers evaluates every mapping of a feed on value, all or none:
+// half a feed applied would skew the weights in a way no one configured.
+func feedMultipliers(mappings map[string]program, value float64) (map[string]float64, error) {
+	muls := make(map[string]float64, len(mappings))
+	for key, prg := range mappings {
+		mul, err := evalDouble(prg, map[string]interface{}{"value": value})
+		if err != nil {
+			return nil, fmt.Errorf("mapping of %s: %w", key, err)
+		}
+		muls[key] = mul
+	}
+	return muls, nil
+}
@@ -0,0 +1,88 @@
+package solver
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	rcv1 "github.com/lcereser6/recluster-sync/apis/recluster.com/v1alpha1"
+)
+
+var _ = Describe("effective weights", func() {
+	fp := func(v float64) *float64 { return &v }
+	at := func(hhmm string) time.Time {
+		t, err := time.ParseInLocation("15:04", hhmm, time.UTC)
+		Expect(err).NotTo(HaveOccurred())
+		return time.Date(2025, 1, 1, t.Hour(), t.Minute(), 0, 0, time.UTC)
+	}
+
+	var pol *rcv1.RcPolicy
+	BeforeEach(func() {
+		pol = &rcv1.RcPolicy{Spec: rcv1.RcPolicySpec{
+			Metrics: []rcv1.PolicyMetric{{Key: "watts", Weight: 2}, {Key: "boot", Weight: 1}},
+			Schedule: []rcv1.PolicyScheduleEntry{{
+				Name: "night", Start: "22:00", End: "06:00",
+				Adjustments: []rcv1.MetricAdjustment{
+					{Key: "watts", Replace: fp(10.0), Multiply: fp(3.0)},
+					{Key: "boot", Multiply: fp(0.5)},
+					{Key: "unknown", Replace: fp(99.0)},
+				},
+			}},
+			ExternalFeeds: []rcv1.ExternalFeedRef{
+				{Name: "price", Mappings: []rcv1.FeedMetricMapping{{Key: "watts", Transform: "1 + ($value / 100)"}}},
+				{Name: "co2", Mappings: []rcv1.FeedMetricMapping{{Key: "watts", Transform: "2"}}},
+			},
+		}}
+	})
+
+	It("uses the base weights outside every window", func() {
+		w := EffectiveWeights(pol, at("21:59"))
+		Expect(w.Schedule).To(BeEmpty())
+		Expect(w.Effective).To(Equal(map[string]float64{"watts": 2, "boot": 1}))
+		Expect(w.SkippedFeeds).To(ConsistOf("price", "co2"))
+	})
+
+	It("applies the active window, Replace winning over Multiply", func() {
+		for _, hhmm := range []string{"22:00", "03:00"} {
+			w := EffectiveWeights(pol, at(hhmm))
+			Expect(w.Schedule).To(Equal("night"), hhmm)
+			Expect(w.Effective).To(Equal(map[string]float64{"watts": 10, "boot": 0.5}), hhmm)
+		}
+	})
+
+	It("multiplies in every feed that has a stored value", func() {
+		pol.Status.Feeds = []rcv1.FeedStatus{{Name: "price", Value: fp(50.0),
+			LastUpdated: &metav1.Time{Time: at("11:59")}}}
+		w := EffectiveWeights(pol, at("12:00"))
+		Expect(w.Multipliers).To(Equal(map[string]map[string]float64{"watts": {"price": 1.5}}))
+		Expect(w.Effective["watts"]).To(BeNumerically("~", 3.0))
+		Expect(w.SkippedFeeds).To(ConsistOf("co2"))
+	})
+
+	It("uses the fallback multiplier once a feed value is stale", func() {
+		pol.Spec.ExternalFeeds[0].FallbackMultiplier = fp(4)
+		pol.Status.Feeds = []rcv1.FeedStatus{{Name: "price", Value: fp(50.0),
+			LastUpdated: &metav1.Time{Time: at("11:00")}}}
+		w := EffectiveWeights(pol, at("12:00")) // default TTL: 15m
+		Expect(w.StaleFeeds).To(ConsistOf("price"))
+		Expect(w.Effective["watts"]).To(Equal(8.0))
+	})
+
+	It("treats a feed whose mapping fails on its value like a stale one", func() {
+		pol.Spec.ExternalFeeds[0].Mappings = append(pol.Spec.ExternalFeeds[0].Mappings,
+			rcv1.FeedMetricMapping{Key: "boot", Transform: "double(100 / int($value))"})
+		pol.Status.Feeds = []rcv1.FeedStatus{{Name: "price", Value: fp(0),
+			LastUpdated: &metav1.Time{Time: at("11:59")}}}
+		w := EffectiveWeights(pol, at("12:00"))
+		Expect(w.SkippedFeeds).To(ConsistOf("price", "co2"))
+		Expect(w.FeedErrors).To(HaveKeyWithValue("price", ContainSubstring("division by zero")))
+		Expect(w.Effective).To(Equal(map[string]float64{"watts": 2, "boot": 1}))
+
+		pol.Spec.ExternalFeeds[0].FallbackMultiplier = fp(4)
+		w = EffectiveWeights(pol, at("12:00"))
+		Expect(w.StaleFeeds).To(ConsistOf("price"))
+		Expect(w.Effective).To(Equal(map[string]float64{"watts": 8, "boot": 4}))
+	})
+})
@@ -8,9 +8,9 @@ import (
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
-)
 
-const gateKey = "recluster-sync/wating-for-recluster-scheduling"
+	"github.com/lcereser6/recluster-sync/internal/graph"
+)
 
 type GateInjector struct {
 	decoder admission.Decoder // interface (NOT *admission.Decoder)
@@ -33,7 +33,7 @@ func (g *GateInjector) Handle(ctx context.Context, req admission.Request) admiss
 	}
 
 	pod.Spec.SchedulingGates = append(pod.Spec.SchedulingGates,
-		corev1.PodSchedulingGate{Name: gateKey})
+		corev1.PodSchedulingGate{Name: graph.GateKey})
 
 	marshaled, _ := json.Marshal(&pod)
 	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
@@ -59,10 +59,5 @@ func skipPod(p *corev1.Pod) bool {
 			return true
 		}
 	}
-	for _, g := range p.Spec.SchedulingGates {
-		if g.Name == gateKey {
-			return true
-		}
-	}
-	return false
+	return graph.HasGate(p)
 }